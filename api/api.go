@@ -23,23 +23,54 @@ var routerList = []struct {
 	{"subscribe", "POST", false, &handler.SubscribeHandler{}},
 	{"subscribeByFile", "POST", false, &handler.SubscribeByFileHandler{}},
 	{"unsubscribe", "POST", false, &handler.UnSubscribeHandler{}},
+	{"pause", "POST", true, &handler.PauseSubscriptionHandler{}},
+	{"resume", "POST", true, &handler.ResumeSubscriptionHandler{}},
+	{"getSyncStatus", "POST", false, &handler.SyncStatusHandler{}},
 
 	{"upload", "POST", false, &handler.UploadFileHandler{}},
 
+	// 管理接口
+	{"checkChainIntegrity", "POST", true, &handler.ChainIntegrityHandler{}},
+	{"reindex", "POST", true, &handler.ReindexChainHandler{}},
+	{"rebuildDailyStats", "POST", true, &handler.RebuildDailyStatsHandler{}},
+
 	// 浏览器接口
 	// 区块
 	{"getBlockList", "POST", false, &handler.BlockListHandler{}},
 	{"getBlockDetails", "POST", false, &handler.BlockDetailsHandler{}},
+	{"getProposerStats", "POST", false, &handler.ProposerStatsHandler{}},
 	// 交易
 	{"getTxList", "POST", false, &handler.TxListHandler{}},
 	{"getTxDetails", "POST", false, &handler.TxDetailsHandler{}},
 	{"getTxAmountByTime", "POST", false, &handler.TxAmountByTimeHandler{}},
+	{"getTxRwSet", "POST", false, &handler.TxRwSetHandler{}},
+	// 状态
+	{"getKeyHistory", "POST", false, &handler.KeyHistoryHandler{}},
 	// 合约
 	{"getContractList", "POST", false, &handler.ContractListHandler{}},
 	{"getContractDetails", "POST", false, &handler.ContractDetailsHandler{}},
+	{"getContractHistory", "POST", false, &handler.ContractHistoryHandler{}},
+	// 账户
+	{"getAddressDetails", "POST", false, &handler.AddressDetailsHandler{}},
+	{"getAddressTxList", "POST", false, &handler.AddressTxListHandler{}},
+	{"getAddressCreatedContracts", "POST", false, &handler.AddressCreatedContractsHandler{}},
+	{"getAddressCalledContracts", "POST", false, &handler.AddressCalledContractsHandler{}},
+	// 燃料
+	{"getGasTopConsumers", "POST", false, &handler.GasTopConsumersHandler{}},
+	{"getGasTimeSeries", "POST", false, &handler.GasTimeSeriesHandler{}},
+	{"getTxGasEfficiency", "POST", false, &handler.TxGasEfficiencyHandler{}},
+	// 事件
+	{"getEventList", "POST", false, &handler.EventListHandler{}},
+	// 链配置
+	{"getChainConfig", "POST", false, &handler.ChainConfigHandler{}},
+	{"getChainConfigList", "POST", false, &handler.ChainConfigListHandler{}},
+	{"getChainConfigDiff", "POST", false, &handler.ChainConfigDiffHandler{}},
 	// 其他
 	{"search", "POST", false, &handler.SearchHandler{}},
+	{"globalSearch", "POST", false, &handler.GlobalSearchHandler{}},
 	{"overview", "POST", false, &handler.OverviewHandler{}},
+	{"getTimeSeriesStats", "POST", false, &handler.TimeSeriesStatsHandler{}},
+	{"getDailyStats", "POST", false, &handler.DailyStatsHandler{}},
 }
 
 // LoadHttpHandlers 路由通用加载
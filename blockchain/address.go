@@ -0,0 +1,113 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"chainmaker.org/chainmaker/common/v2/crypto/hash"
+	"chainmaker.org/chainmaker/common/v2/evmutils"
+	"chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
+)
+
+// DefaultHashType 未获取到链的哈希算法时使用
+const DefaultHashType = "SHA256"
+
+// 地址为哈希值的后20字节
+const addressLength = 20
+
+// CertLookup 按证书哈希（hex）查询链上登记的证书，未登记时返回nil
+type CertLookup func(certHash string) ([]byte, error)
+
+// MemberAddress 计算成员的ChainMaker格式地址和EVM格式地址，hashType为链的哈希算法。
+// 证书哈希成员需先经ResolveCertHash转换为证书成员，DID等无法得到公钥的成员返回空地址，地址成员直接返回其地址
+func MemberAddress(member *accesscontrol.Member, hashType string) (string, string, error) {
+	if member == nil {
+		return "", "", nil
+	}
+
+	var key *asn1PublicKeyInfo
+
+	switch member.MemberType {
+	case accesscontrol.MemberType_CERT:
+		cert, err := parseCertificate(pemToDer(member.MemberInfo))
+		if err != nil {
+			return "", "", err
+		}
+		key = &cert.TBSCertificate.PublicKey
+
+	case accesscontrol.MemberType_PUBLIC_KEY:
+		var err error
+		key, err = parsePublicKeyInfo(pemToDer(member.MemberInfo))
+		if err != nil {
+			return "", "", err
+		}
+
+	case accesscontrol.MemberType_ADDR:
+		return NormalizeAddress(string(member.MemberInfo)), "", nil
+
+	default:
+		return "", "", nil
+	}
+
+	return publicKeyAddress(key, hashType)
+}
+
+// ResolveCertHash 证书哈希成员通过certLookup查询链上登记的证书，转换为证书成员，
+// 其他成员或证书未登记时原样返回，查询失败时返回错误
+func ResolveCertHash(member *accesscontrol.Member, certLookup CertLookup) (*accesscontrol.Member, error) {
+	if member == nil || member.MemberType != accesscontrol.MemberType_CERT_HASH || certLookup == nil {
+		return member, nil
+	}
+
+	cert, err := certLookup(certHashString(member.MemberInfo))
+	if err != nil {
+		return nil, errors.New("fail to query the cert by hash, " + err.Error())
+	}
+
+	if len(cert) == 0 {
+		return member, nil
+	}
+
+	return &accesscontrol.Member{
+		OrgId:      member.OrgId,
+		MemberType: accesscontrol.MemberType_CERT,
+		MemberInfo: cert,
+	}, nil
+}
+
+// publicKeyAddress ChainMaker地址为公钥DER按链哈希算法计算的哈希后20字节，
+// EVM地址为未压缩椭圆曲线公钥点的Keccak256后20字节
+func publicKeyAddress(key *asn1PublicKeyInfo, hashType string) (string, string, error) {
+	if len(hashType) == 0 {
+		hashType = DefaultHashType
+	}
+
+	data, err := hash.GetByStrType(strings.ToUpper(hashType), key.Raw)
+	if err != nil {
+		return "", "", errors.New("fail to hash the public key, " + err.Error())
+	}
+
+	if len(data) < addressLength {
+		return "", "", errors.New("the hash of the public key is too short")
+	}
+
+	addr := hex.EncodeToString(data[len(data)-addressLength:])
+
+	var evmAddr string
+
+	point := key.PublicKey.RightAlign()
+	if key.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) && len(point) == 65 && point[0] == 4 {
+		evmHash := evmutils.Keccak256(point[1:])
+		evmAddr = hex.EncodeToString(evmHash[len(evmHash)-addressLength:])
+	}
+
+	return addr, evmAddr, nil
+}
+
+// NormalizeAddress 地址统一为不带0x前缀的小写hex
+func NormalizeAddress(addr string) string {
+	addr = strings.TrimSpace(addr)
+	addr = strings.TrimPrefix(strings.TrimPrefix(addr, "0x"), "0X")
+	return strings.ToLower(addr)
+}
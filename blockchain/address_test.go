@@ -0,0 +1,87 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+)
+
+func TestResolveCertHash(t *testing.T) {
+	certHash := []byte{0x01, 0xab}
+	cert := []byte("cert")
+
+	lookup := func(h string) ([]byte, error) {
+		switch h {
+		case hex.EncodeToString(certHash):
+			return cert, nil
+		case "ff":
+			return nil, errors.New("query failed")
+		}
+		return nil, nil
+	}
+
+	member, err := ResolveCertHash(&accesscontrol.Member{
+		OrgId:      "org1",
+		MemberType: accesscontrol.MemberType_CERT_HASH,
+		MemberInfo: certHash,
+	}, lookup)
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+
+	if member.MemberType != accesscontrol.MemberType_CERT || string(member.MemberInfo) != string(cert) ||
+		member.OrgId != "org1" {
+		t.Errorf("the cert hash member was not resolved: [%v]", member)
+	}
+
+	// 未登记的证书原样返回
+	unknown := &accesscontrol.Member{MemberType: accesscontrol.MemberType_CERT_HASH, MemberInfo: []byte{0x02}}
+	member, err = ResolveCertHash(unknown, lookup)
+	if err != nil || member != unknown {
+		t.Errorf("the unknown cert hash should be returned as is, err: [%v]", err)
+	}
+
+	_, err = ResolveCertHash(&accesscontrol.Member{
+		MemberType: accesscontrol.MemberType_CERT_HASH,
+		MemberInfo: []byte{0xff},
+	}, lookup)
+	if err == nil {
+		t.Error("the lookup error should be returned")
+	}
+
+	// 其他类型的成员不查询证书
+	pk := &accesscontrol.Member{MemberType: accesscontrol.MemberType_PUBLIC_KEY, MemberInfo: []byte("ff")}
+	member, err = ResolveCertHash(pk, func(string) ([]byte, error) {
+		t.Error("the lookup should not be called")
+		return nil, nil
+	})
+	if err != nil || member != pk {
+		t.Errorf("the public key member should be returned as is, err: [%v]", err)
+	}
+}
+
+// TestParseBlockCertLookupError 证书查询失败时区块正常解析，发送者地址留空
+func TestParseBlockCertLookupError(t *testing.T) {
+	blockInfo := benchBlock(1, 1)
+	blockInfo.Block.Txs[0].Sender = &common.EndorsementEntry{
+		Signer: &accesscontrol.Member{
+			OrgId:      "org1",
+			MemberType: accesscontrol.MemberType_CERT_HASH,
+			MemberInfo: []byte{0x01},
+		},
+	}
+
+	blockData, err := ParseBlock(blockInfo, "", func(string) ([]byte, error) {
+		return nil, errors.New("query failed")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+
+	if len(blockData.Transactions) != 1 || len(blockData.Transactions[0].SenderAddress) != 0 {
+		t.Errorf("the sender address should be empty")
+	}
+}
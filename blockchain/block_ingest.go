@@ -0,0 +1,453 @@
+package blockchain
+
+import (
+	"chainmscan/db/dao"
+	dbModel "chainmscan/db/model"
+	"context"
+	"errors"
+	"fmt"
+
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultParseWorkers      = 4
+	DefaultBatchBlocks       = 50
+	DefaultBackfillRangeSize = 1000
+	// 单条insert语句的最大行数
+	InsertBatchSize = 500
+)
+
+type IngestConfig struct {
+	// 并发解析区块的协程数
+	ParseWorkers int `mapstructure:"parse_workers"`
+	// 单个数据库事务最多合并的区块数
+	BatchBlocks int `mapstructure:"batch_blocks"`
+	// 首次订阅时并行回填历史区块的协程数，为0时不回填，直接从0号区块订阅
+	BackfillWorkers int `mapstructure:"backfill_workers"`
+	// 回填时每个任务区间的区块数
+	BackfillRangeSize int `mapstructure:"backfill_range_size"`
+}
+
+type parseResult struct {
+	blockData *BlockData
+	err       error
+}
+
+// ProcessBlocks 区块入库流水线：并发解析区块，按高度顺序合并多个区块批量入库。
+// 追块时队列中有积压，按BatchBlocks合并入库；实时同步时队列为空，收到区块立即入库。
+// 订阅通道关闭或入库失败时返回错误，ctx结束时将已解析的区块入库后返回nil。
+// onStored不为nil时每批区块入库后回调，回调不应阻塞。hashType和certLookup见ParseBlock
+func ProcessBlocks(ctx context.Context, blockC <-chan interface{}, genHash string,
+	tableNum int, hashType string, certLookup CertLookup, conf *IngestConfig, gormDb *gorm.DB,
+	onStored func(blocks []*BlockData)) error {
+
+	p := newBlockPipeline(conf,
+		func(blockInfo *common.BlockInfo) (*BlockData, error) {
+			return ParseBlock(blockInfo, hashType, certLookup)
+		},
+		func(blocks []*BlockData) error {
+			return StorageBlocks(blocks, genHash, tableNum, gormDb)
+		})
+
+	return p.run(ctx, blockC, onStored)
+}
+
+// blockPipeline 区块入库流水线，parse解析单个区块，store在一个数据库事务内入库一批区块
+type blockPipeline struct {
+	parse        func(blockInfo *common.BlockInfo) (*BlockData, error)
+	store        func(blocks []*BlockData) error
+	parseWorkers int
+	batchBlocks  int
+}
+
+func newBlockPipeline(conf *IngestConfig, parse func(blockInfo *common.BlockInfo) (*BlockData, error),
+	store func(blocks []*BlockData) error) *blockPipeline {
+
+	p := &blockPipeline{
+		parse:        parse,
+		store:        store,
+		parseWorkers: DefaultParseWorkers,
+		batchBlocks:  DefaultBatchBlocks,
+	}
+
+	if conf != nil && conf.ParseWorkers > 0 {
+		p.parseWorkers = conf.ParseWorkers
+	}
+	if conf != nil && conf.BatchBlocks > 0 {
+		p.batchBlocks = conf.BatchBlocks
+	}
+
+	return p
+}
+
+func (p *blockPipeline) run(ctx context.Context, blockC <-chan interface{},
+	onStored func(blocks []*BlockData)) error {
+
+	dispatchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 按接收顺序排队的解析结果，保证入库顺序与区块高度一致
+	futures := make(chan chan *parseResult, p.batchBlocks)
+	dispatchErrC := make(chan error, 1)
+
+	go p.dispatch(dispatchCtx, blockC, futures, dispatchErrC)
+
+	batch := make([]*BlockData, 0, p.batchBlocks)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		err := p.store(batch)
+		if err != nil {
+			return fmt.Errorf("fail to storage block, height: [%d-%d], err: [%s]",
+				batch[0].Block.BlockHeight, batch[len(batch)-1].Block.BlockHeight, err.Error())
+		}
+
+		if onStored != nil {
+			onStored(batch)
+		}
+
+		// 回调方可能持有已入库的批次，重新分配
+		batch = make([]*BlockData, 0, p.batchBlocks)
+		return nil
+	}
+
+	for {
+		select {
+		case f, ok := <-futures:
+			if !ok {
+				if err := flush(); err != nil {
+					return err
+				}
+
+				select {
+				case err := <-dispatchErrC:
+					return err
+				default:
+					return nil
+				}
+			}
+
+			var res *parseResult
+			select {
+			case res = <-f:
+			case <-ctx.Done():
+				return flush()
+			}
+
+			if res.err != nil {
+				if err := flush(); err != nil {
+					return err
+				}
+				return errors.New("fail to parse block, " + res.err.Error())
+			}
+
+			batch = append(batch, res.blockData)
+
+			if len(batch) >= p.batchBlocks || len(futures) == 0 {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+
+		case <-ctx.Done():
+			return flush()
+		}
+	}
+}
+
+// dispatch 接收订阅区块并分发给解析协程，结束时关闭futures
+func (p *blockPipeline) dispatch(ctx context.Context, blockC <-chan interface{},
+	futures chan<- chan *parseResult, errC chan<- error) {
+
+	defer close(futures)
+
+	sem := make(chan struct{}, p.parseWorkers)
+
+	for {
+		select {
+		case block, ok := <-blockC:
+			if !ok {
+				errC <- errors.New("the chan of subscriber is closed")
+				return
+			}
+
+			blockInfo, ok := block.(*common.BlockInfo)
+			if !ok {
+				errC <- errors.New("the block info type error")
+				return
+			}
+
+			f := make(chan *parseResult, 1)
+
+			select {
+			case futures <- f:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			go func() {
+				defer func() { <-sem }()
+				blockData, err := p.parse(blockInfo)
+				f <- &parseResult{blockData: blockData, err: err}
+			}()
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// StorageBlocks 多个区块合并在一个数据库事务内批量入库，并原子累加链统计信息，
+// hooks在同一事务内执行
+func StorageBlocks(blocks []*BlockData, genHash string, tableNum int,
+	gormDb *gorm.DB, hooks ...func(tx *gorm.DB) error) error {
+
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	dbBlocks := make([]*dbModel.Block, 0, len(blocks))
+	dbBlockDetails := make([]*dbModel.BlockDetails, 0, len(blocks))
+	transactions := make([]*dbModel.Transaction, 0)
+	transactionDetails := make([]*dbModel.TxDetails, 0)
+	contractHistories := make([]*dbModel.ContractHistory, 0)
+	txWrites := make([]*dbModel.TxWrite, 0)
+	contractEvents := make([]*dbModel.ContractEvent, 0)
+	chainConfigs := make([]*dbModel.ChainConfig, 0)
+
+	var txAmount int
+
+	for _, b := range blocks {
+		dbBlocks = append(dbBlocks, b.Block)
+		dbBlockDetails = append(dbBlockDetails, b.BlockDetails)
+		transactions = append(transactions, b.Transactions...)
+		transactionDetails = append(transactionDetails, b.TransactionDetails...)
+		contractHistories = append(contractHistories, b.ContractHistories...)
+		txWrites = append(txWrites, b.TxWrites...)
+		contractEvents = append(contractEvents, b.ContractEvents...)
+		chainConfigs = append(chainConfigs, b.ChainConfigs...)
+
+		txAmount += int(b.Block.TxCount)
+	}
+
+	err := gormDb.Transaction(func(tx *gorm.DB) error {
+		err := dao.InsertObjectsToDBByTableName(dbBlocks,
+			fmt.Sprintf(dbModel.TableNamePrefix_Block+"_%02d", tableNum), InsertBatchSize, tx)
+		if err != nil {
+			return err
+		}
+
+		err = dao.InsertObjectsToDBByTableName(dbBlockDetails,
+			fmt.Sprintf(dbModel.TableNamePrefix_BlockDetails+"_%02d", tableNum), InsertBatchSize, tx)
+		if err != nil {
+			return err
+		}
+
+		if len(transactions) != 0 {
+			err = dao.InsertObjectsToDBByTableName(transactions,
+				fmt.Sprintf(dbModel.TableNamePrefix_Transaction+"_%02d", tableNum), InsertBatchSize, tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(transactionDetails) != 0 {
+			err = dao.InsertObjectsToDBByTableName(transactionDetails,
+				fmt.Sprintf(dbModel.TableNamePrefix_TxDetails+"_%02d", tableNum), InsertBatchSize, tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(contractHistories) != 0 {
+			err = dao.InsertObjectsToDBByTableName(contractHistories,
+				fmt.Sprintf(dbModel.TableNamePrefix_ContractHistory+"_%02d", tableNum), InsertBatchSize, tx)
+			if err != nil {
+				return err
+			}
+
+			// 回填时区块乱序入库，合约当前状态按全部生命周期记录重新推导
+			names := make([]string, 0, len(contractHistories))
+			for _, h := range contractHistories {
+				names = append(names, h.Name)
+			}
+
+			err = dao.RefreshContracts(tableNum, names, tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(txWrites) != 0 {
+			err = dao.InsertObjectsToDBByTableName(txWrites,
+				fmt.Sprintf(dbModel.TableNamePrefix_TxWrite+"_%02d", tableNum), InsertBatchSize, tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(contractEvents) != 0 {
+			err = dao.InsertObjectsToDBByTableName(contractEvents,
+				fmt.Sprintf(dbModel.TableNamePrefix_ContractEvent+"_%02d", tableNum), InsertBatchSize, tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(chainConfigs) != 0 {
+			err = dao.InsertObjectsToDBByTableName(chainConfigs,
+				fmt.Sprintf(dbModel.TableNamePrefix_ChainConfig+"_%02d", tableNum), InsertBatchSize, tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(transactions) != 0 {
+			err = dao.UpsertGasRollups(gasRollups(transactions), tableNum, tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		stats, senders := dailyStats(blocks)
+
+		err = dao.UpsertDailyStats(stats, senders, tableNum, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, hook := range hooks {
+			err = hook(tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		return dao.IncreaseChainTxAndBlockAmount(genHash, txAmount, len(blocks), tx)
+	})
+	if err != nil {
+		return errors.New("fail to insert block to db, " + err.Error())
+	}
+
+	return nil
+}
+
+// gasRollups 按小时、合约、方法、发送者组织汇总一批交易的燃料消耗
+func gasRollups(transactions []*dbModel.Transaction) []*dbModel.GasRollup {
+	type rollupKey struct {
+		bucketTime   int64
+		contractName string
+		method       string
+		senderOrgId  string
+	}
+
+	rollups := make(map[rollupKey]*dbModel.GasRollup)
+	list := make([]*dbModel.GasRollup, 0)
+
+	for _, t := range transactions {
+		key := rollupKey{
+			bucketTime:   t.Timestamp - t.Timestamp%dbModel.GasRollupInterval,
+			contractName: t.ContractName,
+			method:       t.Method,
+			senderOrgId:  t.SenderOrgId,
+		}
+
+		r, ok := rollups[key]
+		if !ok {
+			r = &dbModel.GasRollup{
+				BucketTime:   key.bucketTime,
+				ContractName: key.contractName,
+				Method:       key.method,
+				SenderOrgId:  key.senderOrgId,
+			}
+			rollups[key] = r
+			list = append(list, r)
+		}
+
+		r.TxCount++
+		r.GasUsed += t.GasUsed
+		r.GasLimit += t.GasLimit
+	}
+
+	return list
+}
+
+// dailyStats 按区块时间所在自然日汇总一批区块的统计及当天的发送者地址
+func dailyStats(blocks []*BlockData) ([]*dbModel.ChainDailyStats, []*dbModel.ChainDailySender) {
+	type senderKey struct {
+		statDate      int64
+		senderAddress string
+	}
+
+	stats := make(map[int64]*dbModel.ChainDailyStats)
+	list := make([]*dbModel.ChainDailyStats, 0)
+	senderSet := make(map[senderKey]struct{})
+	senders := make([]*dbModel.ChainDailySender, 0)
+
+	for _, b := range blocks {
+		blockTime := b.Block.BlockTimestamp
+		statDate := dbModel.DailyStatDate(blockTime)
+
+		s, ok := stats[statDate]
+		if !ok {
+			s = &dbModel.ChainDailyStats{
+				StatDate:       statDate,
+				FirstBlockTime: blockTime,
+				LastBlockTime:  blockTime,
+			}
+			stats[statDate] = s
+			list = append(list, s)
+		}
+
+		s.BlockCount++
+
+		if blockTime < s.FirstBlockTime {
+			s.FirstBlockTime = blockTime
+		}
+
+		if blockTime > s.LastBlockTime {
+			s.LastBlockTime = blockTime
+		}
+
+		for _, t := range b.Transactions {
+			s.TxCount++
+			s.GasUsed += t.GasUsed
+
+			if t.TxStatusCode != dao.TxStatusCode_Success {
+				s.FailedTxCount++
+			}
+
+			if len(t.SenderAddress) == 0 {
+				continue
+			}
+
+			key := senderKey{statDate: statDate, senderAddress: t.SenderAddress}
+			if _, ok := senderSet[key]; !ok {
+				senderSet[key] = struct{}{}
+				senders = append(senders, &dbModel.ChainDailySender{
+					StatDate:      statDate,
+					SenderAddress: t.SenderAddress,
+				})
+			}
+		}
+
+		for _, h := range b.ContractHistories {
+			if h.Operation == dbModel.ContractOperation_Init {
+				s.NewContractCount++
+			}
+		}
+	}
+
+	return list, senders
+}
@@ -0,0 +1,311 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+)
+
+// testBlock 只有区块头的区块
+func testBlock(height uint64) *common.BlockInfo {
+	return &common.BlockInfo{
+		Block: &common.Block{
+			Header: &common.BlockHeader{BlockHeight: height},
+		},
+	}
+}
+
+// testParse 随机延迟解析，使解析完成的顺序与接收顺序不一致
+func testParse(blockInfo *common.BlockInfo) (*BlockData, error) {
+	time.Sleep(time.Duration(rand.Intn(200)) * time.Microsecond)
+
+	return ParseBlock(blockInfo, "", nil)
+}
+
+// testStore 记录每批入库的区块高度
+type testStore struct {
+	mu      sync.Mutex
+	batches [][]uint64
+	failAt  uint64
+}
+
+func (s *testStore) store(blocks []*BlockData) error {
+	heights := make([]uint64, 0, len(blocks))
+	for _, b := range blocks {
+		if s.failAt != 0 && b.Block.BlockHeight == s.failAt {
+			return errors.New("store failed")
+		}
+		heights = append(heights, b.Block.BlockHeight)
+	}
+
+	s.mu.Lock()
+	s.batches = append(s.batches, heights)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// heights 按入库顺序返回全部区块高度
+func (s *testStore) heights() []uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	heights := make([]uint64, 0)
+	for _, b := range s.batches {
+		heights = append(heights, b...)
+	}
+
+	return heights
+}
+
+func sendBlocks(blockC chan<- interface{}, from, to uint64) {
+	for h := from; h < to; h++ {
+		blockC <- testBlock(h)
+	}
+}
+
+func checkSequential(t *testing.T, heights []uint64, n uint64) {
+	t.Helper()
+
+	if uint64(len(heights)) != n {
+		t.Fatalf("stored %d blocks, want %d", len(heights), n)
+	}
+
+	for i, h := range heights {
+		if h != uint64(i) {
+			t.Fatalf("the block at index %d has height %d", i, h)
+		}
+	}
+}
+
+func TestProcessBlocksOrder(t *testing.T) {
+	const n = 1000
+
+	s := &testStore{}
+	p := newBlockPipeline(&IngestConfig{ParseWorkers: 8, BatchBlocks: 16}, testParse, s.store)
+
+	blockC := make(chan interface{}, n)
+	sendBlocks(blockC, 0, n)
+	close(blockC)
+
+	var callbacks int
+	err := p.run(context.Background(), blockC, func(blocks []*BlockData) {
+		callbacks++
+	})
+	if err == nil || !strings.Contains(err.Error(), "the chan of subscriber is closed") {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+
+	checkSequential(t, s.heights(), n)
+
+	for _, b := range s.batches {
+		if len(b) > 16 {
+			t.Errorf("the batch has %d blocks, more than BatchBlocks", len(b))
+		}
+	}
+
+	if callbacks != len(s.batches) {
+		t.Errorf("onStored was called %d times, want %d", callbacks, len(s.batches))
+	}
+}
+
+// TestProcessBlocksFlushLive 实时同步时队列为空，每个区块不等待凑满批次立即入库
+func TestProcessBlocksFlushLive(t *testing.T) {
+	s := &testStore{}
+	p := newBlockPipeline(&IngestConfig{ParseWorkers: 4, BatchBlocks: 50}, testParse, s.store)
+
+	blockC := make(chan interface{})
+	stored := make(chan []*BlockData, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errC := make(chan error, 1)
+
+	go func() {
+		errC <- p.run(ctx, blockC, func(blocks []*BlockData) { stored <- blocks })
+	}()
+
+	for h := uint64(0); h < 5; h++ {
+		blockC <- testBlock(h)
+
+		select {
+		case blocks := <-stored:
+			if len(blocks) != 1 || blocks[0].Block.BlockHeight != h {
+				t.Fatalf("unexpected batch for height %d", h)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the block %d was not flushed", h)
+		}
+	}
+
+	cancel()
+
+	if err := <-errC; err != nil {
+		t.Fatalf("unexpected error after cancel: [%s]", err.Error())
+	}
+
+	checkSequential(t, s.heights(), 5)
+}
+
+// TestProcessBlocksCancel ctx结束时已解析的区块入库后返回nil
+func TestProcessBlocksCancel(t *testing.T) {
+	const n = 200
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &testStore{}
+	var once sync.Once
+	p := newBlockPipeline(&IngestConfig{ParseWorkers: 4, BatchBlocks: 10}, testParse,
+		func(blocks []*BlockData) error {
+			once.Do(cancel)
+			return s.store(blocks)
+		})
+
+	blockC := make(chan interface{}, n)
+	sendBlocks(blockC, 0, n)
+
+	err := p.run(ctx, blockC, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: [%s]", err.Error())
+	}
+
+	heights := s.heights()
+	if len(heights) == 0 {
+		t.Fatal("no block was stored")
+	}
+
+	checkSequential(t, heights, uint64(len(heights)))
+}
+
+// TestProcessBlocksParseError 解析失败时之前的区块入库后返回错误
+func TestProcessBlocksParseError(t *testing.T) {
+	const failAt = 37
+
+	s := &testStore{}
+	p := newBlockPipeline(&IngestConfig{ParseWorkers: 4, BatchBlocks: 10},
+		func(blockInfo *common.BlockInfo) (*BlockData, error) {
+			if blockInfo.Block.Header.BlockHeight == failAt {
+				return nil, errors.New("bad block")
+			}
+			return testParse(blockInfo)
+		}, s.store)
+
+	blockC := make(chan interface{}, 100)
+	sendBlocks(blockC, 0, 100)
+
+	err := p.run(context.Background(), blockC, nil)
+	if err == nil || !strings.Contains(err.Error(), "fail to parse block") {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+
+	checkSequential(t, s.heights(), failAt)
+}
+
+func TestProcessBlocksStoreError(t *testing.T) {
+	s := &testStore{failAt: 25}
+	p := newBlockPipeline(&IngestConfig{ParseWorkers: 4, BatchBlocks: 10}, testParse, s.store)
+
+	blockC := make(chan interface{}, 100)
+	sendBlocks(blockC, 0, 100)
+
+	err := p.run(context.Background(), blockC, nil)
+	if err == nil || !strings.Contains(err.Error(), "fail to storage block") {
+		t.Fatalf("unexpected error: [%v]", err)
+	}
+
+	heights := s.heights()
+	if uint64(len(heights)) >= s.failAt {
+		t.Fatalf("stored %d blocks after the failed batch", len(heights))
+	}
+
+	checkSequential(t, heights, uint64(len(heights)))
+}
+
+// benchBlock 包含txCount笔交易的区块
+func benchBlock(height uint64, txCount int) *common.BlockInfo {
+	txs := make([]*common.Transaction, 0, txCount)
+	for i := 0; i < txCount; i++ {
+		txs = append(txs, &common.Transaction{
+			Payload: &common.Payload{
+				ChainId:      "chain1",
+				TxId:         fmt.Sprintf("%016x%016x", height, i),
+				Timestamp:    time.Now().Unix(),
+				ContractName: "contract1",
+				Method:       "invoke",
+				Parameters: []*common.KeyValuePair{
+					{Key: "key", Value: []byte("value")},
+				},
+			},
+			Result: &common.Result{
+				ContractResult: &common.ContractResult{
+					Result:  []byte("ok"),
+					GasUsed: 100,
+				},
+			},
+		})
+	}
+
+	return &common.BlockInfo{
+		Block: &common.Block{
+			Header: &common.BlockHeader{
+				ChainId:        "chain1",
+				BlockHeight:    height,
+				BlockHash:      []byte(fmt.Sprintf("hash%d", height)),
+				BlockTimestamp: time.Now().Unix(),
+				TxCount:        uint32(txCount),
+			},
+			Txs: txs,
+		},
+	}
+}
+
+// BenchmarkProcessBlocks 模拟每个数据库事务固定耗时，比较不同解析协程数和批次大小的吞吐
+func BenchmarkProcessBlocks(b *testing.B) {
+	const (
+		txPerBlock   = 100
+		storeLatency = time.Millisecond
+	)
+
+	blocks := make([]*common.BlockInfo, 0, 512)
+	for h := 0; h < cap(blocks); h++ {
+		blocks = append(blocks, benchBlock(uint64(h), txPerBlock))
+	}
+
+	for _, c := range []IngestConfig{
+		{ParseWorkers: 1, BatchBlocks: 1},
+		{ParseWorkers: 4, BatchBlocks: 1},
+		{ParseWorkers: 4, BatchBlocks: 50},
+		{ParseWorkers: 8, BatchBlocks: 50},
+	} {
+		conf := c
+		b.Run(fmt.Sprintf("workers=%d/batch=%d", conf.ParseWorkers, conf.BatchBlocks), func(b *testing.B) {
+			p := newBlockPipeline(&conf,
+				func(blockInfo *common.BlockInfo) (*BlockData, error) {
+					return ParseBlock(blockInfo, "", nil)
+				},
+				func(blocks []*BlockData) error {
+					time.Sleep(storeLatency)
+					return nil
+				})
+
+			blockC := make(chan interface{}, len(blocks))
+
+			b.ResetTimer()
+
+			go func() {
+				for i := 0; i < b.N; i++ {
+					blockC <- blocks[i%len(blocks)]
+				}
+				close(blockC)
+			}()
+
+			_ = p.run(context.Background(), blockC, nil)
+		})
+	}
+}
@@ -1,18 +1,14 @@
 package blockchain
 
 import (
-	"chainmscan/db/dao"
 	dbModel "chainmscan/db/model"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
-	"errors"
-	"fmt"
 
 	"chainmaker.org/chainmaker/pb-go/v2/common"
 	"chainmaker.org/chainmaker/pb-go/v2/syscontract"
 	"github.com/gogo/protobuf/proto"
-	"gorm.io/gorm"
 )
 
 type BlockData struct {
@@ -20,10 +16,24 @@ type BlockData struct {
 	BlockDetails       *dbModel.BlockDetails
 	Transactions       []*dbModel.Transaction
 	TransactionDetails []*dbModel.TxDetails
-	Contracts          []*dbModel.Contract
+	ContractHistories  []*dbModel.ContractHistory
+	TxWrites           []*dbModel.TxWrite
+	ContractEvents     []*dbModel.ContractEvent
+	ChainConfigs       []*dbModel.ChainConfig
 }
 
-func ParseBlock(blockInfo *common.BlockInfo) (*BlockData, error) {
+// contractOperations 合约管理方法对应的生命周期操作
+var contractOperations = map[string]string{
+	syscontract.ContractManageFunction_INIT_CONTRACT.String():     dbModel.ContractOperation_Init,
+	syscontract.ContractManageFunction_UPGRADE_CONTRACT.String():  dbModel.ContractOperation_Upgrade,
+	syscontract.ContractManageFunction_FREEZE_CONTRACT.String():   dbModel.ContractOperation_Freeze,
+	syscontract.ContractManageFunction_UNFREEZE_CONTRACT.String(): dbModel.ContractOperation_Unfreeze,
+	syscontract.ContractManageFunction_REVOKE_CONTRACT.String():   dbModel.ContractOperation_Revoke,
+}
+
+// ParseBlock 解析区块，hashType为链的哈希算法，用于计算交易发送者地址，
+// certLookup用于查询证书哈希发送者的证书，为nil或查询失败时证书哈希发送者的地址留空
+func ParseBlock(blockInfo *common.BlockInfo, hashType string, certLookup CertLookup) (*BlockData, error) {
 
 	blockData := new(BlockData)
 
@@ -31,24 +41,33 @@ func ParseBlock(blockInfo *common.BlockInfo) (*BlockData, error) {
 	blockHeader = blockInfo.Block.Header
 
 	dbBlock := &dbModel.Block{
-		BlockHeight:    blockHeader.BlockHeight,
-		BlockHash:      hex.EncodeToString(blockHeader.BlockHash),
-		ChainId:        blockHeader.ChainId,
-		PreBlockHash:   hex.EncodeToString(blockHeader.PreBlockHash),
-		BlockType:      blockHeader.BlockType.String(),
-		BlockVersion:   blockHeader.BlockVersion,
-		PreConfHeight:  blockHeader.PreConfHeight,
-		TxCount:        blockHeader.TxCount,
-		TxRoot:         hex.EncodeToString(blockHeader.TxRoot),
-		DagHash:        hex.EncodeToString(blockHeader.DagHash),
-		RwSetRoot:      hex.EncodeToString(blockHeader.RwSetRoot),
-		BlockTimestamp: blockHeader.BlockTimestamp,
-		ConsensusArgs:  string(blockHeader.ConsensusArgs),
+		BlockHeight:      blockHeader.BlockHeight,
+		BlockHash:        hex.EncodeToString(blockHeader.BlockHash),
+		ChainId:          blockHeader.ChainId,
+		PreBlockHash:     hex.EncodeToString(blockHeader.PreBlockHash),
+		BlockType:        blockHeader.BlockType.String(),
+		BlockVersion:     blockHeader.BlockVersion,
+		PreConfHeight:    blockHeader.PreConfHeight,
+		TxCount:          blockHeader.TxCount,
+		TxRoot:           hex.EncodeToString(blockHeader.TxRoot),
+		DagHash:          hex.EncodeToString(blockHeader.DagHash),
+		RwSetRoot:        hex.EncodeToString(blockHeader.RwSetRoot),
+		BlockTimestamp:   blockHeader.BlockTimestamp,
+		ConsensusArgs:    string(blockHeader.ConsensusArgs),
+		ConsensusArgsHex: hex.EncodeToString(blockHeader.ConsensusArgs),
 	}
 
 	dbBlockDetails := &dbModel.BlockDetails{
 		BlockHash: dbBlock.BlockHash,
-		Dag:       blockInfo.Block.Dag.String(),
+	}
+
+	if dag := ParseBlockDag(blockInfo.Block); dag != nil {
+		dagJson, err := json.Marshal(dag)
+		if err != nil {
+			return nil, err
+		}
+
+		dbBlockDetails.Dag = string(dagJson)
 	}
 
 	if blockHeader.Proposer != nil {
@@ -58,16 +77,38 @@ func ParseBlock(blockInfo *common.BlockInfo) (*BlockData, error) {
 		}
 
 		dbBlock.ProposerOrgId = blockHeader.Proposer.OrgId
+		dbBlock.ProposerId, dbBlock.ProposerName = ProposerId(blockHeader.Proposer, hashType)
 		dbBlockDetails.ProposerBytes = proposerBytes
 		dbBlockDetails.ProposerSignature = base64.StdEncoding.EncodeToString(blockHeader.Signature)
 	}
 
+	if consensusInfo := DecodeConsensusInfo(blockInfo.Block); consensusInfo != nil {
+		consensusInfoJson, err := json.Marshal(consensusInfo)
+		if err != nil {
+			return nil, err
+		}
+
+		dbBlock.ConsensusRound = consensusInfo.Round
+		dbBlockDetails.ConsensusInfo = string(consensusInfoJson)
+	}
+
 	blockData.Block = dbBlock
 	blockData.BlockDetails = dbBlockDetails
 
 	transactions := make([]*dbModel.Transaction, 0)
 	transactionDetails := make([]*dbModel.TxDetails, 0)
-	contracts := make([]*dbModel.Contract, 0)
+	contractHistories := make([]*dbModel.ContractHistory, 0)
+	txWrites := make([]*dbModel.TxWrite, 0)
+	contractEvents := make([]*dbModel.ContractEvent, 0)
+	chainConfigs := make([]*dbModel.ChainConfig, 0)
+
+	// 订阅时开启读写集才会携带
+	rwSets := make(map[string]*common.TxRWSet, len(blockInfo.RwsetList))
+	for _, rwSet := range blockInfo.RwsetList {
+		if rwSet != nil {
+			rwSets[rwSet.TxId] = rwSet
+		}
+	}
 
 	for _, t := range blockInfo.Block.Txs {
 		tx := &dbModel.Transaction{
@@ -77,6 +118,14 @@ func ParseBlock(blockInfo *common.BlockInfo) (*BlockData, error) {
 
 		txDetails := &dbModel.TxDetails{}
 
+		if t.Sender != nil && t.Sender.Signer != nil {
+			// 发送者身份无法解析或证书查询失败时不影响区块入库，地址留空，查询失败由certLookup记录日志
+			signer, err := ResolveCertHash(t.Sender.Signer, certLookup)
+			if err == nil {
+				tx.SenderAddress, tx.SenderEvmAddress, _ = MemberAddress(signer, hashType)
+			}
+		}
+
 		if t.Payload != nil {
 			tx.TxId = t.Payload.TxId
 			tx.ContractName = t.Payload.ContractName
@@ -104,6 +153,7 @@ func ParseBlock(blockInfo *common.BlockInfo) (*BlockData, error) {
 				txDetails.ContractResult = t.Result.ContractResult.Result
 				txDetails.ContractResultMessage = t.Result.ContractResult.Message
 				txDetails.GasUsed = t.Result.ContractResult.GasUsed
+				tx.GasUsed = t.Result.ContractResult.GasUsed
 
 				if len(t.Result.ContractResult.ContractEvent) != 0 {
 					eventJson, err := json.Marshal(t.Result.ContractResult.ContractEvent)
@@ -111,9 +161,34 @@ func ParseBlock(blockInfo *common.BlockInfo) (*BlockData, error) {
 						return nil, err
 					}
 					txDetails.ContractEventBytes = eventJson
+
+					// 只有成功的交易事件才会生效，才计入事件索引
+					if t.Result.Code == common.TxStatusCode_SUCCESS {
+						for i, e := range t.Result.ContractResult.ContractEvent {
+							eventData, err := json.Marshal(e.EventData)
+							if err != nil {
+								return nil, err
+							}
+
+							contractEvents = append(contractEvents, &dbModel.ContractEvent{
+								TxId:            tx.TxId,
+								BlockHeight:     blockHeader.BlockHeight,
+								ContractName:    e.ContractName,
+								ContractVersion: e.ContractVersion,
+								Topic:           e.Topic,
+								EventIndex:      i,
+								EventData:       string(eventData),
+								Timestamp:       tx.Timestamp,
+							})
+						}
+					}
 				}
 
-				if t.Payload.ContractName == syscontract.SystemContract_CONTRACT_MANAGE.String() {
+				operation, ok := contractOperations[t.Payload.Method]
+
+				if ok && t.Payload.ContractName == syscontract.SystemContract_CONTRACT_MANAGE.String() &&
+					t.Result.Code == common.TxStatusCode_SUCCESS && t.Result.ContractResult.Code == 0 {
+
 					pbContract := new(common.Contract)
 					err := proto.Unmarshal(t.Result.ContractResult.Result, pbContract)
 					if err != nil {
@@ -123,29 +198,36 @@ func ParseBlock(blockInfo *common.BlockInfo) (*BlockData, error) {
 						}
 					}
 
-					if t.Result.Code == common.TxStatusCode_SUCCESS &&
-						t.Result.ContractResult.Code == 0 {
-						c := &dbModel.Contract{
-							Name:         pbContract.Name,
-							Version:      pbContract.Version,
-							ChainId:      blockHeader.ChainId,
-							State:        pbContract.Status.String(),
-							RuntimeType:  pbContract.RuntimeType.String(),
-							CreatorOrgId: pbContract.Creator.OrgId,
-							Address:      pbContract.Address,
-							TxId:         t.Payload.TxId,
-							Height:       blockHeader.BlockHeight,
-							TxTimestamp:  t.Payload.Timestamp,
-						}
+					h := &dbModel.ContractHistory{
+						Name:        pbContract.Name,
+						Operation:   operation,
+						Version:     pbContract.Version,
+						ChainId:     blockHeader.ChainId,
+						State:       pbContract.Status.String(),
+						RuntimeType: pbContract.RuntimeType.String(),
+						Address:     pbContract.Address,
+						TxId:        t.Payload.TxId,
+						BlockHeight: blockHeader.BlockHeight,
+						Timestamp:   t.Payload.Timestamp,
+					}
+
+					if pbContract.Creator != nil {
 						creatorBytes, err := pbContract.Creator.Marshal()
 						if err != nil {
 							return nil, err
 						}
 
-						c.CreatorBytes = creatorBytes
+						h.CreatorOrgId = pbContract.Creator.OrgId
+						h.CreatorBytes = creatorBytes
+					}
 
-						contracts = append(contracts, c)
+					if t.Sender != nil && t.Sender.Signer != nil {
+						h.OperatorOrgId = t.Sender.Signer.OrgId
+						h.OperatorAddress = tx.SenderAddress
+						h.OperatorEvmAddress = tx.SenderEvmAddress
 					}
+
+					contractHistories = append(contractHistories, h)
 				}
 			}
 		}
@@ -175,80 +257,71 @@ func ParseBlock(blockInfo *common.BlockInfo) (*BlockData, error) {
 			txDetails.EndorsersBytes = endorserJson
 		}
 
-		transactions = append(transactions, tx)
-		transactionDetails = append(transactionDetails, txDetails)
-	}
-
-	blockData.Transactions = transactions
-	blockData.TransactionDetails = transactionDetails
-	blockData.Contracts = contracts
-
-	return blockData, nil
-}
-
-func StorageBlock(blockInfo *common.BlockInfo, genHash string, tableNum int,
-	gormDb *gorm.DB) error {
-	blockData, err := ParseBlock(blockInfo)
-	if err != nil {
-		return errors.New("fail to parse block, " + err.Error())
-	}
-
-	err = gormDb.Transaction(func(tx *gorm.DB) error {
-		// 查询链统计信息
-		chainInfo, err := dao.GetChainInfo(genHash, tx)
-		if err != nil {
-			return err
-		}
-
-		if chainInfo == nil {
-			chainInfo = &dbModel.ChainInfo{}
-		}
-
-		// 区块为单位解析，区块+1
-		blockAmount := chainInfo.BlockAmount + 1
-		txAmount := chainInfo.TxAmount + int(blockData.Block.TxCount)
+		if rwSet, ok := rwSets[tx.TxId]; ok {
+			if len(rwSet.TxReads) != 0 {
+				readsJson, err := json.Marshal(rwSet.TxReads)
+				if err != nil {
+					return nil, err
+				}
+				txDetails.TxReadsBytes = readsJson
+			}
 
-		err = dao.InsertOneObjectToDBByTableName(blockData.Block,
-			fmt.Sprintf(dbModel.TableNamePrefix_Block+"_%02d", tableNum), tx)
-		if err != nil {
-			return err
-		}
+			if len(rwSet.TxWrites) != 0 {
+				writesJson, err := json.Marshal(rwSet.TxWrites)
+				if err != nil {
+					return nil, err
+				}
+				txDetails.TxWritesBytes = writesJson
+			}
 
-		err = dao.InsertOneObjectToDBByTableName(blockData.BlockDetails,
-			fmt.Sprintf(dbModel.TableNamePrefix_BlockDetails+"_%02d", tableNum), tx)
-		if err != nil {
-			return err
-		}
+			// 只有成功的交易写集才会生效，才计入状态历史
+			if t.Result != nil && t.Result.Code == common.TxStatusCode_SUCCESS {
+				for i, w := range rwSet.TxWrites {
+					if len(w.Key) > dbModel.StateKeyMaxSize {
+						continue
+					}
 
-		for _, t := range blockData.Transactions {
-			err = dao.InsertOneObjectToDBByTableName(t,
-				fmt.Sprintf(dbModel.TableNamePrefix_Transaction+"_%02d", tableNum), tx)
-			if err != nil {
-				return err
+					txWrites = append(txWrites, &dbModel.TxWrite{
+						TxId:         tx.TxId,
+						BlockHeight:  blockHeader.BlockHeight,
+						WriteIndex:   i,
+						ContractName: w.ContractName,
+						StateKey:     w.Key,
+						StateValue:   w.Value,
+						Timestamp:    tx.Timestamp,
+					})
+				}
 			}
 		}
 
-		for _, td := range blockData.TransactionDetails {
-			err = dao.InsertOneObjectToDBByTableName(td,
-				fmt.Sprintf(dbModel.TableNamePrefix_TxDetails+"_%02d", tableNum), tx)
-			if err != nil {
-				return err
-			}
-		}
+		// 链配置变更交易，记录变更后的完整链配置
+		if tx.ContractName == syscontract.SystemContract_CHAIN_CONFIG.String() &&
+			t.Result != nil && t.Result.Code == common.TxStatusCode_SUCCESS {
+			if chainConfig := decodeChainConfig(t, rwSets[tx.TxId]); chainConfig != nil {
+				record, err := NewChainConfigRecord(chainConfig)
+				if err != nil {
+					return nil, err
+				}
 
-		for _, c := range blockData.Contracts {
-			err = dao.InsertOneObjectToDBByTableName(c,
-				fmt.Sprintf(dbModel.TableNamePrefix_Contract+"_%02d", tableNum), tx)
-			if err != nil {
-				return err
+				record.BlockHeight = blockHeader.BlockHeight
+				record.TxId = tx.TxId
+				record.Method = tx.Method
+				record.Timestamp = tx.Timestamp
+
+				chainConfigs = append(chainConfigs, record)
 			}
 		}
 
-		return dao.UpdateChainTxAndBlockAmount(genHash, txAmount, blockAmount, tx)
-	})
-	if err != nil {
-		return errors.New("fail to insert block to db, " + err.Error())
+		transactions = append(transactions, tx)
+		transactionDetails = append(transactionDetails, txDetails)
 	}
 
-	return nil
+	blockData.Transactions = transactions
+	blockData.TransactionDetails = transactionDetails
+	blockData.ContractHistories = contractHistories
+	blockData.TxWrites = txWrites
+	blockData.ContractEvents = contractEvents
+	blockData.ChainConfigs = chainConfigs
+
+	return blockData, nil
 }
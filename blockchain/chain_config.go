@@ -0,0 +1,186 @@
+package blockchain
+
+import (
+	dbModel "chainmscan/db/model"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/pb-go/v2/config"
+	"chainmaker.org/chainmaker/pb-go/v2/syscontract"
+	"github.com/gogo/protobuf/proto"
+)
+
+const (
+	ConfigChange_Added   = "added"
+	ConfigChange_Removed = "removed"
+	ConfigChange_Changed = "changed"
+)
+
+// 对象数组中用于标识元素的字段，按顺序取第一个存在的字段
+var configElemKeyFields = []string{"org_id", "resource_name", "member_info"}
+
+// ConfigChange 两个链配置之间的一处差异，Path为字段路径
+type ConfigChange struct {
+	Path     string      `json:"path"`
+	Type     string      `json:"type"`
+	OldValue interface{} `json:"oldValue"`
+	NewValue interface{} `json:"newValue"`
+}
+
+// decodeChainConfig 解析链配置交易更新后的链配置，优先取合约执行结果，其次取读写集中的链配置写入
+func decodeChainConfig(t *common.Transaction, rwSet *common.TxRWSet) *config.ChainConfig {
+	if t.Result != nil && t.Result.ContractResult != nil && len(t.Result.ContractResult.Result) != 0 {
+		chainConfig := new(config.ChainConfig)
+		err := proto.Unmarshal(t.Result.ContractResult.Result, chainConfig)
+		if err == nil && len(chainConfig.ChainId) != 0 {
+			return chainConfig
+		}
+	}
+
+	if rwSet == nil {
+		return nil
+	}
+
+	configContract := syscontract.SystemContract_CHAIN_CONFIG.String()
+
+	for _, w := range rwSet.TxWrites {
+		if w.ContractName != configContract || string(w.Key) != configContract {
+			continue
+		}
+
+		chainConfig := new(config.ChainConfig)
+		err := proto.Unmarshal(w.Value, chainConfig)
+		if err == nil && len(chainConfig.ChainId) != 0 {
+			return chainConfig
+		}
+	}
+
+	return nil
+}
+
+// NewChainConfigRecord 链配置转换为变更记录
+func NewChainConfigRecord(chainConfig *config.ChainConfig) (*dbModel.ChainConfig, error) {
+	configJson, err := json.Marshal(chainConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &dbModel.ChainConfig{
+		Sequence:       chainConfig.Sequence,
+		Version:        chainConfig.Version,
+		AuthType:       chainConfig.AuthType,
+		TrustRootCount: len(chainConfig.TrustRoots),
+		ConfigJson:     string(configJson),
+	}
+
+	if chainConfig.Consensus != nil {
+		record.ConsensusType = chainConfig.Consensus.Type.String()
+		for _, org := range chainConfig.Consensus.Nodes {
+			record.ConsensusNodeCount += len(org.NodeId)
+		}
+	}
+
+	if chainConfig.Block != nil {
+		record.BlockTxCapacity = chainConfig.Block.BlockTxCapacity
+		record.BlockSize = chainConfig.Block.BlockSize
+		record.BlockInterval = chainConfig.Block.BlockInterval
+		record.TxTimeout = chainConfig.Block.TxTimeout
+	}
+
+	return record, nil
+}
+
+// DiffChainConfig 比较两个链配置JSON，返回按路径排序的差异列表
+func DiffChainConfig(oldJson, newJson string) ([]*ConfigChange, error) {
+	var oldConfig, newConfig interface{}
+
+	err := json.Unmarshal([]byte(oldJson), &oldConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(newJson), &newConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	oldFields, newFields := make(map[string]interface{}), make(map[string]interface{})
+	flattenConfig("", oldConfig, oldFields)
+	flattenConfig("", newConfig, newFields)
+
+	changes := make([]*ConfigChange, 0)
+
+	for path, oldValue := range oldFields {
+		newValue, ok := newFields[path]
+		if !ok {
+			changes = append(changes, &ConfigChange{Path: path, Type: ConfigChange_Removed, OldValue: oldValue})
+			continue
+		}
+
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, &ConfigChange{Path: path, Type: ConfigChange_Changed,
+				OldValue: oldValue, NewValue: newValue})
+		}
+	}
+
+	for path, newValue := range newFields {
+		if _, ok := oldFields[path]; !ok {
+			changes = append(changes, &ConfigChange{Path: path, Type: ConfigChange_Added, NewValue: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+// flattenConfig 将JSON展开为字段路径到叶子值的映射。对象数组的元素以标识字段作为路径，
+// 避免增删组织或节点时后续元素全部错位；标量数组整体作为叶子值比较
+func flattenConfig(prefix string, v interface{}, res map[string]interface{}) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			path := k
+			if len(prefix) != 0 {
+				path = prefix + "." + k
+			}
+			flattenConfig(path, child, res)
+		}
+
+	case []interface{}:
+		isObjects := len(value) != 0
+		for _, elem := range value {
+			if _, ok := elem.(map[string]interface{}); !ok {
+				isObjects = false
+				break
+			}
+		}
+
+		if !isObjects {
+			res[prefix] = value
+			return
+		}
+
+		for i, elem := range value {
+			flattenConfig(prefix+configElemKey(elem.(map[string]interface{}), i), elem, res)
+		}
+
+	default:
+		res[prefix] = value
+	}
+}
+
+func configElemKey(elem map[string]interface{}, index int) string {
+	for _, field := range configElemKeyFields {
+		if key, ok := elem[field].(string); ok && len(key) != 0 {
+			return fmt.Sprintf("[%s=%s]", field, key)
+		}
+	}
+
+	return fmt.Sprintf("[%d]", index)
+}
@@ -0,0 +1,127 @@
+package blockchain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffChainConfig(t *testing.T) {
+	cases := []struct {
+		name     string
+		old, new string
+		want     []*ConfigChange
+		wantErr  bool
+	}{
+		{
+			name: "identical",
+			old:  `{"block":{"tx_timeout":600},"trust_roots":[{"org_id":"org1","root":["ca1"]}]}`,
+			new:  `{"trust_roots":[{"root":["ca1"],"org_id":"org1"}],"block":{"tx_timeout":600}}`,
+			want: []*ConfigChange{},
+		},
+		{
+			name: "changed scalar",
+			old:  `{"block":{"tx_timeout":600,"block_tx_capacity":100}}`,
+			new:  `{"block":{"tx_timeout":900,"block_tx_capacity":100}}`,
+			want: []*ConfigChange{
+				{Path: "block.tx_timeout", Type: ConfigChange_Changed, OldValue: 600.0, NewValue: 900.0},
+			},
+		},
+		{
+			name: "added and removed fields sorted by path",
+			old:  `{"version":"v2.3.0","core":{"tx_scheduler_timeout":10}}`,
+			new:  `{"core":{"enable_sender_group":true},"version":"v2.3.0","auth_type":"permissionedWithCert"}`,
+			want: []*ConfigChange{
+				{Path: "auth_type", Type: ConfigChange_Added, NewValue: "permissionedWithCert"},
+				{Path: "core.enable_sender_group", Type: ConfigChange_Added, NewValue: true},
+				{Path: "core.tx_scheduler_timeout", Type: ConfigChange_Removed, OldValue: 10.0},
+			},
+		},
+		{
+			// 删除第一个组织时后续组织不错位
+			name: "org removed",
+			old:  `{"trust_roots":[{"org_id":"org1","root":["ca1"]},{"org_id":"org2","root":["ca2"]}]}`,
+			new:  `{"trust_roots":[{"org_id":"org2","root":["ca2"]}]}`,
+			want: []*ConfigChange{
+				{Path: "trust_roots[org_id=org1].org_id", Type: ConfigChange_Removed, OldValue: "org1"},
+				{Path: "trust_roots[org_id=org1].root", Type: ConfigChange_Removed,
+					OldValue: []interface{}{"ca1"}},
+			},
+		},
+		{
+			name: "scalar array as a whole",
+			old:  `{"trust_roots":[{"org_id":"org1","root":["ca1"]}]}`,
+			new:  `{"trust_roots":[{"org_id":"org1","root":["ca1","ca2"]}]}`,
+			want: []*ConfigChange{
+				{Path: "trust_roots[org_id=org1].root", Type: ConfigChange_Changed,
+					OldValue: []interface{}{"ca1"}, NewValue: []interface{}{"ca1", "ca2"}},
+			},
+		},
+		{
+			name: "element key fields in order",
+			old:  `{"resource_policies":[{"resource_name":"CHAIN_CONFIG-CORE_UPDATE","policy":{"rule":"MAJORITY"}}]}`,
+			new:  `{"resource_policies":[{"resource_name":"CHAIN_CONFIG-CORE_UPDATE","policy":{"rule":"ANY"}}]}`,
+			want: []*ConfigChange{
+				{Path: "resource_policies[resource_name=CHAIN_CONFIG-CORE_UPDATE].policy.rule",
+					Type: ConfigChange_Changed, OldValue: "MAJORITY", NewValue: "ANY"},
+			},
+		},
+		{
+			name: "elements without key by index",
+			old:  `{"consensus":{"ext_config":[{"key":"a","value":"1"}]}}`,
+			new:  `{"consensus":{"ext_config":[{"key":"a","value":"2"}]}}`,
+			want: []*ConfigChange{
+				{Path: "consensus.ext_config[0].value", Type: ConfigChange_Changed, OldValue: "1", NewValue: "2"},
+			},
+		},
+		{
+			name: "empty array to objects",
+			old:  `{"trust_members":[]}`,
+			new:  `{"trust_members":[{"member_info":"m1","role":"light"}]}`,
+			want: []*ConfigChange{
+				{Path: "trust_members", Type: ConfigChange_Removed, OldValue: []interface{}{}},
+				{Path: "trust_members[member_info=m1].member_info", Type: ConfigChange_Added, NewValue: "m1"},
+				{Path: "trust_members[member_info=m1].role", Type: ConfigChange_Added, NewValue: "light"},
+			},
+		},
+		{
+			name:    "invalid old",
+			old:     `{"block":`,
+			new:     `{}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid new",
+			old:     `{}`,
+			new:     `not json`,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		changes, err := DiffChainConfig(c.old, c.new)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: want an error", c.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: [%s]", c.name, err.Error())
+			continue
+		}
+
+		if !reflect.DeepEqual(changes, c.want) {
+			t.Errorf("%s: got %s, want %s", c.name, formatChanges(changes), formatChanges(c.want))
+		}
+	}
+}
+
+func formatChanges(changes []*ConfigChange) string {
+	s := "["
+	for _, c := range changes {
+		s += " " + c.Path + ":" + c.Type
+	}
+
+	return s + " ]"
+}
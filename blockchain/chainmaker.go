@@ -2,13 +2,26 @@ package blockchain
 
 import (
 	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"strings"
+	"sync"
+	"time"
 
 	cmsdk "chainmaker.org/chainmaker/sdk-go/v2"
 	"go.uber.org/zap"
 )
 
+// CertLookupMissTTL 未登记的证书哈希的缓存时间，过期后重新查询
+const CertLookupMissTTL = 5 * time.Minute
+
+// 链的身份认证模式
+const (
+	AuthType_PermissionedWithCert = "permissionedwithcert"
+	AuthType_PermissionedWithKey  = "permissionedwithkey"
+	AuthType_Public               = "public"
+)
+
 type NodeConnConfig struct {
 	Addr        string
 	ConnCount   int
@@ -29,12 +42,18 @@ type ClientConfig struct {
 	TlsCertBytes                                                []byte
 	RpcClientMaxReceiveMessageSize, RpcClientMaxSendMessageSize int
 	ArchiveCenterUrl                                            string
+	// 为空时有签名证书使用permissionedwithcert，否则使用public
+	AuthType string
 }
 
 type BlockChainClient struct {
 	client       *cmsdk.ChainClient
 	config       *ClientConfig
 	chainGenHash string
+	// 链配置的哈希算法，用于计算账户地址
+	chainHashType string
+	// 按证书哈希缓存的链上证书
+	certCache sync.Map
 }
 
 func (c *BlockChainClient) GetConfig() *ClientConfig {
@@ -49,6 +68,46 @@ func (c *BlockChainClient) GetChainGenHash() string {
 	return c.chainGenHash
 }
 
+func (c *BlockChainClient) GetChainHashType() string {
+	return c.chainHashType
+}
+
+// certCacheEntry 证书缓存项，cert为nil表示证书未登记，在expireAt之前不再查询
+type certCacheEntry struct {
+	cert     []byte
+	expireAt time.Time
+}
+
+// LookupCert 按证书哈希（hex）查询链上登记的证书并缓存，未登记时返回nil，
+// 未登记的结果缓存CertLookupMissTTL，查询失败时记录日志并返回错误
+func (c *BlockChainClient) LookupCert(certHash string) ([]byte, error) {
+	if v, ok := c.certCache.Load(certHash); ok {
+		entry := v.(*certCacheEntry)
+		if entry.cert != nil || time.Now().Before(entry.expireAt) {
+			return entry.cert, nil
+		}
+	}
+
+	certInfos, err := c.client.QueryCert([]string{certHash})
+	if err != nil {
+		c.config.Logger.Warnf("fail to query the cert, err: [%s], certHash: [%s]\n", err.Error(), certHash)
+		return nil, err
+	}
+
+	if certInfos != nil {
+		for _, info := range certInfos.CertInfos {
+			if info != nil && strings.EqualFold(info.Hash, certHash) && len(info.Cert) != 0 {
+				c.certCache.Store(certHash, &certCacheEntry{cert: info.Cert})
+				return info.Cert, nil
+			}
+		}
+	}
+
+	c.certCache.Store(certHash, &certCacheEntry{expireAt: time.Now().Add(CertLookupMissTTL)})
+
+	return nil, nil
+}
+
 func NewChainmakerClient(config *ClientConfig) (*BlockChainClient, error) {
 
 	optionList := make([]cmsdk.ChainClientOption, 0)
@@ -65,7 +124,22 @@ func NewChainmakerClient(config *ClientConfig) (*BlockChainClient, error) {
 		return nil, errors.New("the logger cannot be nil")
 	}
 
-	if len(config.SignCertBytes) == 0 {
+	authType, err := NormalizeAuthType(config.AuthType, len(config.SignCertBytes) != 0)
+	if err != nil {
+		return nil, err
+	}
+
+	config.AuthType = authType
+
+	if authType == AuthType_PermissionedWithCert {
+		if len(config.SignCertBytes) == 0 {
+			return nil, errors.New("the sign cert bytes cannot be empty")
+		}
+
+		optionList = append(optionList, cmsdk.WithAuthType(authType))
+
+	} else {
+		// 公钥模式使用签名私钥对应的公钥作为身份，需指定哈希算法
 		err := checkTheHashAlgo(config.HashAlgorithm)
 		if err != nil {
 			return nil, err
@@ -74,10 +148,7 @@ func NewChainmakerClient(config *ClientConfig) (*BlockChainClient, error) {
 		optionList = append(optionList,
 			cmsdk.WithCryptoConfig(
 				cmsdk.NewCryptoConfig(cmsdk.WithHashAlgo(config.HashAlgorithm))),
-			cmsdk.WithAuthType("public"))
-
-	} else {
-		optionList = append(optionList, cmsdk.WithAuthType("permissionedwithcert"))
+			cmsdk.WithAuthType(authType))
 	}
 
 	optionList = append(optionList,
@@ -90,10 +161,15 @@ func NewChainmakerClient(config *ClientConfig) (*BlockChainClient, error) {
 		cmsdk.WithUserCrtBytes(config.TlsCertBytes),
 	)
 
-	nodeOptionList := make([]cmsdk.NodeOption, 0)
+	if len(config.NodeConfs) == 0 {
+		return nil, errors.New("the node list cannot be empty")
+	}
 
+	// 每个节点单独配置，由SDK的连接池在可用节点间选择
 	for _, nodeConf := range config.NodeConfs {
 
+		nodeOptionList := make([]cmsdk.NodeOption, 0)
+
 		if nodeConf.UseTls {
 
 			nodeOptionList = append(nodeOptionList, cmsdk.WithNodeUseTLS(true))
@@ -124,10 +200,9 @@ func NewChainmakerClient(config *ClientConfig) (*BlockChainClient, error) {
 			nodeOptionList = append(nodeOptionList, cmsdk.WithNodeConnCnt(nodeConf.ConnCount))
 		}
 
+		optionList = append(optionList, cmsdk.AddChainClientNodeConfig(cmsdk.NewNodeConfig(nodeOptionList...)))
 	}
 
-	optionList = append(optionList, cmsdk.AddChainClientNodeConfig(cmsdk.NewNodeConfig(nodeOptionList...)))
-
 	rpcOptionList := make([]cmsdk.RPCClientOption, 0)
 
 	if config.RpcClientMaxReceiveMessageSize == 0 {
@@ -156,6 +231,19 @@ func NewChainmakerClient(config *ClientConfig) (*BlockChainClient, error) {
 
 	chainGenHash := hex.EncodeToString(block.Block.Hash())
 
+	chainConfig, err := client.GetChainConfig()
+	if err != nil {
+		return nil, errors.New("get chain config error, " + err.Error())
+	}
+
+	// 链配置没有哈希算法时，公钥模式使用订阅指定的哈希算法
+	chainHashType := DefaultHashType
+	if chainConfig.Crypto != nil && len(chainConfig.Crypto.Hash) != 0 {
+		chainHashType = chainConfig.Crypto.Hash
+	} else if len(config.HashAlgorithm) != 0 {
+		chainHashType = strings.ToUpper(config.HashAlgorithm)
+	}
+
 	if len(config.ArchiveCenterUrl) != 0 {
 
 		optionList = append(optionList, cmsdk.WithArchiveCenterQueryFirst(true),
@@ -175,12 +263,57 @@ func NewChainmakerClient(config *ClientConfig) (*BlockChainClient, error) {
 	}
 
 	return &BlockChainClient{
-		client:       client,
-		config:       config,
-		chainGenHash: chainGenHash,
+		client:        client,
+		config:        config,
+		chainGenHash:  chainGenHash,
+		chainHashType: chainHashType,
 	}, nil
 }
 
+// SplitCertPems 将拼接在一起的多个PEM证书拆分为列表，无法按PEM解析时整体作为一个证书
+func SplitCertPems(pemStr string) []string {
+	certs := make([]string, 0)
+
+	rest := []byte(pemStr)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		certs = append(certs, string(pem.EncodeToMemory(block)))
+	}
+
+	if len(certs) == 0 && len(strings.TrimSpace(pemStr)) != 0 {
+		certs = append(certs, pemStr)
+	}
+
+	return certs
+}
+
+// NormalizeAuthType 校验并统一身份认证模式为小写，为空时根据是否有签名证书选择默认模式
+func NormalizeAuthType(authType string, hasSignCert bool) (string, error) {
+	if len(authType) == 0 {
+		if hasSignCert {
+			return AuthType_PermissionedWithCert, nil
+		}
+
+		return AuthType_Public, nil
+	}
+
+	switch strings.ToLower(authType) {
+	case AuthType_PermissionedWithCert:
+		return AuthType_PermissionedWithCert, nil
+	case AuthType_PermissionedWithKey:
+		return AuthType_PermissionedWithKey, nil
+	case AuthType_Public:
+		return AuthType_Public, nil
+	}
+
+	return "", errors.New("the auth type is unknown")
+}
+
 func checkTheHashAlgo(hashAlgo string) error {
 	switch strings.ToLower(hashAlgo) {
 	case strings.ToLower("SHA256"):
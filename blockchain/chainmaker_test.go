@@ -0,0 +1,35 @@
+package blockchain
+
+import (
+	"encoding/pem"
+	"testing"
+)
+
+func TestSplitCertPems(t *testing.T) {
+	ca1 := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("ca1")}))
+	ca2 := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("ca2")}))
+
+	cases := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"empty", "", []string{}},
+		{"single", ca1, []string{ca1}},
+		{"joined", ca1 + "\n" + ca2, []string{ca1, ca2}},
+		{"not pem", "not a pem", []string{"not a pem"}},
+	}
+
+	for _, c := range cases {
+		got := SplitCertPems(c.in)
+		if len(got) != len(c.want) {
+			t.Fatalf("%s: got %d certs, want %d", c.name, len(got), len(c.want))
+		}
+
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: the cert %d is [%s], want [%s]", c.name, i, got[i], c.want[i])
+			}
+		}
+	}
+}
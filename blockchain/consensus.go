@@ -0,0 +1,116 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+	"chainmaker.org/chainmaker/pb-go/v2/consensus"
+	"chainmaker.org/chainmaker/pb-go/v2/consensus/tbft"
+	"github.com/gogo/protobuf/proto"
+)
+
+// TBFT共识在区块附加数据中保存投票信息的键
+const TBFTAdditionalDataKey = "TBFTAddtionalDataKey"
+
+// ConsensusInfo 区块头共识参数和共识投票的解析结果
+type ConsensusInfo struct {
+	ConsensusType string           `json:"consensusType"`
+	Round         int64            `json:"round"`
+	Level         int64            `json:"level"`
+	Votes         []*ConsensusVote `json:"votes,omitempty"`
+}
+
+// ConsensusVote 对区块的共识投票
+type ConsensusVote struct {
+	Voter string `json:"voter"`
+	Type  string `json:"type"`
+	Round int32  `json:"round"`
+}
+
+// DecodeConsensusInfo 解析区块头的ConsensusArgs和TBFT投票，无共识信息时返回nil
+func DecodeConsensusInfo(block *common.Block) *ConsensusInfo {
+	if block == nil || block.Header == nil {
+		return nil
+	}
+
+	var info *ConsensusInfo
+
+	if len(block.Header.ConsensusArgs) != 0 {
+		var args consensus.BlockHeaderConsensusArgs
+		// 不同共识的参数格式不同，无法解析时忽略
+		if err := proto.Unmarshal(block.Header.ConsensusArgs, &args); err == nil {
+			info = &ConsensusInfo{
+				ConsensusType: consensus.ConsensusType(args.ConsensusType).String(),
+				Round:         args.Round,
+				Level:         args.Level,
+			}
+		}
+	}
+
+	if block.AdditionalData == nil {
+		return info
+	}
+
+	qcBytes, ok := block.AdditionalData.ExtraData[TBFTAdditionalDataKey]
+	if !ok {
+		return info
+	}
+
+	var qc tbft.QuorumCert
+	if err := proto.Unmarshal(qcBytes, &qc); err != nil {
+		return info
+	}
+
+	if info == nil {
+		info = &ConsensusInfo{
+			ConsensusType: consensus.ConsensusType_TBFT.String(),
+		}
+	}
+
+	for _, v := range qc.Votes {
+		if v == nil {
+			continue
+		}
+
+		info.Votes = append(info.Votes, &ConsensusVote{
+			Voter: v.Voter,
+			Type:  v.Type.String(),
+			Round: v.Round,
+		})
+	}
+
+	return info
+}
+
+// ProposerId 出块节点的标识和名称：标识优先取节点地址，名称取证书CN
+func ProposerId(member *accesscontrol.Member, hashType string) (string, string) {
+	if member == nil {
+		return "", ""
+	}
+
+	var name string
+	if member.MemberType == accesscontrol.MemberType_CERT {
+		if cert, err := ParseCert(member.MemberInfo); err == nil {
+			name = cert.CommonName
+		}
+	}
+
+	addr, _, err := MemberAddress(member, hashType)
+	if err == nil && len(addr) != 0 {
+		return addr, name
+	}
+
+	switch member.MemberType {
+	case accesscontrol.MemberType_CERT_HASH:
+		return certHashString(member.MemberInfo), name
+
+	case accesscontrol.MemberType_CERT, accesscontrol.MemberType_PUBLIC_KEY:
+		// 证书或公钥无法解析时以内容哈希作为标识
+		h := sha256.Sum256(member.MemberInfo)
+		return hex.EncodeToString(h[:]), name
+	}
+
+	return string(member.MemberInfo), name
+}
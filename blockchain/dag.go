@@ -0,0 +1,135 @@
+package blockchain
+
+import (
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+)
+
+// BlockDag 区块内交易的依赖关系及其并行度指标
+type BlockDag struct {
+	Vertexes []*DagVertex `json:"vertexes"`
+	// 交易依赖边数
+	EdgeCount int `json:"edgeCount"`
+	// 同一层可并行执行的最大交易数
+	Width int `json:"width"`
+	// 最长依赖链上的交易数
+	CriticalPathLength int `json:"criticalPathLength"`
+}
+
+// DagVertex 交易顶点，Dependencies为所依赖交易在区块内的序号。
+// 入库时只保存序号，DependencyTxIds在返回前由ResolveDependencyTxIds填充
+type DagVertex struct {
+	Index           int      `json:"index"`
+	TxId            string   `json:"txId"`
+	Dependencies    []int    `json:"dependencies"`
+	DependencyTxIds []string `json:"dependencyTxIds,omitempty"`
+	// 所在层，从1开始，同层交易互不依赖
+	Level int `json:"level"`
+}
+
+// ParseBlockDag 将区块DAG的顶点映射为交易ID并计算并行度指标，区块没有DAG时返回nil
+func ParseBlockDag(block *common.Block) *BlockDag {
+	if block == nil || block.Dag == nil {
+		return nil
+	}
+
+	txIds := make([]string, len(block.Txs))
+	for i, tx := range block.Txs {
+		if tx != nil && tx.Payload != nil {
+			txIds[i] = tx.Payload.TxId
+		}
+	}
+
+	dag := &BlockDag{
+		Vertexes: make([]*DagVertex, 0, len(block.Dag.Vertexes)),
+	}
+
+	for i, v := range block.Dag.Vertexes {
+		vertex := &DagVertex{
+			Index:        i,
+			Dependencies: make([]int, 0),
+		}
+
+		if i < len(txIds) {
+			vertex.TxId = txIds[i]
+		}
+
+		if v != nil {
+			for _, n := range v.Neighbors {
+				// 忽略越界和自身的依赖
+				if int(n) >= len(block.Dag.Vertexes) || int(n) == i {
+					continue
+				}
+
+				vertex.Dependencies = append(vertex.Dependencies, int(n))
+			}
+		}
+
+		dag.EdgeCount += len(vertex.Dependencies)
+		dag.Vertexes = append(dag.Vertexes, vertex)
+	}
+
+	dag.computeLevels()
+
+	return dag
+}
+
+// ResolveDependencyTxIds 按依赖序号填充所依赖交易的ID
+func (d *BlockDag) ResolveDependencyTxIds() {
+	for _, v := range d.Vertexes {
+		v.DependencyTxIds = make([]string, 0, len(v.Dependencies))
+
+		for _, dep := range v.Dependencies {
+			if dep >= 0 && dep < len(d.Vertexes) {
+				v.DependencyTxIds = append(v.DependencyTxIds, d.Vertexes[dep].TxId)
+			} else {
+				v.DependencyTxIds = append(v.DependencyTxIds, "")
+			}
+		}
+	}
+}
+
+// computeLevels 计算每个顶点的层及DAG的宽度和关键路径长度
+func (d *BlockDag) computeLevels() {
+	// 0未计算，-1计算中（用于跳过异常的环）
+	levels := make([]int, len(d.Vertexes))
+
+	var level func(i int) int
+	level = func(i int) int {
+		if levels[i] > 0 {
+			return levels[i]
+		}
+
+		if levels[i] < 0 {
+			return 0
+		}
+
+		levels[i] = -1
+
+		l := 1
+		for _, dep := range d.Vertexes[i].Dependencies {
+			if depLevel := level(dep) + 1; depLevel > l {
+				l = depLevel
+			}
+		}
+
+		levels[i] = l
+		return l
+	}
+
+	widths := make(map[int]int)
+
+	for i, v := range d.Vertexes {
+		v.Level = level(i)
+		widths[v.Level]++
+
+		if v.Level > d.CriticalPathLength {
+			d.CriticalPathLength = v.Level
+		}
+	}
+
+	for _, w := range widths {
+		if w > d.Width {
+			d.Width = w
+		}
+	}
+}
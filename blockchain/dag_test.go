@@ -0,0 +1,94 @@
+package blockchain
+
+import (
+	"testing"
+
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+)
+
+// testDag 按每个顶点的依赖序号构造DAG
+func testDag(deps ...[]int) *BlockDag {
+	dag := &BlockDag{Vertexes: make([]*DagVertex, 0, len(deps))}
+	for i, d := range deps {
+		dag.Vertexes = append(dag.Vertexes, &DagVertex{Index: i, Dependencies: d})
+	}
+
+	return dag
+}
+
+func TestComputeLevels(t *testing.T) {
+	cases := []struct {
+		name         string
+		dag          *BlockDag
+		levels       []int
+		width        int
+		criticalPath int
+	}{
+		{"empty", testDag(), []int{}, 0, 0},
+		{"independent", testDag(nil, nil, nil), []int{1, 1, 1}, 3, 1},
+		{"chain", testDag(nil, []int{0}, []int{1}), []int{1, 2, 3}, 1, 3},
+		{"diamond", testDag(nil, []int{0}, []int{0}, []int{1, 2}), []int{1, 2, 2, 3}, 2, 3},
+		{"fan in", testDag(nil, nil, nil, []int{0, 1, 2}, nil), []int{1, 1, 1, 2, 1}, 4, 2},
+		// 异常的环不会无限递归，环上的依赖被跳过
+		{"cycle", testDag([]int{1}, []int{0}), []int{2, 1}, 1, 2},
+		{"self cycle", testDag([]int{0}), []int{1}, 1, 1},
+		{"cycle with tail", testDag([]int{2}, []int{0}, []int{1}, []int{2}), []int{3, 1, 2, 3}, 2, 3},
+	}
+
+	for _, c := range cases {
+		c.dag.computeLevels()
+
+		for i, v := range c.dag.Vertexes {
+			if v.Level != c.levels[i] {
+				t.Errorf("%s: the level of vertex %d is %d, want %d", c.name, i, v.Level, c.levels[i])
+			}
+		}
+
+		if c.dag.Width != c.width {
+			t.Errorf("%s: the width is %d, want %d", c.name, c.dag.Width, c.width)
+		}
+
+		if c.dag.CriticalPathLength != c.criticalPath {
+			t.Errorf("%s: the critical path length is %d, want %d", c.name, c.dag.CriticalPathLength,
+				c.criticalPath)
+		}
+	}
+}
+
+func TestParseBlockDag(t *testing.T) {
+	block := benchBlock(1, 3).Block
+	block.Dag = &common.DAG{
+		Vertexes: []*common.DAG_Neighbor{
+			{},
+			// 越界和自身的依赖被忽略
+			{Neighbors: []uint32{0, 1, 5}},
+			{Neighbors: []uint32{0, 1}},
+		},
+	}
+
+	dag := ParseBlockDag(block)
+	if dag == nil {
+		t.Fatal("the dag should not be nil")
+	}
+
+	if dag.EdgeCount != 3 || dag.Width != 1 || dag.CriticalPathLength != 3 {
+		t.Errorf("unexpected dag metrics: [%+v]", dag)
+	}
+
+	for _, v := range dag.Vertexes {
+		if v.DependencyTxIds != nil {
+			t.Errorf("the dependency tx ids of vertex %d should not be stored", v.Index)
+		}
+	}
+
+	dag.ResolveDependencyTxIds()
+
+	deps := dag.Vertexes[2].DependencyTxIds
+	if len(deps) != 2 || deps[0] != block.Txs[0].Payload.TxId || deps[1] != block.Txs[1].Payload.TxId {
+		t.Errorf("unexpected dependency tx ids: [%v]", deps)
+	}
+
+	if ParseBlockDag(&common.Block{}) != nil {
+		t.Error("the dag of a block without dag should be nil")
+	}
+}
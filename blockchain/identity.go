@@ -0,0 +1,457 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+	"github.com/gogo/protobuf/proto"
+)
+
+// Identity 链上成员（交易发送者、背书者、出块者、合约创建者）的身份信息
+type Identity struct {
+	OrgId      string `json:"orgId"`
+	MemberType string `json:"memberType"`
+	// 成员角色，证书成员取自证书OU
+	Role      string         `json:"role,omitempty"`
+	Cert      *CertInfo      `json:"cert,omitempty"`
+	PublicKey *PublicKeyInfo `json:"publicKey,omitempty"`
+	// 证书哈希成员的证书哈希（hex）
+	CertHash string `json:"certHash,omitempty"`
+	// DID、别名、地址类型成员的标识
+	MemberId string `json:"memberId,omitempty"`
+	// 身份内容解析失败的原因，此时MemberId为原始内容
+	DecodeError string `json:"decodeError,omitempty"`
+
+	memberInfo []byte
+}
+
+// CertInfo X.509证书的主要字段
+type CertInfo struct {
+	CommonName         string   `json:"commonName"`
+	Organization       []string `json:"organization"`
+	OrganizationalUnit []string `json:"organizationalUnit"`
+	Subject            string   `json:"subject"`
+	Issuer             string   `json:"issuer"`
+	IssuerCommonName   string   `json:"issuerCommonName"`
+	SerialNumber       string   `json:"serialNumber"`
+	NotBefore          int64    `json:"notBefore"`
+	NotAfter           int64    `json:"notAfter"`
+	SignatureAlgorithm string   `json:"signatureAlgorithm"`
+	PublicKeyAlgorithm string   `json:"publicKeyAlgorithm"`
+	PublicKeyBits      int      `json:"publicKeyBits"`
+	// 证书DER的sha256（hex）
+	Fingerprint string `json:"fingerprint"`
+	Pem         string `json:"pem"`
+}
+
+// PublicKeyInfo 公钥成员的公钥信息
+type PublicKeyInfo struct {
+	Algorithm string `json:"algorithm"`
+	Bits      int    `json:"bits"`
+	Pem       string `json:"pem"`
+}
+
+// ChainMaker证书OU中使用的角色
+var certRoles = map[string]struct{}{
+	"admin":     {},
+	"client":    {},
+	"consensus": {},
+	"common":    {},
+	"light":     {},
+}
+
+var (
+	oidPublicKeyRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidPublicKeyECDSA   = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidPublicKeyEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+)
+
+// 椭圆曲线名称和位数，国密证书使用ecPublicKey+SM2曲线
+var namedCurves = map[string]struct {
+	name string
+	bits int
+}{
+	"1.2.840.10045.3.1.7":   {"P-256", 256},
+	"1.3.132.0.34":          {"P-384", 384},
+	"1.3.132.0.35":          {"P-521", 521},
+	"1.3.132.0.10":          {"secp256k1", 256},
+	"1.2.156.10197.1.301":   {"SM2", 256},
+	"1.2.840.10045.3.1.1":   {"P-192", 192},
+	"1.3.132.0.33":          {"P-224", 224},
+	"1.2.156.10197.1.301.1": {"SM2", 256},
+}
+
+var signatureAlgorithms = map[string]string{
+	"1.2.840.113549.1.1.5":  "SHA1-RSA",
+	"1.2.840.113549.1.1.11": "SHA256-RSA",
+	"1.2.840.113549.1.1.12": "SHA384-RSA",
+	"1.2.840.113549.1.1.13": "SHA512-RSA",
+	"1.2.840.10045.4.1":     "ECDSA-SHA1",
+	"1.2.840.10045.4.3.2":   "ECDSA-SHA256",
+	"1.2.840.10045.4.3.3":   "ECDSA-SHA384",
+	"1.2.840.10045.4.3.4":   "ECDSA-SHA512",
+	"1.3.101.112":           "Ed25519",
+	"1.2.156.10197.1.501":   "SM2-SM3",
+}
+
+// 证书结构按ASN.1直接解析，crypto/x509不支持国密SM2证书
+type asn1Certificate struct {
+	TBSCertificate     asn1TBSCertificate
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+type asn1TBSCertificate struct {
+	Raw                asn1.RawContent
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           asn1Validity
+	Subject            asn1.RawValue
+	PublicKey          asn1PublicKeyInfo
+	UniqueId           asn1.BitString   `asn1:"optional,tag:1"`
+	SubjectUniqueId    asn1.BitString   `asn1:"optional,tag:2"`
+	Extensions         []pkix.Extension `asn1:"omitempty,optional,explicit,tag:3"`
+}
+
+type asn1Validity struct {
+	NotBefore, NotAfter time.Time
+}
+
+type asn1PublicKeyInfo struct {
+	Raw       asn1.RawContent
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type asn1RSAPublicKey struct {
+	N *big.Int
+	E int
+}
+
+// DecodeMember 按成员类型解析成员身份，身份内容无法解析时记录在DecodeError中
+func DecodeMember(orgId string, memberType accesscontrol.MemberType,
+	memberInfo []byte) *Identity {
+
+	identity := &Identity{
+		OrgId:      orgId,
+		MemberType: memberType.String(),
+		memberInfo: memberInfo,
+	}
+
+	var err error
+
+	switch memberType {
+	case accesscontrol.MemberType_CERT:
+		identity.Cert, err = ParseCert(memberInfo)
+		if err == nil {
+			identity.Role = certRole(identity.Cert.OrganizationalUnit)
+		}
+
+	case accesscontrol.MemberType_PUBLIC_KEY:
+		identity.PublicKey, err = ParsePublicKey(memberInfo)
+
+	case accesscontrol.MemberType_CERT_HASH:
+		identity.CertHash = certHashString(memberInfo)
+
+	default:
+		identity.MemberId = string(memberInfo)
+	}
+
+	if err != nil {
+		identity.DecodeError = err.Error()
+		identity.MemberId = string(memberInfo)
+	}
+
+	return identity
+}
+
+// MemberInfo 原始成员信息，用于兼容按字符串返回成员信息的接口字段
+func (i *Identity) MemberInfo() string {
+	return string(i.memberInfo)
+}
+
+// DecodeMemberBytes 解析序列化的Member，如区块出块者
+func DecodeMemberBytes(memberBytes []byte) (*Identity, error) {
+	if len(memberBytes) == 0 {
+		return nil, nil
+	}
+
+	var member accesscontrol.Member
+	err := proto.Unmarshal(memberBytes, &member)
+	if err != nil {
+		return nil, errors.New("fail to unmarshal the member, " + err.Error())
+	}
+
+	return DecodeMember(member.OrgId, member.MemberType, member.MemberInfo), nil
+}
+
+// DecodeMemberFullBytes 解析序列化的MemberFull，如合约创建者
+func DecodeMemberFullBytes(memberBytes []byte) (*Identity, error) {
+	if len(memberBytes) == 0 {
+		return nil, nil
+	}
+
+	var member accesscontrol.MemberFull
+	err := proto.Unmarshal(memberBytes, &member)
+	if err != nil {
+		return nil, errors.New("fail to unmarshal the member, " + err.Error())
+	}
+
+	identity := DecodeMember(member.OrgId, member.MemberType, member.MemberInfo)
+	if len(member.Role) != 0 {
+		identity.Role = member.Role
+	}
+
+	return identity, nil
+}
+
+// DecodeEndorsementBytes 解析序列化的EndorsementEntry，如交易发送者
+func DecodeEndorsementBytes(entryBytes []byte) (*Identity, error) {
+	if len(entryBytes) == 0 {
+		return nil, nil
+	}
+
+	var entry common.EndorsementEntry
+	err := proto.Unmarshal(entryBytes, &entry)
+	if err != nil {
+		return nil, errors.New("fail to unmarshal the endorsement entry, " + err.Error())
+	}
+
+	if entry.Signer == nil {
+		return nil, nil
+	}
+
+	return DecodeMember(entry.Signer.OrgId, entry.Signer.MemberType, entry.Signer.MemberInfo), nil
+}
+
+// EndorsementSigner 解析序列化的EndorsementEntry，返回签名成员
+func EndorsementSigner(entryBytes []byte) (*accesscontrol.Member, error) {
+	if len(entryBytes) == 0 {
+		return nil, nil
+	}
+
+	var entry common.EndorsementEntry
+	err := proto.Unmarshal(entryBytes, &entry)
+	if err != nil {
+		return nil, errors.New("fail to unmarshal the endorsement entry, " + err.Error())
+	}
+
+	return entry.Signer, nil
+}
+
+// DecodeEndorsersJson 解析TxDetails中以JSON保存的背书者列表
+func DecodeEndorsersJson(endorsersJson []byte) ([]*Identity, error) {
+	if len(endorsersJson) == 0 {
+		return nil, nil
+	}
+
+	var entries []*common.EndorsementEntry
+	err := json.Unmarshal(endorsersJson, &entries)
+	if err != nil {
+		return nil, errors.New("fail to unmarshal the endorsers, " + err.Error())
+	}
+
+	identities := make([]*Identity, 0, len(entries))
+	for _, e := range entries {
+		if e == nil || e.Signer == nil {
+			continue
+		}
+		identities = append(identities, DecodeMember(e.Signer.OrgId, e.Signer.MemberType,
+			e.Signer.MemberInfo))
+	}
+
+	return identities, nil
+}
+
+// ParseCert 解析PEM或DER格式的X.509证书，支持国密证书
+func ParseCert(certBytes []byte) (*CertInfo, error) {
+	der := pemToDer(certBytes)
+
+	cert, err := parseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	tbs := &cert.TBSCertificate
+
+	subject, err := parseName(tbs.Subject.FullBytes)
+	if err != nil {
+		return nil, errors.New("fail to parse the certificate subject, " + err.Error())
+	}
+
+	issuer, err := parseName(tbs.Issuer.FullBytes)
+	if err != nil {
+		return nil, errors.New("fail to parse the certificate issuer, " + err.Error())
+	}
+
+	keyAlgorithm, keyBits := publicKeyAlgorithm(&tbs.PublicKey)
+
+	fingerprint := sha256.Sum256(der)
+
+	info := &CertInfo{
+		CommonName:         subject.CommonName,
+		Organization:       subject.Organization,
+		OrganizationalUnit: subject.OrganizationalUnit,
+		Subject:            subject.String(),
+		Issuer:             issuer.String(),
+		IssuerCommonName:   issuer.CommonName,
+		NotBefore:          tbs.Validity.NotBefore.Unix(),
+		NotAfter:           tbs.Validity.NotAfter.Unix(),
+		SignatureAlgorithm: oidName(signatureAlgorithms, cert.SignatureAlgorithm.Algorithm),
+		PublicKeyAlgorithm: keyAlgorithm,
+		PublicKeyBits:      keyBits,
+		Fingerprint:        hex.EncodeToString(fingerprint[:]),
+		Pem:                string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	}
+
+	if tbs.SerialNumber != nil {
+		info.SerialNumber = tbs.SerialNumber.Text(16)
+	}
+
+	return info, nil
+}
+
+// ParsePublicKey 解析PEM或DER格式的PKIX公钥，支持国密公钥
+func ParsePublicKey(keyBytes []byte) (*PublicKeyInfo, error) {
+	der := pemToDer(keyBytes)
+
+	key, err := parsePublicKeyInfo(der)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, bits := publicKeyAlgorithm(key)
+
+	return &PublicKeyInfo{
+		Algorithm: algorithm,
+		Bits:      bits,
+		Pem:       string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	}, nil
+}
+
+// pemToDer PEM格式时取出DER内容，否则按DER处理
+func pemToDer(b []byte) []byte {
+	if block, _ := pem.Decode(b); block != nil {
+		return block.Bytes
+	}
+
+	return b
+}
+
+func parseCertificate(der []byte) (*asn1Certificate, error) {
+	var cert asn1Certificate
+	rest, err := asn1.Unmarshal(der, &cert)
+	if err != nil {
+		return nil, errors.New("fail to parse the certificate, " + err.Error())
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("fail to parse the certificate, trailing data")
+	}
+
+	return &cert, nil
+}
+
+func parsePublicKeyInfo(der []byte) (*asn1PublicKeyInfo, error) {
+	var key asn1PublicKeyInfo
+	rest, err := asn1.Unmarshal(der, &key)
+	if err != nil {
+		return nil, errors.New("fail to parse the public key, " + err.Error())
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("fail to parse the public key, trailing data")
+	}
+
+	return &key, nil
+}
+
+func parseName(raw []byte) (*pkix.Name, error) {
+	var rdn pkix.RDNSequence
+	_, err := asn1.Unmarshal(raw, &rdn)
+	if err != nil {
+		return nil, err
+	}
+
+	var name pkix.Name
+	name.FillFromRDNSequence(&rdn)
+	return &name, nil
+}
+
+// publicKeyAlgorithm 返回公钥算法名称和位数
+func publicKeyAlgorithm(key *asn1PublicKeyInfo) (string, int) {
+	oid := key.Algorithm.Algorithm
+
+	switch {
+	case oid.Equal(oidPublicKeyRSA):
+		var rsaKey asn1RSAPublicKey
+		_, err := asn1.Unmarshal(key.PublicKey.RightAlign(), &rsaKey)
+		if err != nil || rsaKey.N == nil {
+			return "RSA", 0
+		}
+		return "RSA", rsaKey.N.BitLen()
+
+	case oid.Equal(oidPublicKeyECDSA):
+		var curveOid asn1.ObjectIdentifier
+		_, err := asn1.Unmarshal(key.Algorithm.Parameters.FullBytes, &curveOid)
+		if err != nil {
+			return "ECDSA", 0
+		}
+
+		curve, ok := namedCurves[curveOid.String()]
+		if !ok {
+			return "ECDSA " + curveOid.String(), 0
+		}
+		if curve.name == "SM2" {
+			return curve.name, curve.bits
+		}
+		return "ECDSA " + curve.name, curve.bits
+
+	case oid.Equal(oidPublicKeyEd25519):
+		return "Ed25519", 256
+	}
+
+	return oid.String(), 0
+}
+
+func oidName(names map[string]string, oid asn1.ObjectIdentifier) string {
+	if name, ok := names[oid.String()]; ok {
+		return name
+	}
+	return oid.String()
+}
+
+// certRole 从证书OU中取ChainMaker角色，没有已知角色时取第一个OU
+func certRole(ous []string) string {
+	for _, ou := range ous {
+		if _, ok := certRoles[strings.ToLower(ou)]; ok {
+			return strings.ToLower(ou)
+		}
+	}
+
+	if len(ous) != 0 {
+		return ous[0]
+	}
+
+	return ""
+}
+
+// certHashString 证书哈希成员的MemberInfo可能是hex字符串或原始哈希字节
+func certHashString(memberInfo []byte) string {
+	s := string(memberInfo)
+	if _, err := hex.DecodeString(s); err == nil {
+		return strings.ToLower(s)
+	}
+
+	return hex.EncodeToString(memberInfo)
+}
@@ -0,0 +1,216 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
+)
+
+var (
+	oidCurveSM2    = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
+	oidSignatureSM = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 501}
+)
+
+var testCertTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testCert 生成自签名证书的DER
+func testCert(t *testing.T, pub, priv interface{}, ou string) []byte {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(0x1a2b),
+		Subject: pkix.Name{
+			CommonName:         "client1.sign.org1",
+			Organization:       []string{"org1"},
+			OrganizationalUnit: []string{ou},
+		},
+		NotBefore: testCertTime,
+		NotAfter:  testCertTime.AddDate(1, 0, 0),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		t.Fatalf("fail to create the certificate, err: [%s]", err.Error())
+	}
+
+	return der
+}
+
+// testSM2Cert 将ECDSA证书的曲线和签名算法替换为国密SM2，crypto/x509无法直接生成国密证书
+func testSM2Cert(t *testing.T, ecdsaDer []byte) []byte {
+	t.Helper()
+
+	cert, err := parseCertificate(ecdsaDer)
+	if err != nil {
+		t.Fatalf("fail to parse the certificate, err: [%s]", err.Error())
+	}
+
+	curve, err := asn1.Marshal(oidCurveSM2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tbs := &cert.TBSCertificate
+	tbs.Raw = nil
+	tbs.PublicKey.Raw = nil
+	tbs.PublicKey.Algorithm.Parameters = asn1.RawValue{FullBytes: curve}
+	tbs.SignatureAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidSignatureSM}
+	cert.SignatureAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidSignatureSM}
+
+	der, err := asn1.Marshal(*cert)
+	if err != nil {
+		t.Fatalf("fail to marshal the certificate, err: [%s]", err.Error())
+	}
+
+	return der
+}
+
+func TestParseCert(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaDer := testCert(t, &rsaKey.PublicKey, rsaKey, "admin")
+	ecDer := testCert(t, &ecKey.PublicKey, ecKey, "client")
+	sm2Der := testSM2Cert(t, ecDer)
+
+	cases := []struct {
+		name          string
+		in            []byte
+		der           []byte
+		keyAlgorithm  string
+		keyBits       int
+		signAlgorithm string
+		wantErr       bool
+	}{
+		{"rsa pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rsaDer}), rsaDer,
+			"RSA", 2048, "SHA256-RSA", false},
+		{"ecdsa der", ecDer, ecDer, "ECDSA P-256", 256, "ECDSA-SHA256", false},
+		{"sm2 der", sm2Der, sm2Der, "SM2", 256, "SM2-SM3", false},
+		{"sm2 pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: sm2Der}), sm2Der,
+			"SM2", 256, "SM2-SM3", false},
+		{"not a cert", []byte("not a cert"), nil, "", 0, "", true},
+		{"empty", nil, nil, "", 0, "", true},
+		{"trailing data", append(append([]byte{}, sm2Der...), 0x00), nil, "", 0, "", true},
+		{"truncated", sm2Der[:len(sm2Der)/2], nil, "", 0, "", true},
+	}
+
+	for _, c := range cases {
+		info, err := ParseCert(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: want an error", c.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: [%s]", c.name, err.Error())
+			continue
+		}
+
+		fingerprint := sha256.Sum256(c.der)
+
+		if info.CommonName != "client1.sign.org1" || len(info.Organization) != 1 ||
+			info.Organization[0] != "org1" || info.IssuerCommonName != "client1.sign.org1" {
+			t.Errorf("%s: unexpected subject: [%+v]", c.name, info)
+		}
+
+		if info.PublicKeyAlgorithm != c.keyAlgorithm || info.PublicKeyBits != c.keyBits ||
+			info.SignatureAlgorithm != c.signAlgorithm {
+			t.Errorf("%s: unexpected algorithms: [%s] [%d] [%s]", c.name, info.PublicKeyAlgorithm,
+				info.PublicKeyBits, info.SignatureAlgorithm)
+		}
+
+		if info.SerialNumber != "1a2b" || info.NotBefore != testCertTime.Unix() ||
+			info.NotAfter != testCertTime.AddDate(1, 0, 0).Unix() {
+			t.Errorf("%s: unexpected serial number or validity: [%+v]", c.name, info)
+		}
+
+		if info.Fingerprint != hex.EncodeToString(fingerprint[:]) {
+			t.Errorf("%s: unexpected fingerprint: [%s]", c.name, info.Fingerprint)
+		}
+
+		if block, _ := pem.Decode([]byte(info.Pem)); block == nil || string(block.Bytes) != string(c.der) {
+			t.Errorf("%s: the pem does not match the certificate", c.name)
+		}
+	}
+}
+
+func TestDecodeMember(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sm2Pem := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: testSM2Cert(t, testCert(t, &ecKey.PublicKey, ecKey, "Client")),
+	})
+
+	pubDer, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		memberType accesscontrol.MemberType
+		info       []byte
+		check      func(*Identity) bool
+	}{
+		{"sm2 cert", accesscontrol.MemberType_CERT, sm2Pem, func(i *Identity) bool {
+			return i.Cert != nil && i.Cert.PublicKeyAlgorithm == "SM2" && i.Role == "client" &&
+				len(i.DecodeError) == 0
+		}},
+		{"malformed cert", accesscontrol.MemberType_CERT, []byte("bad cert"), func(i *Identity) bool {
+			return i.Cert == nil && len(i.DecodeError) != 0 && i.MemberId == "bad cert"
+		}},
+		{"cert hash hex", accesscontrol.MemberType_CERT_HASH, []byte("ABCD"), func(i *Identity) bool {
+			return i.CertHash == "abcd"
+		}},
+		{"cert hash bytes", accesscontrol.MemberType_CERT_HASH, []byte{0x01, 0xab}, func(i *Identity) bool {
+			return i.CertHash == "01ab"
+		}},
+		{"public key", accesscontrol.MemberType_PUBLIC_KEY,
+			pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer}), func(i *Identity) bool {
+				return i.PublicKey != nil && i.PublicKey.Algorithm == "ECDSA P-256" && i.PublicKey.Bits == 256
+			}},
+		{"malformed public key", accesscontrol.MemberType_PUBLIC_KEY, []byte("bad key"), func(i *Identity) bool {
+			return i.PublicKey == nil && len(i.DecodeError) != 0 && i.MemberId == "bad key"
+		}},
+		{"did", accesscontrol.MemberType_DID, []byte("did:cnbn:123"), func(i *Identity) bool {
+			return i.MemberId == "did:cnbn:123" && len(i.DecodeError) == 0
+		}},
+	}
+
+	for _, c := range cases {
+		identity := DecodeMember("org1", c.memberType, c.info)
+
+		if identity.OrgId != "org1" || identity.MemberInfo() != string(c.info) {
+			t.Errorf("%s: unexpected org id or member info", c.name)
+		}
+
+		if !c.check(identity) {
+			t.Errorf("%s: unexpected identity: [%+v]", c.name, identity)
+		}
+	}
+}
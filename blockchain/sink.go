@@ -0,0 +1,370 @@
+package blockchain
+
+import (
+	"bytes"
+	"chainmscan/db"
+	"chainmscan/db/dao"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	dbModel "chainmscan/db/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	SinkType_File  = "file"
+	SinkType_Mysql = "mysql"
+)
+
+// BlockSink 区块导出目标，按高度顺序接收同一条链已解析的区块。
+// 导出为至少一次语义，写入成功但检查点未保存时区块可能被重复写入
+type BlockSink interface {
+	// Name 导出目标名称，作为检查点的标识
+	Name() string
+	// WriteBlocks 写入同一条链高度连续的区块
+	WriteBlocks(genHash string, blocks []*BlockData) error
+	// Reset 重新索引后删除已导出的高度不小于fromHeight的区块，之后从fromHeight重新导出
+	Reset(genHash string, fromHeight uint64) error
+	Close() error
+}
+
+type SinkConfig struct {
+	Name string `mapstructure:"name"`
+	// 导出类型：file、mysql
+	Type string `mapstructure:"type"`
+	// 导出的链genHash列表，为空时导出全部订阅链
+	Chains []string `mapstructure:"chains"`
+	// file：导出目录，每条链一个<genHash>.ndjson文件
+	Path string `mapstructure:"path"`
+	// mysql：导出的数据库
+	MysqlConfig *db.MysqlConfig `mapstructure:"mysql"`
+	GormConfig  *db.GormConfig  `mapstructure:"gorm_config"`
+}
+
+// Match 该导出目标是否导出指定的链
+func (c *SinkConfig) Match(genHash string) bool {
+	if len(c.Chains) == 0 {
+		return true
+	}
+
+	for _, v := range c.Chains {
+		if v == genHash {
+			return true
+		}
+	}
+
+	return false
+}
+
+func NewBlockSink(conf *SinkConfig, log *zap.SugaredLogger) (BlockSink, error) {
+	if len(conf.Name) == 0 {
+		return nil, errors.New("the sink name is empty")
+	}
+
+	switch conf.Type {
+	case SinkType_File:
+		return NewFileSink(conf.Name, conf.Path)
+	case SinkType_Mysql:
+		return NewMysqlSink(conf.Name, conf.MysqlConfig, conf.GormConfig, log)
+	default:
+		return nil, fmt.Errorf("unknown sink type [%s]", conf.Type)
+	}
+}
+
+// FileSink 将区块以每行一个JSON对象的格式追加写入文件，每批区块整行写入
+type FileSink struct {
+	name  string
+	path  string
+	mutex sync.Mutex
+	files map[string]*os.File
+}
+
+func NewFileSink(name, path string) (*FileSink, error) {
+	if len(path) == 0 {
+		return nil, errors.New("the path of file sink is empty")
+	}
+
+	err := os.MkdirAll(path, os.ModePerm)
+	if err != nil {
+		return nil, errors.New("fail to create the sink path, " + err.Error())
+	}
+
+	return &FileSink{
+		name:  name,
+		path:  path,
+		files: make(map[string]*os.File),
+	}, nil
+}
+
+func (s *FileSink) Name() string {
+	return s.name
+}
+
+func (s *FileSink) WriteBlocks(genHash string, blocks []*BlockData) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	f, ok := s.files[genHash]
+	if !ok {
+		var err error
+		f, err = os.OpenFile(filepath.Join(s.path, genHash+".ndjson"),
+			os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return errors.New("fail to open the sink file, " + err.Error())
+		}
+		s.files[genHash] = f
+	}
+
+	// 全部区块序列化成功后一次写入，编码失败时不写入任何内容
+	var buf bytes.Buffer
+	for _, b := range blocks {
+		line, err := json.Marshal(b)
+		if err != nil {
+			return errors.New("fail to encode the block, " + err.Error())
+		}
+
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return errors.New("fail to stat the sink file, " + err.Error())
+	}
+
+	_, err = f.Write(buf.Bytes())
+	if err != nil {
+		// 截断写入失败时残留的不完整行
+		if truncErr := f.Truncate(info.Size()); truncErr != nil {
+			return fmt.Errorf("fail to write the sink file, err: [%s], and fail to truncate it, err: [%s]",
+				err.Error(), truncErr.Error())
+		}
+
+		return errors.New("fail to write the sink file, " + err.Error())
+	}
+
+	return f.Sync()
+}
+
+// Reset 导出文件只追加写入，重新导出的区块追加在文件末尾，同一高度以最后一行为准
+func (s *FileSink) Reset(genHash string, fromHeight uint64) error {
+	return nil
+}
+
+func (s *FileSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for genHash, f := range s.files {
+		f.Close()
+		delete(s.files, genHash)
+	}
+
+	return nil
+}
+
+// MysqlSink 将区块写入另一个数据库，表结构与主库相同，分表序号由导出库自行分配
+type MysqlSink struct {
+	name   string
+	gormDb *gorm.DB
+	// 多条链的导出任务并发写入，串行分配分表序号
+	tableNumMutex sync.Mutex
+}
+
+func NewMysqlSink(name string, mysqlConf *db.MysqlConfig, gormConf *db.GormConfig,
+	log *zap.SugaredLogger) (*MysqlSink, error) {
+
+	if mysqlConf == nil {
+		return nil, errors.New("not found the mysql config of sink")
+	}
+
+	if gormConf == nil {
+		gormConf = &db.GormConfig{EnableAutoMigrate: true}
+	}
+
+	gormDb, err := db.MysqlInit(mysqlConf, gormConf, db.TableSlice, log)
+	if err != nil {
+		return nil, errors.New("fail to init the sink db, " + err.Error())
+	}
+
+	return &MysqlSink{
+		name:   name,
+		gormDb: gormDb,
+	}, nil
+}
+
+func (s *MysqlSink) Name() string {
+	return s.name
+}
+
+func (s *MysqlSink) WriteBlocks(genHash string, blocks []*BlockData) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	tableNum, err := s.getTableNum(genHash, blocks[0].Block.ChainId)
+	if err != nil {
+		return err
+	}
+
+	maxHeight, err := dao.MaxBlockHeightInDb(genHash, s.gormDb)
+	if err != nil {
+		return errors.New("fail to get max block height in db, " + err.Error())
+	}
+
+	// 写入成功但检查点未保存时重新导出，先删除已写入的区块再写入
+	if maxHeight >= int64(blocks[0].Block.BlockHeight) {
+		err = s.deleteFromHeight(genHash, tableNum, blocks[0].Block.BlockHeight)
+		if err != nil {
+			return err
+		}
+	}
+
+	list := make([]*BlockData, 0, len(blocks))
+	for _, b := range blocks {
+		list = append(list, cloneBlockData(b))
+	}
+
+	return StorageBlocks(list, genHash, tableNum, s.gormDb)
+}
+
+// Reset 删除导出库中链的高度不小于fromHeight的数据
+func (s *MysqlSink) Reset(genHash string, fromHeight uint64) error {
+	chainInfo, err := dao.GetChainInfo(genHash, s.gormDb)
+	if err != nil {
+		return errors.New("query chain info err, " + err.Error())
+	}
+
+	if chainInfo == nil {
+		return nil
+	}
+
+	return s.deleteFromHeight(genHash, chainInfo.TableNum, fromHeight)
+}
+
+// deleteFromHeight 删除高度不小于fromHeight的数据并按实际行数重置链统计信息
+func (s *MysqlSink) deleteFromHeight(genHash string, tableNum int, fromHeight uint64) error {
+	var err error
+	if fromHeight == 0 {
+		err = dao.TruncateChainData(tableNum, s.gormDb)
+	} else {
+		err = dao.DeleteChainDataFromHeight(tableNum, fromHeight, s.gormDb)
+	}
+	if err != nil {
+		return errors.New("fail to delete the chain data, " + err.Error())
+	}
+
+	err = dao.ResetChainTxAndBlockAmount(genHash, tableNum, s.gormDb)
+	if err != nil {
+		return errors.New("fail to reset the chain counters, " + err.Error())
+	}
+
+	return nil
+}
+
+// cloneBlockData 复制区块数据并清空主键和时间字段，同一批区块由多个导出目标共享，不能修改原数据
+func cloneBlockData(b *BlockData) *BlockData {
+	block, blockDetails := *b.Block, *b.BlockDetails
+	block.CommonField, blockDetails.CommonField = db.CommonField{}, db.CommonField{}
+
+	res := &BlockData{
+		Block:              &block,
+		BlockDetails:       &blockDetails,
+		Transactions:       make([]*dbModel.Transaction, 0, len(b.Transactions)),
+		TransactionDetails: make([]*dbModel.TxDetails, 0, len(b.TransactionDetails)),
+		ContractHistories:  make([]*dbModel.ContractHistory, 0, len(b.ContractHistories)),
+		TxWrites:           make([]*dbModel.TxWrite, 0, len(b.TxWrites)),
+		ContractEvents:     make([]*dbModel.ContractEvent, 0, len(b.ContractEvents)),
+		ChainConfigs:       make([]*dbModel.ChainConfig, 0, len(b.ChainConfigs)),
+	}
+
+	for _, v := range b.Transactions {
+		t := *v
+		t.CommonField = db.CommonField{}
+		res.Transactions = append(res.Transactions, &t)
+	}
+
+	for _, v := range b.TransactionDetails {
+		t := *v
+		t.CommonField = db.CommonField{}
+		res.TransactionDetails = append(res.TransactionDetails, &t)
+	}
+
+	for _, v := range b.ContractHistories {
+		t := *v
+		t.CommonField = db.CommonField{}
+		res.ContractHistories = append(res.ContractHistories, &t)
+	}
+
+	for _, v := range b.TxWrites {
+		t := *v
+		t.CommonField = db.CommonField{}
+		res.TxWrites = append(res.TxWrites, &t)
+	}
+
+	for _, v := range b.ContractEvents {
+		t := *v
+		t.CommonField = db.CommonField{}
+		res.ContractEvents = append(res.ContractEvents, &t)
+	}
+
+	for _, v := range b.ChainConfigs {
+		t := *v
+		t.CommonField = db.CommonField{}
+		res.ChainConfigs = append(res.ChainConfigs, &t)
+	}
+
+	return res
+}
+
+func (s *MysqlSink) getTableNum(genHash, chainId string) (int, error) {
+	s.tableNumMutex.Lock()
+	defer s.tableNumMutex.Unlock()
+
+	chainInfo, err := dao.GetChainInfo(genHash, s.gormDb)
+	if err != nil {
+		return 0, errors.New("query chain info err, " + err.Error())
+	}
+
+	if chainInfo != nil {
+		return chainInfo.TableNum, nil
+	}
+
+	maxTableNum, err := dao.GetMaxTableNumOfChainInfo(s.gormDb)
+	if err != nil {
+		return 0, errors.New("query the max table num err, " + err.Error())
+	}
+
+	chainInfo = &dbModel.ChainInfo{
+		ChainId:  chainId,
+		GenHash:  genHash,
+		TableNum: maxTableNum + 1,
+	}
+
+	err = db.MigrateShardTables(s.gormDb, chainInfo.TableNum)
+	if err != nil {
+		return 0, errors.New("fail to create the shard tables, " + err.Error())
+	}
+
+	err = dao.InsertOneObjectToDB(chainInfo, s.gormDb)
+	if err != nil {
+		return 0, errors.New("insert chaininfo to db err, " + err.Error())
+	}
+
+	return chainInfo.TableNum, nil
+}
+
+func (s *MysqlSink) Close() error {
+	sqlDB, err := s.gormDb.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.Close()
+}
@@ -0,0 +1,64 @@
+package blockchain
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	dbModel "chainmscan/db/model"
+)
+
+func TestFileSinkWriteBlocks(t *testing.T) {
+	dir := t.TempDir()
+
+	sink, err := NewFileSink("file", dir)
+	if err != nil {
+		t.Fatalf("fail to create the file sink, err: [%s]", err.Error())
+	}
+	defer sink.Close()
+
+	batch := func(from, to uint64) []*BlockData {
+		blocks := make([]*BlockData, 0)
+		for h := from; h < to; h++ {
+			blocks = append(blocks, &BlockData{
+				Block:        &dbModel.Block{BlockHeight: h},
+				BlockDetails: &dbModel.BlockDetails{},
+			})
+		}
+		return blocks
+	}
+
+	for _, b := range [][]*BlockData{batch(0, 3), batch(3, 5)} {
+		err = sink.WriteBlocks("genHash", b)
+		if err != nil {
+			t.Fatalf("fail to write blocks, err: [%s]", err.Error())
+		}
+	}
+
+	f, err := os.Open(filepath.Join(dir, "genHash.ndjson"))
+	if err != nil {
+		t.Fatalf("fail to open the sink file, err: [%s]", err.Error())
+	}
+	defer f.Close()
+
+	var height uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var b BlockData
+		err = json.Unmarshal(scanner.Bytes(), &b)
+		if err != nil {
+			t.Fatalf("the line %d is not a complete json, err: [%s]", height, err.Error())
+		}
+
+		if b.Block.BlockHeight != height {
+			t.Fatalf("the line %d has height %d", height, b.Block.BlockHeight)
+		}
+		height++
+	}
+
+	if height != 5 {
+		t.Fatalf("read %d blocks, want 5", height)
+	}
+}
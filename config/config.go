@@ -1,6 +1,7 @@
 package config
 
 import (
+	"chainmscan/blockchain"
 	"chainmscan/db"
 	"chainmscan/logger"
 	"errors"
@@ -13,16 +14,29 @@ import (
 )
 
 type Config struct {
-	ServerPort     string            `mapstructure:"server_port"`
-	LogConfig      *logger.LogConfig `mapstructure:"log_config"`
-	MysqlConfig    *db.MysqlConfig   `mapstructure:"mysql"`
-	GormConfig     *db.GormConfig    `mapstructure:"gorm_config"`
-	UploadFilePath string            `mapstructure:"upload_file_path"`
+	ServerPort     string                   `mapstructure:"server_port"`
+	LogConfig      *logger.LogConfig        `mapstructure:"log_config"`
+	MysqlConfig    *db.MysqlConfig          `mapstructure:"mysql"`
+	GormConfig     *db.GormConfig           `mapstructure:"gorm_config"`
+	UploadFilePath string                   `mapstructure:"upload_file_path"`
+	IngestConfig   *blockchain.IngestConfig `mapstructure:"ingest_config"`
+	// 定时校验链数据完整性的间隔（分钟），为0时不开启
+	IntegrityCheckInterval int `mapstructure:"integrity_check_interval"`
+	// 同步进度检查间隔（秒）
+	SyncStatusInterval int `mapstructure:"sync_status_interval"`
+	// 区块导出目标
+	Sinks []*blockchain.SinkConfig `mapstructure:"sinks"`
+	// 库内未查到交易或区块时是否从节点实时查询
+	LiveFallback bool `mapstructure:"live_fallback"`
+	// 统计切分自然日的时区相对UTC的偏移（分钟），默认0即UTC
+	StatsUtcOffset int `mapstructure:"stats_utc_offset"`
 }
 
 const (
 	DefaultServerPort     = "9660"
 	DefaultUploadFilePath = "./tmp"
+	// 默认同步进度检查间隔（秒）
+	DefaultSyncStatusInterval = 10
 )
 
 var configLastChangeTime time.Time
@@ -91,5 +105,29 @@ func InitConfig(configPath string) (*Config, error) {
 		conf.UploadFilePath = DefaultUploadFilePath
 	}
 
+	if conf.IngestConfig == nil {
+		conf.IngestConfig = &blockchain.IngestConfig{}
+	}
+
+	if conf.IngestConfig.ParseWorkers <= 0 {
+		conf.IngestConfig.ParseWorkers = blockchain.DefaultParseWorkers
+	}
+
+	if conf.IngestConfig.BatchBlocks <= 0 {
+		conf.IngestConfig.BatchBlocks = blockchain.DefaultBatchBlocks
+	}
+
+	if conf.IngestConfig.BackfillRangeSize <= 0 {
+		conf.IngestConfig.BackfillRangeSize = blockchain.DefaultBackfillRangeSize
+	}
+
+	if conf.SyncStatusInterval <= 0 {
+		conf.SyncStatusInterval = DefaultSyncStatusInterval
+	}
+
+	if conf.StatsUtcOffset < -12*60 || conf.StatsUtcOffset > 14*60 {
+		return nil, fmt.Errorf("the stats utc offset [%d] is out of range", conf.StatsUtcOffset)
+	}
+
 	return &conf, nil
 }
@@ -0,0 +1,298 @@
+package dao
+
+import (
+	"chainmscan/db"
+	dbModel "chainmscan/db/model"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// AddressSummary 账户地址的交易统计
+type AddressSummary struct {
+	SenderAddress    string
+	SenderEvmAddress string
+	SenderOrgId      string
+	TxCount          int64
+	FirstSeen        int64
+	LastSeen         int64
+}
+
+// AddressContractCall 账户调用合约的统计
+type AddressContractCall struct {
+	ContractName  string
+	TxCount       int64
+	FirstCallTime int64
+	LastCallTime  int64
+}
+
+// GetAddressSummary 统计地址发送的交易数和首次、最近出现时间，address可以是ChainMaker地址或EVM地址，
+// 地址没有交易时返回nil
+func GetAddressSummary(genHash, address string, gormDb *gorm.DB) (*AddressSummary, error) {
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return nil, err
+	}
+
+	if tableNum == 0 {
+		return nil, nil
+	}
+
+	var summary AddressSummary
+
+	err = gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)).
+		Select("MAX(sender_address) AS sender_address, MAX(sender_evm_address) AS sender_evm_address, "+
+			"MAX(sender_org_id) AS sender_org_id, COUNT(*) AS tx_count, "+
+			"MIN(timestamp) AS first_seen, MAX(timestamp) AS last_seen").
+		Where("(sender_address = ? OR sender_evm_address = ?)", address, address).
+		Scan(&summary).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if summary.TxCount == 0 {
+		return nil, nil
+	}
+
+	return &summary, nil
+}
+
+// GetAddressTxList 查询地址发送的交易，按区块高度倒序
+func GetAddressTxList(genHash, address string, page, pageSize int32,
+	gormDb *gorm.DB) ([]*dbModel.Transaction, int64, error) {
+
+	var list []*dbModel.Transaction
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return list, 0, err
+	}
+
+	if tableNum == 0 {
+		return nil, 0, nil
+	}
+
+	queryDb := gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)).
+		Where("(sender_address = ? OR sender_evm_address = ?)", address, address).
+		Session(&gorm.Session{})
+
+	var total int64
+
+	err = queryDb.Count(&total).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+
+	err = queryDb.Limit(int(pageSize)).Offset(int(offset)).
+		Order("block_height desc").Order("id desc").
+		Find(&list).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	return list, total, nil
+}
+
+// GetAddressCreatedContracts 查询地址创建的合约，按创建高度倒序
+func GetAddressCreatedContracts(genHash, address string, page, pageSize int32,
+	gormDb *gorm.DB) ([]*dbModel.ContractHistory, int64, error) {
+
+	var list []*dbModel.ContractHistory
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return list, 0, err
+	}
+
+	if tableNum == 0 {
+		return nil, 0, nil
+	}
+
+	queryDb := gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_ContractHistory, tableNum)).
+		Where("operation = ?", dbModel.ContractOperation_Init).
+		Where("(operator_address = ? OR operator_evm_address = ?)", address, address).
+		Session(&gorm.Session{})
+
+	var total int64
+
+	err = queryDb.Count(&total).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+
+	err = queryDb.Limit(int(pageSize)).Offset(int(offset)).
+		Order("block_height desc").Order("id desc").
+		Find(&list).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	return list, total, nil
+}
+
+// GetAddressCalledContracts 按合约统计地址发送的交易，按交易数倒序
+func GetAddressCalledContracts(genHash, address string, page, pageSize int32,
+	gormDb *gorm.DB) ([]*AddressContractCall, int64, error) {
+
+	var list []*AddressContractCall
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return list, 0, err
+	}
+
+	if tableNum == 0 {
+		return nil, 0, nil
+	}
+
+	queryDb := gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)).
+		Where("(sender_address = ? OR sender_evm_address = ?)", address, address).
+		Session(&gorm.Session{})
+
+	var total int64
+
+	err = queryDb.Distinct("contract_name").Count(&total).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+
+	err = queryDb.Select("contract_name, COUNT(*) AS tx_count, " +
+		"MIN(timestamp) AS first_call_time, MAX(timestamp) AS last_call_time").
+		Group("contract_name").
+		Order("tx_count desc").Order("contract_name").
+		Limit(int(pageSize)).Offset(int(offset)).
+		Scan(&list).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	return list, total, nil
+}
+
+// CountAddressCreatedContracts 统计地址创建的合约数
+func CountAddressCreatedContracts(genHash, address string, gormDb *gorm.DB) (int64, error) {
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return 0, err
+	}
+
+	if tableNum == 0 {
+		return 0, nil
+	}
+
+	var count int64
+
+	err = gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_ContractHistory, tableNum)).
+		Where("operation = ?", dbModel.ContractOperation_Init).
+		Where("(operator_address = ? OR operator_evm_address = ?)", address, address).
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// TxSender 待补齐发送者地址的交易及其序列化的发送者
+type TxSender struct {
+	Id          uint
+	SenderBytes []byte
+}
+
+// GetTxSendersWithoutAddress 按id升序查询id大于afterId且发送者地址为空的交易
+func GetTxSendersWithoutAddress(tableNum int, afterId uint, limit int,
+	gormDb *gorm.DB) ([]*TxSender, error) {
+
+	txTable := db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)
+	txDetailsTable := db.ShardTableName(dbModel.TableNamePrefix_TxDetails, tableNum)
+
+	var list []*TxSender
+
+	err := gormDb.Table("`"+txTable+"` t").
+		Joins("JOIN `"+txDetailsTable+"` d ON t.tx_id = d.tx_id").
+		Where("t.sender_address = '' AND t.id > ?", afterId).
+		Order("t.id").
+		Limit(limit).
+		Select("t.id, d.sender_bytes").
+		Scan(&list).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// TxSenderAddress 交易补齐后的发送者地址
+type TxSenderAddress struct {
+	Id         uint
+	Address    string
+	EvmAddress string
+}
+
+// UpdateTxSenderAddresses 按交易id批量更新发送者地址，一批交易只执行一条UPDATE语句
+func UpdateTxSenderAddresses(tableNum int, list []*TxSenderAddress, gormDb *gorm.DB) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	var (
+		addrCase    strings.Builder
+		evmAddrCase strings.Builder
+		addrArgs    = make([]interface{}, 0, 2*len(list))
+		evmAddrArgs = make([]interface{}, 0, 2*len(list))
+		ids         = make([]uint, 0, len(list))
+	)
+
+	addrCase.WriteString("CASE id")
+	evmAddrCase.WriteString("CASE id")
+
+	for _, v := range list {
+		addrCase.WriteString(" WHEN ? THEN ?")
+		evmAddrCase.WriteString(" WHEN ? THEN ?")
+		addrArgs = append(addrArgs, v.Id, v.Address)
+		evmAddrArgs = append(evmAddrArgs, v.Id, v.EvmAddress)
+		ids = append(ids, v.Id)
+	}
+
+	addrCase.WriteString(" END")
+	evmAddrCase.WriteString(" END")
+
+	return gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"sender_address":     gorm.Expr(addrCase.String(), addrArgs...),
+			"sender_evm_address": gorm.Expr(evmAddrCase.String(), evmAddrArgs...),
+		}).Error
+}
+
+// FinishSenderAddressMigration 交易发送者地址补齐后，同步合约操作者地址和每日发送者统计，并标记该链已完成迁移
+func FinishSenderAddressMigration(genHash string, tableNum int, gormDb *gorm.DB) error {
+	txTable := db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)
+	historyTable := db.ShardTableName(dbModel.TableNamePrefix_ContractHistory, tableNum)
+
+	return gormDb.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("UPDATE `" + historyTable + "` h JOIN `" + txTable + "` t ON h.tx_id = t.tx_id " +
+			"SET h.operator_address = t.sender_address, h.operator_evm_address = t.sender_evm_address " +
+			"WHERE h.operator_address = '' AND t.sender_address != ''").Error
+		if err != nil {
+			return err
+		}
+
+		err = RebuildDailySenders(tableNum, tx)
+		if err != nil {
+			return err
+		}
+
+		return tx.Table(dbModel.TableName_ChainInfo).
+			Where("gen_hash = ?", genHash).
+			Update("sender_address_migrated", true).Error
+	})
+}
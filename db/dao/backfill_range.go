@@ -0,0 +1,81 @@
+package dao
+
+import (
+	dbModel "chainmscan/db/model"
+	"database/sql"
+
+	"gorm.io/gorm"
+)
+
+func InsertBackfillRanges(ranges []*dbModel.BackfillRange, gormDb *gorm.DB) error {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	return gormDb.CreateInBatches(ranges, 500).Error
+}
+
+func GetUnfinishedBackfillRanges(genHash string,
+	gormDb *gorm.DB) ([]*dbModel.BackfillRange, error) {
+
+	var res []*dbModel.BackfillRange
+
+	err := gormDb.Table(dbModel.TableName_BackfillRange).
+		Where("gen_hash = ? AND done = ?", genHash, false).
+		Order("start_height").Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// UpdateBackfillRangeProgress 更新回填区间进度，需与区块入库在同一事务内执行
+func UpdateBackfillRangeProgress(id uint, nextHeight uint64, done bool,
+	gormDb *gorm.DB) error {
+	return gormDb.Table(dbModel.TableName_BackfillRange).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"next_height": nextHeight,
+			"done":        done,
+		}).Error
+}
+
+// ClampBackfillRanges 重新索引时删除起始高度不小于fromHeight的回填区间，
+// 跨越fromHeight的区间截断到fromHeight-1，之前的区间保留
+func ClampBackfillRanges(genHash string, fromHeight uint64, gormDb *gorm.DB) error {
+	return gormDb.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("gen_hash = ? AND start_height >= ?", genHash, fromHeight).
+			Delete(&dbModel.BackfillRange{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Table(dbModel.TableName_BackfillRange).
+			Where("gen_hash = ? AND start_height < ? AND end_height >= ?", genHash, fromHeight, fromHeight).
+			Updates(map[string]interface{}{
+				"end_height":  fromHeight - 1,
+				"next_height": gorm.Expr("LEAST(next_height, ?)", fromHeight),
+				"done":        gorm.Expr("next_height >= ?", fromHeight),
+			}).Error
+	})
+}
+
+// GetMaxBackfillEndHeight 查询链的回填区间的最大结束高度，没有回填区间时返回-1
+func GetMaxBackfillEndHeight(genHash string, gormDb *gorm.DB) (int64, error) {
+
+	var endHeight sql.NullInt64
+
+	err := gormDb.Table(dbModel.TableName_BackfillRange).
+		Select("MAX(end_height)").
+		Where("gen_hash = ?", genHash).Scan(&endHeight).Error
+	if err != nil {
+		return 0, err
+	}
+
+	if !endHeight.Valid {
+		return -1, nil
+	}
+
+	return endHeight.Int64, nil
+}
@@ -35,7 +35,7 @@ func MaxBlockHeightInDb(genHash string, gormDb *gorm.DB) (int64, error) {
 	return *max.MaxBlockHeight, nil
 }
 
-func GetBlockList(genHash string, page, pageSize int32,
+func GetBlockList(genHash string, page, pageSize int32, asc bool,
 	gormDb *gorm.DB) ([]*dbModel.Block, error) {
 
 	var list []*dbModel.Block
@@ -52,7 +52,7 @@ func GetBlockList(genHash string, page, pageSize int32,
 	offset := (page - 1) * pageSize
 
 	err = gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_Block+"_%02d", tableNum)).
-		Limit(int(pageSize)).Offset(int(offset)).Order("block_height desc").
+		Limit(int(pageSize)).Offset(int(offset)).Order("block_height " + sortOrder(asc)).
 		Find(&list).Error
 	if err != nil {
 		return list, err
@@ -61,6 +61,25 @@ func GetBlockList(genHash string, page, pageSize int32,
 	return list, nil
 }
 
+// GetBlockListByCursor 按区块高度游标分页查询区块列表
+func GetBlockListByCursor(genHash string, p *CursorPage,
+	gormDb *gorm.DB) ([]*dbModel.Block, *CursorResult, error) {
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tableNum == 0 {
+		return nil, new(CursorResult), nil
+	}
+
+	return findByCursor(gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_Block+"_%02d", tableNum)),
+		"block_height", p, func(b *dbModel.Block) (int64, uint) {
+			return int64(b.BlockHeight), b.ID
+		})
+}
+
 func GetBlockInfo(genHash string, blockHeight int64, blockHash string, id int,
 	gormDb *gorm.DB) (*dbModel.Block, int, error) {
 
@@ -120,3 +139,44 @@ func GetBlockTxCount(genHash string, blockHeight int64,
 
 	return txCount.Int64, nil
 }
+
+// BlockLink 区块链接关系，用于校验区块表的连续性
+type BlockLink struct {
+	ID           uint
+	BlockHeight  uint64
+	BlockHash    string
+	PreBlockHash string
+}
+
+// GetBlockLinks 按(高度,id)顺序分页读取区块链接关系，从(height,id)之后开始
+func GetBlockLinks(tableNum int, height uint64, id uint, limit int,
+	gormDb *gorm.DB) ([]*BlockLink, error) {
+
+	var list []*BlockLink
+
+	err := gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_Block+"_%02d", tableNum)).
+		Select("id, block_height, block_hash, pre_block_hash").
+		Where("block_height > ? OR (block_height = ? AND id > ?)", height, height, id).
+		Order("block_height").Order("id").
+		Limit(limit).Scan(&list).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// CountShardTableRows 统计链分表的实际行数
+func CountShardTableRows(tableNamePrefix string, tableNum int,
+	gormDb *gorm.DB) (int64, error) {
+
+	var count int64
+
+	err := gormDb.Table(fmt.Sprintf(tableNamePrefix+"_%02d", tableNum)).
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,75 @@
+package dao
+
+import (
+	dbModel "chainmscan/db/model"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// GetChainConfigAtHeight 查询在指定高度生效的链配置，即高度不大于blockHeight的最近一次变更
+func GetChainConfigAtHeight(genHash string, blockHeight uint64,
+	gormDb *gorm.DB) (*dbModel.ChainConfig, error) {
+
+	var chainConfig dbModel.ChainConfig
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return nil, err
+	}
+
+	if tableNum == 0 {
+		return nil, nil
+	}
+
+	err = gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_ChainConfig+"_%02d", tableNum)).
+		Where("block_height <= ?", blockHeight).
+		Order("block_height desc").Order("id desc").
+		First(&chainConfig).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &chainConfig, nil
+}
+
+// GetChainConfigList 查询链配置变更记录，按区块高度倒序
+func GetChainConfigList(genHash string, page, pageSize int32,
+	gormDb *gorm.DB) ([]*dbModel.ChainConfig, int64, error) {
+
+	var list []*dbModel.ChainConfig
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return list, 0, err
+	}
+
+	if tableNum == 0 {
+		return nil, 0, nil
+	}
+
+	queryDb := gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_ChainConfig+"_%02d", tableNum)).
+		Session(&gorm.Session{})
+
+	var total int64
+
+	err = queryDb.Count(&total).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+
+	err = queryDb.Omit("config_json").Limit(int(pageSize)).Offset(int(offset)).
+		Order("block_height desc").Order("id desc").
+		Find(&list).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	return list, total, nil
+}
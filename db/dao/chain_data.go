@@ -0,0 +1,124 @@
+package dao
+
+import (
+	"chainmscan/db"
+	dbModel "chainmscan/db/model"
+	"database/sql"
+
+	"gorm.io/gorm"
+)
+
+// TruncateChainData 清空链的全部分表
+func TruncateChainData(tableNum int, gormDb *gorm.DB) error {
+	for _, t := range db.ShardTableSlice {
+		err := gormDb.Exec("TRUNCATE TABLE `" + db.ShardTableName(t.TableName(), tableNum) + "`").Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteChainDataFromHeight 删除链分表中区块高度不小于fromHeight的数据
+func DeleteChainDataFromHeight(tableNum int, fromHeight uint64, gormDb *gorm.DB) error {
+	blockTable := db.ShardTableName(dbModel.TableNamePrefix_Block, tableNum)
+	txTable := db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)
+
+	return gormDb.Transaction(func(tx *gorm.DB) error {
+		// 详情表通过区块哈希和交易ID关联，需先于区块表和交易表删除
+		err := tx.Exec("DELETE FROM `"+db.ShardTableName(dbModel.TableNamePrefix_BlockDetails, tableNum)+
+			"` WHERE block_hash IN (SELECT block_hash FROM `"+blockTable+"` WHERE block_height >= ?)",
+			fromHeight).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec("DELETE FROM `"+db.ShardTableName(dbModel.TableNamePrefix_TxDetails, tableNum)+
+			"` WHERE tx_id IN (SELECT tx_id FROM `"+txTable+"` WHERE block_height >= ?)",
+			fromHeight).Error
+		if err != nil {
+			return err
+		}
+
+		// 删除生命周期记录后重新推导受影响合约的当前状态
+		var contractNames []string
+
+		historyDb := tx.Table(db.ShardTableName(dbModel.TableNamePrefix_ContractHistory, tableNum)).
+			Where("block_height >= ?", fromHeight).Session(&gorm.Session{})
+
+		err = historyDb.Distinct("name").Pluck("name", &contractNames).Error
+		if err != nil {
+			return err
+		}
+
+		err = historyDb.Delete(&dbModel.ContractHistory{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = RefreshContracts(tableNum, contractNames, tx)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Table(db.ShardTableName(dbModel.TableNamePrefix_TxWrite, tableNum)).
+			Where("block_height >= ?", fromHeight).Delete(&dbModel.TxWrite{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Table(db.ShardTableName(dbModel.TableNamePrefix_ContractEvent, tableNum)).
+			Where("block_height >= ?", fromHeight).Delete(&dbModel.ContractEvent{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Table(db.ShardTableName(dbModel.TableNamePrefix_ChainConfig, tableNum)).
+			Where("block_height >= ?", fromHeight).Delete(&dbModel.ChainConfig{}).Error
+		if err != nil {
+			return err
+		}
+
+		// 燃料汇总按时间段累加，删除交易后重新汇总受影响的时间段
+		var minTimestamp sql.NullInt64
+
+		err = tx.Table(txTable).Select("MIN(timestamp)").Where("block_height >= ?", fromHeight).
+			Scan(&minTimestamp).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Table(txTable).Where("block_height >= ?", fromHeight).Delete(&dbModel.Transaction{}).Error
+		if err != nil {
+			return err
+		}
+
+		if minTimestamp.Valid {
+			err = RebuildGasRollups(tableNum, minTimestamp.Int64, tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		// 每日统计按区块时间归属，删除区块后重新汇总受影响的日期
+		var minBlockTimestamp sql.NullInt64
+
+		err = tx.Table(blockTable).Select("MIN(block_timestamp)").Where("block_height >= ?", fromHeight).
+			Scan(&minBlockTimestamp).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Table(blockTable).Where("block_height >= ?", fromHeight).Delete(&dbModel.Block{}).Error
+		if err != nil {
+			return err
+		}
+
+		if minBlockTimestamp.Valid {
+			return RebuildDailyStats(tableNum, minBlockTimestamp.Int64, tx)
+		}
+
+		return nil
+	})
+}
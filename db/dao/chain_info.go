@@ -3,6 +3,7 @@ package dao
 import (
 	dbModel "chainmscan/db/model"
 	"database/sql"
+	"fmt"
 
 	"gorm.io/gorm"
 )
@@ -58,11 +59,27 @@ func SaveChainInfo(chainInfo *dbModel.ChainInfo, gormDb *gorm.DB) error {
 	return gormDb.Save(chainInfo).Error
 }
 
-func UpdateChainTxAndBlockAmount(genHash string,
-	txAmount, blockAmount int, gormDb *gorm.DB) error {
+// IncreaseChainTxAndBlockAmount 在数据库内原子累加链的交易数和区块数
+func IncreaseChainTxAndBlockAmount(genHash string,
+	txDelta, blockDelta int, gormDb *gorm.DB) error {
 	return gormDb.Table(dbModel.TableName_ChainInfo).
 		Where("gen_hash = ?", genHash).
-		Updates(dbModel.ChainInfo{TxAmount: txAmount, BlockAmount: blockAmount}).Error
+		Updates(map[string]interface{}{
+			"tx_amount":    gorm.Expr("tx_amount + ?", txDelta),
+			"block_amount": gorm.Expr("block_amount + ?", blockDelta),
+		}).Error
+}
+
+// ResetChainTxAndBlockAmount 按分表实际行数重置链的交易数和区块数
+func ResetChainTxAndBlockAmount(genHash string, tableNum int, gormDb *gorm.DB) error {
+	return gormDb.Table(dbModel.TableName_ChainInfo).
+		Where("gen_hash = ?", genHash).
+		Updates(map[string]interface{}{
+			"tx_amount": gorm.Expr(fmt.Sprintf("(SELECT COUNT(*) FROM `"+
+				dbModel.TableNamePrefix_Transaction+"_%02d`)", tableNum)),
+			"block_amount": gorm.Expr(fmt.Sprintf("(SELECT COUNT(*) FROM `"+
+				dbModel.TableNamePrefix_Block+"_%02d`)", tableNum)),
+		}).Error
 }
 
 func GetChainTxAmount(genHash string, gormDb *gorm.DB) (int64, error) {
@@ -78,3 +95,13 @@ func GetChainTxAmount(genHash string, gormDb *gorm.DB) (int64, error) {
 
 	return txAmount.Int64, nil
 }
+
+func GetAllChainInfo(gormDb *gorm.DB) ([]*dbModel.ChainInfo, error) {
+	var res []*dbModel.ChainInfo
+	err := gormDb.Table(dbModel.TableName_ChainInfo).Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -1,13 +1,15 @@
 package dao
 
 import (
+	"chainmscan/db"
 	dbModel "chainmscan/db/model"
 	"fmt"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func GetContractList(genHash string, page, pageSize int32,
+func GetContractList(genHash string, page, pageSize int32, asc bool,
 	gormDb *gorm.DB) ([]*dbModel.Contract, int64, error) {
 
 	var list []*dbModel.Contract
@@ -21,21 +23,19 @@ func GetContractList(genHash string, page, pageSize int32,
 		return nil, 0, nil
 	}
 
-	var total int64
+	queryDb := gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_Contract+"_%02d", tableNum)).
+		Session(&gorm.Session{})
 
-	sql1 := gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_Contract+"_%02d", tableNum)).
-		Select("name,MAX(tx_timestamp) AS time").Group("name")
+	var total int64
 
-	err = gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_Contract+"_%02d AS c1", tableNum)).
-		Joins("INNER JOIN (?) AS c2 ON c1.tx_timestamp = c2.time", sql1).Count(&total).Error
+	err = queryDb.Count(&total).Error
 	if err != nil {
 		return list, 0, err
 	}
 
 	offset := (page - 1) * pageSize
-	err = gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_Contract+"_%02d AS c1", tableNum)).
-		Joins("INNER JOIN (?) AS c2 ON c1.tx_timestamp = c2.time", sql1).
-		Limit(int(pageSize)).Offset(int(offset)).Find(&list).Error
+	err = queryDb.Omit("creator_bytes").Limit(int(pageSize)).Offset(int(offset)).
+		Order("tx_timestamp " + sortOrder(asc)).Order("id " + sortOrder(asc)).Find(&list).Error
 	if err != nil {
 		return list, 0, err
 	}
@@ -43,6 +43,25 @@ func GetContractList(genHash string, page, pageSize int32,
 	return list, total, nil
 }
 
+// GetContractListByCursor 按合约创建时间游标分页查询合约列表
+func GetContractListByCursor(genHash string, p *CursorPage,
+	gormDb *gorm.DB) ([]*dbModel.Contract, *CursorResult, error) {
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tableNum == 0 {
+		return nil, new(CursorResult), nil
+	}
+
+	return findByCursor(gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_Contract+"_%02d", tableNum)).
+		Omit("creator_bytes"), "tx_timestamp", p, func(c *dbModel.Contract) (int64, uint) {
+		return c.TxTimestamp, c.ID
+	})
+}
+
 func GetContractInfo(genHash string, contractName string, id uint,
 	gormDb *gorm.DB) (*dbModel.Contract, error) {
 
@@ -60,11 +79,11 @@ func GetContractInfo(genHash string, contractName string, id uint,
 	queryDb := gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_Contract+"_%02d", tableNum))
 
 	if len(contractName) != 0 {
-		queryDb = queryDb.Where("name = ?", contractName).Order("tx_timestamp DESC")
+		queryDb = queryDb.Where("name = ?", contractName)
 	}
 
 	if id != 0 {
-		queryDb = queryDb.Where("id = ?", id).Order("tx_timestamp DESC")
+		queryDb = queryDb.Where("id = ?", id)
 	}
 
 	err = queryDb.First(&contract).Error
@@ -78,3 +97,157 @@ func GetContractInfo(genHash string, contractName string, id uint,
 
 	return &contract, nil
 }
+
+// GetContractHistory 查询合约的生命周期记录，按区块高度倒序
+func GetContractHistory(genHash string, contractName string, page, pageSize int32,
+	gormDb *gorm.DB) ([]*dbModel.ContractHistory, int64, error) {
+
+	var list []*dbModel.ContractHistory
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return list, 0, err
+	}
+
+	if tableNum == 0 {
+		return nil, 0, nil
+	}
+
+	queryDb := gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_ContractHistory+"_%02d", tableNum)).
+		Where("name = ?", contractName).
+		Session(&gorm.Session{})
+
+	var total int64
+
+	err = queryDb.Count(&total).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err = queryDb.Omit("creator_bytes").Limit(int(pageSize)).Offset(int(offset)).
+		Order("block_height DESC").Order("id DESC").Find(&list).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	return list, total, nil
+}
+
+// RefreshContracts 根据生命周期记录重新推导合约当前状态：创建信息取init记录，
+// 版本、状态等取高度最大的记录。没有生命周期记录的合约删除当前状态
+func RefreshContracts(tableNum int, names []string, gormDb *gorm.DB) error {
+	historyTable := db.ShardTableName(dbModel.TableNamePrefix_ContractHistory, tableNum)
+	contractTable := db.ShardTableName(dbModel.TableNamePrefix_Contract, tableNum)
+
+	refreshed := make(map[string]struct{}, len(names))
+
+	for _, name := range names {
+		if _, ok := refreshed[name]; ok {
+			continue
+		}
+		refreshed[name] = struct{}{}
+
+		var list []*dbModel.ContractHistory
+
+		err := gormDb.Table(historyTable).Where("name = ?", name).
+			Order("block_height ASC").Order("id ASC").Find(&list).Error
+		if err != nil {
+			return err
+		}
+
+		if len(list) == 0 {
+			err = gormDb.Table(contractTable).Where("name = ?", name).Delete(&dbModel.Contract{}).Error
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		first, last := list[0], list[len(list)-1]
+		for _, h := range list {
+			if h.Operation == dbModel.ContractOperation_Init {
+				first = h
+				break
+			}
+		}
+
+		contract := &dbModel.Contract{
+			Name:            name,
+			Version:         last.Version,
+			ChainId:         last.ChainId,
+			RuntimeType:     last.RuntimeType,
+			State:           last.State,
+			CreatorOrgId:    first.CreatorOrgId,
+			Address:         last.Address,
+			TxId:            first.TxId,
+			Height:          first.BlockHeight,
+			TxTimestamp:     first.Timestamp,
+			CreatorBytes:    first.CreatorBytes,
+			UpdateTxId:      last.TxId,
+			UpdateHeight:    last.BlockHeight,
+			UpdateTimestamp: last.Timestamp,
+		}
+
+		err = gormDb.Table(contractTable).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			UpdateAll: true,
+		}).Create(contract).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MigrateLegacyContracts 旧版合约表每次合约管理操作一条记录，迁移为生命周期记录，
+// 合约表去重为每个合约一条当前状态。需在分表迁移（建立合约名唯一索引）之前执行
+func MigrateLegacyContracts(tableNum int, gormDb *gorm.DB) error {
+	historyTable := db.ShardTableName(dbModel.TableNamePrefix_ContractHistory, tableNum)
+	contractTable := db.ShardTableName(dbModel.TableNamePrefix_Contract, tableNum)
+
+	migrator := gormDb.Table(contractTable).Migrator()
+	if !migrator.HasTable(contractTable) || migrator.HasIndex(&dbModel.Contract{}, "name_index") {
+		return nil
+	}
+
+	err := gormDb.Table(historyTable).AutoMigrate(&dbModel.ContractHistory{})
+	if err != nil {
+		return err
+	}
+
+	var names []string
+
+	err = gormDb.Transaction(func(tx *gorm.DB) error {
+		// 无法区分旧记录的操作类型，每个合约最早的记录视为init，其余视为upgrade
+		err := tx.Exec("INSERT INTO `"+historyTable+"` (created_at, updated_at, name, operation, version, "+
+			"chain_id, runtime_type, state, creator_org_id, address, tx_id, block_height, timestamp, creator_bytes) "+
+			"SELECT NOW(), NOW(), name, IF(ROW_NUMBER() OVER (PARTITION BY name ORDER BY height, id) = 1, ?, ?), "+
+			"version, chain_id, runtime_type, state, creator_org_id, address, tx_id, height, tx_timestamp, creator_bytes "+
+			"FROM `"+contractTable+"` ORDER BY height, id",
+			dbModel.ContractOperation_Init, dbModel.ContractOperation_Upgrade).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Table(historyTable).Distinct("name").Pluck("name", &names).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec("DELETE FROM `" + contractTable + "`").Error
+	})
+	if err != nil {
+		return err
+	}
+
+	err = gormDb.Table(contractTable).AutoMigrate(&dbModel.Contract{})
+	if err != nil {
+		return err
+	}
+
+	return gormDb.Transaction(func(tx *gorm.DB) error {
+		return RefreshContracts(tableNum, names, tx)
+	})
+}
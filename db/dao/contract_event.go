@@ -0,0 +1,102 @@
+package dao
+
+import (
+	dbModel "chainmscan/db/model"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ContractEventFilter 合约事件查询条件，零值表示不过滤
+type ContractEventFilter struct {
+	ContractName string
+	Topic        string
+	StartHeight  uint64
+	EndHeight    uint64
+	StartTime    int64
+	EndTime      int64
+}
+
+func (f *ContractEventFilter) apply(queryDb *gorm.DB) *gorm.DB {
+	if len(f.ContractName) != 0 {
+		queryDb = queryDb.Where("contract_name = ?", f.ContractName)
+	}
+
+	if len(f.Topic) != 0 {
+		queryDb = queryDb.Where("topic = ?", f.Topic)
+	}
+
+	if f.StartHeight > 0 {
+		queryDb = queryDb.Where("block_height >= ?", f.StartHeight)
+	}
+
+	if f.EndHeight > 0 {
+		queryDb = queryDb.Where("block_height <= ?", f.EndHeight)
+	}
+
+	if f.StartTime > 0 {
+		queryDb = queryDb.Where("timestamp >= ?", f.StartTime)
+	}
+
+	if f.EndTime > 0 {
+		queryDb = queryDb.Where("timestamp <= ?", f.EndTime)
+	}
+
+	return queryDb
+}
+
+// GetContractEventList 按条件查询合约事件，按区块高度排序
+func GetContractEventList(genHash string, filter *ContractEventFilter, page, pageSize int32, asc bool,
+	gormDb *gorm.DB) ([]*dbModel.ContractEvent, int64, error) {
+
+	var list []*dbModel.ContractEvent
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return list, 0, err
+	}
+
+	if tableNum == 0 {
+		return nil, 0, nil
+	}
+
+	queryDb := filter.apply(gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_ContractEvent+"_%02d", tableNum))).
+		Session(&gorm.Session{})
+
+	var total int64
+
+	err = queryDb.Count(&total).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+
+	err = queryDb.Limit(int(pageSize)).Offset(int(offset)).
+		Order("block_height " + sortOrder(asc)).Order("id " + sortOrder(asc)).
+		Find(&list).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	return list, total, nil
+}
+
+// GetContractEventListByCursor 按条件以区块高度游标分页查询合约事件
+func GetContractEventListByCursor(genHash string, filter *ContractEventFilter, p *CursorPage,
+	gormDb *gorm.DB) ([]*dbModel.ContractEvent, *CursorResult, error) {
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tableNum == 0 {
+		return nil, new(CursorResult), nil
+	}
+
+	return findByCursor(filter.apply(gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_ContractEvent+"_%02d", tableNum))),
+		"block_height", p, func(e *dbModel.ContractEvent) (int64, uint) {
+			return int64(e.BlockHeight), e.ID
+		})
+}
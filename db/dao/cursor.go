@@ -0,0 +1,95 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+)
+
+// Cursor 游标分页的位置，Key为排序列的值，Id用于排序列相同时确定次序
+type Cursor struct {
+	Key int64 `json:"k"`
+	Id  uint  `json:"i"`
+	// 是否从该位置向前翻页
+	Prev bool `json:"p,omitempty"`
+}
+
+// CursorPage 游标分页参数，Cursor为空时查询第一页
+type CursorPage struct {
+	Cursor *Cursor
+	Asc    bool
+	Limit  int
+}
+
+// CursorResult 当前页前后两页的游标，没有数据时为空
+type CursorResult struct {
+	Next *Cursor
+	Prev *Cursor
+}
+
+// sortOrder 排序方向对应的SQL关键字
+func sortOrder(asc bool) string {
+	if asc {
+		return "asc"
+	}
+
+	return "desc"
+}
+
+// findByCursor 按(keyColumn, id)进行游标分页查询，结果按请求的排序方向返回。
+// keyOf返回记录的排序列值和ID，用于生成前后页游标
+func findByCursor[T any](queryDb *gorm.DB, keyColumn string, p *CursorPage,
+	keyOf func(*T) (int64, uint)) ([]*T, *CursorResult, error) {
+
+	prev := p.Cursor != nil && p.Cursor.Prev
+
+	// 向前翻页时反向查询，查询后再反转为请求的排序方向
+	asc := p.Asc != prev
+
+	if p.Cursor != nil {
+		op := "<"
+		if asc {
+			op = ">"
+		}
+
+		queryDb = queryDb.Where("("+keyColumn+" "+op+" ? OR ("+keyColumn+" = ? AND id "+op+" ?))",
+			p.Cursor.Key, p.Cursor.Key, p.Cursor.Id)
+	}
+
+	var list []*T
+
+	// 多查一条判断查询方向上是否还有数据
+	err := queryDb.Limit(p.Limit + 1).
+		Order(keyColumn + " " + sortOrder(asc)).Order("id " + sortOrder(asc)).
+		Find(&list).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hasMore := len(list) > p.Limit
+	if hasMore {
+		list = list[:p.Limit]
+	}
+
+	if prev {
+		for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+			list[i], list[j] = list[j], list[i]
+		}
+	}
+
+	res := new(CursorResult)
+
+	if len(list) == 0 {
+		return list, res, nil
+	}
+
+	if prev || hasMore {
+		key, id := keyOf(list[len(list)-1])
+		res.Next = &Cursor{Key: key, Id: id}
+	}
+
+	if (prev && hasMore) || (!prev && p.Cursor != nil) {
+		key, id := keyOf(list[0])
+		res.Prev = &Cursor{Key: key, Id: id, Prev: true}
+	}
+
+	return list, res, nil
+}
@@ -0,0 +1,253 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// cursorRow 游标分页测试的记录，按(height, id)排序
+type cursorRow struct {
+	Id     uint
+	Height int64
+}
+
+var limitRegexp = regexp.MustCompile(`LIMIT (\d+)`)
+
+// cursorConn 只支持findByCursor生成的查询，按查询条件、排序和条数从内存数据中返回结果
+type cursorConn struct {
+	rows []*cursorRow
+}
+
+func (c *cursorConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+
+func (c *cursorConn) Close() error {
+	return nil
+}
+
+func (c *cursorConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transaction is not supported")
+}
+
+func (c *cursorConn) QueryContext(ctx context.Context, query string,
+	args []driver.NamedValue) (driver.Rows, error) {
+
+	asc := strings.Contains(query, "height asc")
+
+	list := make([]*cursorRow, 0, len(c.rows))
+	for _, r := range c.rows {
+		if len(args) == 3 {
+			key, id := args[0].Value.(int64), uint(args[2].Value.(int64))
+
+			after := r.Height > key || (r.Height == key && r.Id > id)
+			before := r.Height < key || (r.Height == key && r.Id < id)
+
+			if (strings.Contains(query, "height > ?") && !after) || (strings.Contains(query, "height < ?") && !before) {
+				continue
+			}
+		}
+
+		list = append(list, r)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		less := list[i].Height < list[j].Height || (list[i].Height == list[j].Height && list[i].Id < list[j].Id)
+		if asc {
+			return less
+		}
+		return !less
+	})
+
+	if m := limitRegexp.FindStringSubmatch(query); m != nil {
+		limit, _ := strconv.Atoi(m[1])
+		if limit < len(list) {
+			list = list[:limit]
+		}
+	}
+
+	return &cursorRows{list: list}, nil
+}
+
+type cursorRows struct {
+	list []*cursorRow
+}
+
+func (r *cursorRows) Columns() []string {
+	return []string{"id", "height"}
+}
+
+func (r *cursorRows) Close() error {
+	return nil
+}
+
+func (r *cursorRows) Next(dest []driver.Value) error {
+	if len(r.list) == 0 {
+		return io.EOF
+	}
+
+	dest[0], dest[1] = int64(r.list[0].Id), r.list[0].Height
+	r.list = r.list[1:]
+
+	return nil
+}
+
+type cursorConnector struct {
+	conn *cursorConn
+}
+
+func (c *cursorConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.conn, nil
+}
+
+func (c *cursorConnector) Driver() driver.Driver {
+	return nil
+}
+
+// cursorDb 按高度1-4、每个高度两条记录构造数据，id从1到8
+func cursorDb(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	rows := make([]*cursorRow, 0)
+	for id := uint(1); id <= 8; id++ {
+		rows = append(rows, &cursorRow{Id: id, Height: int64(id+1) / 2})
+	}
+
+	gormDb, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(&cursorConnector{conn: &cursorConn{rows: rows}}),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("fail to open the db, err: [%s]", err.Error())
+	}
+
+	return gormDb
+}
+
+func TestFindByCursor(t *testing.T) {
+	gormDb := cursorDb(t)
+
+	cases := []struct {
+		name     string
+		page     *CursorPage
+		wantIds  []uint
+		wantNext *Cursor
+		wantPrev *Cursor
+	}{
+		{
+			name:     "first page desc",
+			page:     &CursorPage{Limit: 3},
+			wantIds:  []uint{8, 7, 6},
+			wantNext: &Cursor{Key: 3, Id: 6},
+		},
+		{
+			name:     "next page desc",
+			page:     &CursorPage{Cursor: &Cursor{Key: 3, Id: 6}, Limit: 3},
+			wantIds:  []uint{5, 4, 3},
+			wantNext: &Cursor{Key: 2, Id: 3},
+			wantPrev: &Cursor{Key: 3, Id: 5, Prev: true},
+		},
+		{
+			name:     "last page desc",
+			page:     &CursorPage{Cursor: &Cursor{Key: 2, Id: 3}, Limit: 3},
+			wantIds:  []uint{2, 1},
+			wantPrev: &Cursor{Key: 1, Id: 2, Prev: true},
+		},
+		{
+			name:     "prev page desc",
+			page:     &CursorPage{Cursor: &Cursor{Key: 1, Id: 2, Prev: true}, Limit: 3},
+			wantIds:  []uint{5, 4, 3},
+			wantNext: &Cursor{Key: 2, Id: 3},
+			wantPrev: &Cursor{Key: 3, Id: 5, Prev: true},
+		},
+		{
+			name:     "prev to first page desc",
+			page:     &CursorPage{Cursor: &Cursor{Key: 3, Id: 5, Prev: true}, Limit: 3},
+			wantIds:  []uint{8, 7, 6},
+			wantNext: &Cursor{Key: 3, Id: 6},
+		},
+		{
+			name:     "first page asc",
+			page:     &CursorPage{Asc: true, Limit: 5},
+			wantIds:  []uint{1, 2, 3, 4, 5},
+			wantNext: &Cursor{Key: 3, Id: 5},
+		},
+		{
+			name:     "next page asc",
+			page:     &CursorPage{Cursor: &Cursor{Key: 3, Id: 5}, Asc: true, Limit: 5},
+			wantIds:  []uint{6, 7, 8},
+			wantPrev: &Cursor{Key: 3, Id: 6, Prev: true},
+		},
+		{
+			name:    "all in one page",
+			page:    &CursorPage{Limit: 8},
+			wantIds: []uint{8, 7, 6, 5, 4, 3, 2, 1},
+		},
+		{
+			name:    "after the last row",
+			page:    &CursorPage{Cursor: &Cursor{Key: 1, Id: 1}, Limit: 3},
+			wantIds: []uint{},
+		},
+	}
+
+	for _, c := range cases {
+		list, res, err := findByCursor(gormDb.Table("cursor_row"), "height", c.page,
+			func(r *cursorRow) (int64, uint) {
+				return r.Height, r.Id
+			})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: [%s]", c.name, err.Error())
+		}
+
+		ids := make([]uint, 0, len(list))
+		for _, r := range list {
+			ids = append(ids, r.Id)
+		}
+
+		if !equalIds(ids, c.wantIds) {
+			t.Errorf("%s: got ids %v, want %v", c.name, ids, c.wantIds)
+		}
+
+		if !equalCursor(res.Next, c.wantNext) {
+			t.Errorf("%s: got next cursor %+v, want %+v", c.name, res.Next, c.wantNext)
+		}
+
+		if !equalCursor(res.Prev, c.wantPrev) {
+			t.Errorf("%s: got prev cursor %+v, want %+v", c.name, res.Prev, c.wantPrev)
+		}
+	}
+}
+
+func equalIds(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalCursor(a, b *Cursor) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
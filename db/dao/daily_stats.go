@@ -0,0 +1,190 @@
+package dao
+
+import (
+	"chainmscan/db"
+	dbModel "chainmscan/db/model"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpsertDailyStats 累加每日统计，并按当天新增的发送者重新计算去重后的发送者数
+func UpsertDailyStats(stats []*dbModel.ChainDailyStats, senders []*dbModel.ChainDailySender,
+	tableNum int, gormDb *gorm.DB) error {
+
+	if len(stats) == 0 {
+		return nil
+	}
+
+	statsTable := db.ShardTableName(dbModel.TableNamePrefix_ChainDailyStats, tableNum)
+	senderTable := db.ShardTableName(dbModel.TableNamePrefix_ChainDailySender, tableNum)
+
+	err := gormDb.Table(statsTable).
+		Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"block_count":        gorm.Expr("block_count + VALUES(block_count)"),
+				"tx_count":           gorm.Expr("tx_count + VALUES(tx_count)"),
+				"failed_tx_count":    gorm.Expr("failed_tx_count + VALUES(failed_tx_count)"),
+				"gas_used":           gorm.Expr("gas_used + VALUES(gas_used)"),
+				"new_contract_count": gorm.Expr("new_contract_count + VALUES(new_contract_count)"),
+				"first_block_time":   gorm.Expr("LEAST(first_block_time, VALUES(first_block_time))"),
+				"last_block_time":    gorm.Expr("GREATEST(last_block_time, VALUES(last_block_time))"),
+				"updated_at":         gorm.Expr("VALUES(updated_at)"),
+			}),
+		}).CreateInBatches(stats, 500).Error
+	if err != nil {
+		return err
+	}
+
+	if len(senders) == 0 {
+		return nil
+	}
+
+	err = gormDb.Table(senderTable).Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(senders, 500).Error
+	if err != nil {
+		return err
+	}
+
+	dates := make([]int64, 0, len(stats))
+	for _, s := range stats {
+		dates = append(dates, s.StatDate)
+	}
+
+	return gormDb.Exec("UPDATE `"+statsTable+"` s SET s.sender_count = "+
+		"(SELECT COUNT(*) FROM `"+senderTable+"` d WHERE d.stat_date = s.stat_date) "+
+		"WHERE s.stat_date IN ?", dates).Error
+}
+
+// RebuildDailyStats 按区块、交易和合约生命周期记录重新汇总fromTime所在自然日及之后的每日统计
+func RebuildDailyStats(tableNum int, fromTime int64, gormDb *gorm.DB) error {
+	statsTable := db.ShardTableName(dbModel.TableNamePrefix_ChainDailyStats, tableNum)
+	senderTable := db.ShardTableName(dbModel.TableNamePrefix_ChainDailySender, tableNum)
+	blockTable := db.ShardTableName(dbModel.TableNamePrefix_Block, tableNum)
+	txTable := db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)
+	historyTable := db.ShardTableName(dbModel.TableNamePrefix_ContractHistory, tableNum)
+
+	fromDate := dbModel.DailyStatDate(fromTime)
+
+	// SQL中与DailyStatDate按同一偏移切分自然日
+	day := fmt.Sprintf("b.block_timestamp - MOD(b.block_timestamp + %d, 86400)", dbModel.StatZoneOffset())
+
+	return gormDb.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("DELETE FROM `"+statsTable+"` WHERE stat_date >= ?", fromDate).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec("DELETE FROM `"+senderTable+"` WHERE stat_date >= ?", fromDate).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec("INSERT INTO `"+statsTable+"` (created_at, updated_at, stat_date, block_count, "+
+			"tx_count, failed_tx_count, gas_used, sender_count, new_contract_count, first_block_time, "+
+			"last_block_time) SELECT NOW(), NOW(), "+day+" AS day, COUNT(*), 0, 0, 0, 0, 0, "+
+			"MIN(b.block_timestamp), MAX(b.block_timestamp) FROM `"+blockTable+"` b "+
+			"WHERE b.block_timestamp >= ? GROUP BY day", fromDate).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec("UPDATE `"+statsTable+"` s JOIN (SELECT "+day+" AS day, COUNT(*) AS tx_count, "+
+			"SUM(t.tx_status_code != ?) AS failed_tx_count, COALESCE(SUM(t.gas_used), 0) AS gas_used "+
+			"FROM `"+txTable+"` t JOIN `"+blockTable+"` b ON t.block_height = b.block_height "+
+			"WHERE b.block_timestamp >= ? GROUP BY day) r ON s.stat_date = r.day "+
+			"SET s.tx_count = r.tx_count, s.failed_tx_count = r.failed_tx_count, s.gas_used = r.gas_used",
+			TxStatusCode_Success, fromDate).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec("INSERT INTO `"+senderTable+"` (created_at, updated_at, stat_date, sender_address) "+
+			"SELECT NOW(), NOW(), r.day, r.sender_address FROM (SELECT DISTINCT "+day+" AS day, "+
+			"t.sender_address FROM `"+txTable+"` t JOIN `"+blockTable+"` b ON t.block_height = b.block_height "+
+			"WHERE b.block_timestamp >= ? AND t.sender_address != '') r", fromDate).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec("UPDATE `"+statsTable+"` s JOIN (SELECT stat_date, COUNT(*) AS sender_count "+
+			"FROM `"+senderTable+"` WHERE stat_date >= ? GROUP BY stat_date) r ON s.stat_date = r.stat_date "+
+			"SET s.sender_count = r.sender_count", fromDate).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec("UPDATE `"+statsTable+"` s JOIN (SELECT "+day+" AS day, COUNT(*) AS contract_count "+
+			"FROM `"+historyTable+"` h JOIN `"+blockTable+"` b ON h.block_height = b.block_height "+
+			"WHERE b.block_timestamp >= ? AND h.operation = ? GROUP BY day) r ON s.stat_date = r.day "+
+			"SET s.new_contract_count = r.contract_count", fromDate, dbModel.ContractOperation_Init).Error
+	})
+}
+
+// RebuildDailySenders 按交易补齐每日发送者并重新计算去重后的发送者数，已有的每日发送者保留
+func RebuildDailySenders(tableNum int, gormDb *gorm.DB) error {
+	statsTable := db.ShardTableName(dbModel.TableNamePrefix_ChainDailyStats, tableNum)
+	senderTable := db.ShardTableName(dbModel.TableNamePrefix_ChainDailySender, tableNum)
+	blockTable := db.ShardTableName(dbModel.TableNamePrefix_Block, tableNum)
+	txTable := db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)
+
+	day := fmt.Sprintf("b.block_timestamp - MOD(b.block_timestamp + %d, 86400)", dbModel.StatZoneOffset())
+
+	err := gormDb.Exec("INSERT IGNORE INTO `" + senderTable + "` (created_at, updated_at, stat_date, " +
+		"sender_address) SELECT NOW(), NOW(), r.day, r.sender_address FROM (SELECT DISTINCT " + day + " AS day, " +
+		"t.sender_address FROM `" + txTable + "` t JOIN `" + blockTable + "` b ON t.block_height = b.block_height " +
+		"WHERE t.sender_address != '') r").Error
+	if err != nil {
+		return err
+	}
+
+	return gormDb.Exec("UPDATE `" + statsTable + "` s JOIN (SELECT stat_date, COUNT(*) AS sender_count " +
+		"FROM `" + senderTable + "` GROUP BY stat_date) r ON s.stat_date = r.stat_date " +
+		"SET s.sender_count = r.sender_count").Error
+}
+
+// MigrateDailyStats 旧版本入库的链没有每日统计，按已入库数据重新汇总，需在分表迁移之后执行
+func MigrateDailyStats(tableNum int, gormDb *gorm.DB) error {
+	hasStats, err := hasRows(db.ShardTableName(dbModel.TableNamePrefix_ChainDailyStats, tableNum), gormDb)
+	if err != nil {
+		return err
+	}
+
+	hasBlock, err := hasRows(db.ShardTableName(dbModel.TableNamePrefix_Block, tableNum), gormDb)
+	if err != nil {
+		return err
+	}
+
+	if hasStats || !hasBlock {
+		return nil
+	}
+
+	return RebuildDailyStats(tableNum, 0, gormDb)
+}
+
+// GetDailyStats 查询[startTime, endTime]内的每日统计，按日期升序返回，没有区块的日期不返回
+func GetDailyStats(genHash string, startTime, endTime int64,
+	gormDb *gorm.DB) ([]*dbModel.ChainDailyStats, error) {
+
+	var list []*dbModel.ChainDailyStats
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return list, err
+	}
+
+	if tableNum == 0 {
+		return nil, nil
+	}
+
+	err = gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_ChainDailyStats, tableNum)).
+		Where("stat_date >= ? AND stat_date <= ?", dbModel.DailyStatDate(startTime), endTime).
+		Order("stat_date").
+		Find(&list).Error
+	if err != nil {
+		return list, err
+	}
+
+	return list, nil
+}
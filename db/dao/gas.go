@@ -0,0 +1,252 @@
+package dao
+
+import (
+	"chainmscan/db"
+	dbModel "chainmscan/db/model"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 燃料消耗的统计维度
+const (
+	GasDimension_Contract  = "contract"
+	GasDimension_Method    = "method"
+	GasDimension_SenderOrg = "senderOrg"
+)
+
+// GasConsumer 按维度汇总的燃料消耗，非统计维度的字段为空
+type GasConsumer struct {
+	ContractName string
+	Method       string
+	SenderOrgId  string
+	TxCount      int64
+	GasUsed      uint64
+	GasLimit     uint64
+}
+
+// GasTimePoint 单个时间段的燃料消耗
+type GasTimePoint struct {
+	Bucket   int64
+	TxCount  int64
+	GasUsed  uint64
+	GasLimit uint64
+}
+
+// UpsertGasRollups 累加燃料汇总，同一时间段、合约、方法、发送者组织的记录合并
+func UpsertGasRollups(list []*dbModel.GasRollup, tableNum int, gormDb *gorm.DB) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	return gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_GasRollup, tableNum)).
+		Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"tx_count":   gorm.Expr("tx_count + VALUES(tx_count)"),
+				"gas_used":   gorm.Expr("gas_used + VALUES(gas_used)"),
+				"gas_limit":  gorm.Expr("gas_limit + VALUES(gas_limit)"),
+				"updated_at": gorm.Expr("VALUES(updated_at)"),
+			}),
+		}).CreateInBatches(list, 500).Error
+}
+
+// RebuildGasRollups 按交易表重新汇总fromTime所在时间段及之后的燃料消耗
+func RebuildGasRollups(tableNum int, fromTime int64, gormDb *gorm.DB) error {
+	rollupTable := db.ShardTableName(dbModel.TableNamePrefix_GasRollup, tableNum)
+	txTable := db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)
+
+	fromBucket := fromTime - fromTime%dbModel.GasRollupInterval
+
+	err := gormDb.Exec("DELETE FROM `"+rollupTable+"` WHERE bucket_time >= ?", fromBucket).Error
+	if err != nil {
+		return err
+	}
+
+	return gormDb.Exec(fmt.Sprintf("INSERT INTO `"+rollupTable+"` (created_at, updated_at, bucket_time, "+
+		"contract_name, method, sender_org_id, tx_count, gas_used, gas_limit) "+
+		"SELECT NOW(), NOW(), timestamp - timestamp %% %d AS bucket, contract_name, method, sender_org_id, "+
+		"COUNT(*), SUM(gas_used), SUM(gas_limit) FROM `"+txTable+"` WHERE timestamp >= ? "+
+		"GROUP BY bucket, contract_name, method, sender_org_id", dbModel.GasRollupInterval),
+		fromBucket).Error
+}
+
+// MigrateGasRollups 旧版本入库的链没有燃料汇总，从交易详情补齐交易的燃料消耗后重新汇总，
+// 需在分表迁移之后执行
+func MigrateGasRollups(tableNum int, gormDb *gorm.DB) error {
+	rollupTable := db.ShardTableName(dbModel.TableNamePrefix_GasRollup, tableNum)
+	txTable := db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)
+	txDetailsTable := db.ShardTableName(dbModel.TableNamePrefix_TxDetails, tableNum)
+
+	hasRollup, err := hasRows(rollupTable, gormDb)
+	if err != nil {
+		return err
+	}
+
+	hasTx, err := hasRows(txTable, gormDb)
+	if err != nil {
+		return err
+	}
+
+	if hasRollup || !hasTx {
+		return nil
+	}
+
+	return gormDb.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("UPDATE `" + txTable + "` t JOIN `" + txDetailsTable + "` d ON t.tx_id = d.tx_id " +
+			"SET t.gas_used = d.gas_used").Error
+		if err != nil {
+			return err
+		}
+
+		return RebuildGasRollups(tableNum, 0, tx)
+	})
+}
+
+func hasRows(tableName string, gormDb *gorm.DB) (bool, error) {
+	var ids []uint
+
+	err := gormDb.Table(tableName).Limit(1).Pluck("id", &ids).Error
+	if err != nil {
+		return false, err
+	}
+
+	return len(ids) != 0, nil
+}
+
+// GetTopGasConsumers 查询时间范围内燃料消耗最多的合约、方法或发送者组织
+func GetTopGasConsumers(genHash, dimension string, startTime, endTime int64, limit int,
+	gormDb *gorm.DB) ([]*GasConsumer, error) {
+
+	var list []*GasConsumer
+
+	var columns string
+
+	switch dimension {
+	case GasDimension_Contract:
+		columns = "contract_name"
+	case GasDimension_Method:
+		columns = "contract_name, method"
+	case GasDimension_SenderOrg:
+		columns = "sender_org_id"
+	default:
+		return nil, errors.New("unknown gas dimension")
+	}
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return list, err
+	}
+
+	if tableNum == 0 {
+		return nil, nil
+	}
+
+	queryDb := gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_GasRollup, tableNum))
+
+	if startTime > 0 {
+		queryDb = queryDb.Where("bucket_time >= ?", startTime-startTime%dbModel.GasRollupInterval)
+	}
+
+	if endTime > 0 {
+		queryDb = queryDb.Where("bucket_time <= ?", endTime)
+	}
+
+	err = queryDb.Select(columns + ", SUM(tx_count) AS tx_count, SUM(gas_used) AS gas_used, " +
+		"SUM(gas_limit) AS gas_limit").
+		Group(columns).Order("gas_used desc").Limit(limit).
+		Scan(&list).Error
+	if err != nil {
+		return list, err
+	}
+
+	return list, nil
+}
+
+// GetGasTimeSeries 按StatBuckets切分的时间段统计燃料消耗，granularity为小时或天，天按统计时区对齐，
+// contractName不为空时只统计该合约
+func GetGasTimeSeries(genHash, contractName, granularity string, buckets []int64, endTime int64,
+	gormDb *gorm.DB) ([]*GasTimePoint, error) {
+
+	var list []*GasTimePoint
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return list, err
+	}
+
+	if tableNum == 0 || len(buckets) == 0 {
+		return nil, nil
+	}
+
+	if granularity != StatGranularity_Hour && granularity != StatGranularity_Day {
+		return nil, errors.New("unknown gas granularity")
+	}
+
+	queryDb := gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_GasRollup, tableNum)).
+		Where("bucket_time >= ? AND bucket_time <= ?", buckets[0], endTime)
+
+	if len(contractName) != 0 {
+		queryDb = queryDb.Where("contract_name = ?", contractName)
+	}
+
+	offset := statZoneOffset(granularity)
+
+	err = queryDb.Select(statBucketExpr(granularity, "bucket_time", offset) + " AS bucket, " +
+		"SUM(tx_count) AS tx_count, SUM(gas_used) AS gas_used, SUM(gas_limit) AS gas_limit").
+		Group("bucket").Order("bucket").
+		Scan(&list).Error
+	if err != nil {
+		return list, err
+	}
+
+	return list, nil
+}
+
+// GetTxGasList 查询交易的燃料消耗和燃料上限，按区块高度倒序
+func GetTxGasList(genHash, contractName, method string, page, pageSize int32,
+	gormDb *gorm.DB) ([]*dbModel.Transaction, int64, error) {
+
+	var list []*dbModel.Transaction
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return list, 0, err
+	}
+
+	if tableNum == 0 {
+		return nil, 0, nil
+	}
+
+	queryDb := gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)).
+		Where("gas_limit > 0")
+
+	if len(contractName) != 0 {
+		queryDb = queryDb.Where("contract_name = ?", contractName)
+	}
+
+	if len(method) != 0 {
+		queryDb = queryDb.Where("method = ?", method)
+	}
+
+	queryDb = queryDb.Session(&gorm.Session{})
+
+	var total int64
+
+	err = queryDb.Count(&total).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+
+	err = queryDb.Limit(int(pageSize)).Offset(int(offset)).
+		Order("block_height desc").Order("id desc").
+		Find(&list).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	return list, total, nil
+}
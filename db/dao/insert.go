@@ -45,3 +45,12 @@ func InsertOneObjectToDBByTableName(object db.DbModel,
 	}
 	return nil
 }
+
+// InsertObjectsToDBByTableName 多条数据按批次入库，objects需为模型切片
+func InsertObjectsToDBByTableName(objects interface{}, tableName string,
+	batchSize int, gormDb *gorm.DB) error {
+	if err := gormDb.Table(tableName).CreateInBatches(objects, batchSize).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package dao
+
+import (
+	"chainmscan/db"
+	dbModel "chainmscan/db/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// 出块统计的分组方式
+const (
+	ProposerGroup_Node = "node"
+	ProposerGroup_Org  = "org"
+)
+
+// ProposerStat 出块节点或组织在区块窗口内的出块统计
+type ProposerStat struct {
+	ProposerId    string
+	ProposerName  string
+	ProposerOrgId string
+	BlockCount    int64
+	TxCount       int64
+	// 该节点出块后到下一个区块的平均间隔
+	AvgInterval   float64
+	AvgRound      float64
+	LastHeight    uint64
+	LastTimestamp int64
+}
+
+// GetProposerStats 统计区块高度[fromHeight, toHeight]内各出块节点或组织的出块情况，按出块数倒序
+func GetProposerStats(genHash, groupBy string, fromHeight, toHeight uint64,
+	gormDb *gorm.DB) ([]*ProposerStat, error) {
+
+	var list []*ProposerStat
+
+	var columns string
+
+	switch groupBy {
+	case ProposerGroup_Node:
+		columns = "proposer_id, MAX(proposer_name) AS proposer_name, MAX(proposer_org_id) AS proposer_org_id"
+	case ProposerGroup_Org:
+		columns = "proposer_org_id"
+	default:
+		return nil, errors.New("unknown proposer group")
+	}
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return list, err
+	}
+
+	if tableNum == 0 {
+		return nil, nil
+	}
+
+	window := gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_Block, tableNum)).
+		Select("proposer_id, proposer_name, proposer_org_id, tx_count, block_height, block_timestamp, "+
+			"consensus_round, LEAD(block_timestamp) OVER (ORDER BY block_height) AS next_timestamp").
+		Where("block_height >= ? AND block_height <= ?", fromHeight, toHeight)
+
+	groupColumn := "proposer_id"
+	if groupBy == ProposerGroup_Org {
+		groupColumn = "proposer_org_id"
+	}
+
+	// 窗口内最后一个区块没有下一个区块，AVG忽略其NULL间隔
+	err = gormDb.Table("(?) AS b", window).
+		Select(columns + ", COUNT(*) AS block_count, SUM(tx_count) AS tx_count, " +
+			"AVG(next_timestamp - block_timestamp) AS avg_interval, AVG(consensus_round) AS avg_round, " +
+			"MAX(block_height) AS last_height, MAX(block_timestamp) AS last_timestamp").
+		Group(groupColumn).Order("block_count desc").Order(groupColumn).
+		Scan(&list).Error
+	if err != nil {
+		return list, err
+	}
+
+	return list, nil
+}
+
+// GetBlockConsensusInfos 查询区块高度[fromHeight, toHeight]内区块的共识信息JSON，没有共识信息的区块不返回
+func GetBlockConsensusInfos(genHash string, fromHeight, toHeight uint64,
+	gormDb *gorm.DB) ([]string, error) {
+
+	var list []string
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return list, err
+	}
+
+	if tableNum == 0 {
+		return nil, nil
+	}
+
+	blockTable := db.ShardTableName(dbModel.TableNamePrefix_Block, tableNum)
+	detailsTable := db.ShardTableName(dbModel.TableNamePrefix_BlockDetails, tableNum)
+
+	err = gormDb.Table("`"+blockTable+"` b").
+		Joins("JOIN `"+detailsTable+"` d ON b.block_hash = d.block_hash").
+		Where("b.block_height >= ? AND b.block_height <= ?", fromHeight, toHeight).
+		Where("d.consensus_info IS NOT NULL AND d.consensus_info != ''").
+		Pluck("d.consensus_info", &list).Error
+	if err != nil {
+		return list, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,188 @@
+package dao
+
+import (
+	"chainmscan/db"
+	dbModel "chainmscan/db/model"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 搜索结果类型
+const (
+	SearchHitType_Tx       = "tx"
+	SearchHitType_Block    = "block"
+	SearchHitType_Contract = "contract"
+	SearchHitType_Address  = "address"
+	SearchHitType_Org      = "org"
+)
+
+// 搜索结果的排序值，越小越靠前：精确的哈希匹配优先，区块高度在每条链上都可能命中，排在最后
+const (
+	searchRank_Hash = iota + 1
+	searchRank_Contract
+	searchRank_Address
+	searchRank_Org
+	searchRank_Height
+)
+
+var (
+	hex64Regexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	hex40Regexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// SearchHit 单条链上的搜索结果，Value为命中的交易ID、区块哈希、合约名、地址或组织ID
+type SearchHit struct {
+	Type        string
+	Id          uint
+	Value       string
+	BlockHeight uint64
+	Rank        int
+}
+
+// SearchChain 在一条链的分表中按关键字的格式搜索交易、区块、合约、账户地址和组织
+func SearchChain(tableNum int, keyword string, gormDb *gorm.DB) ([]*SearchHit, error) {
+	hits := make([]*SearchHit, 0)
+
+	keyword = strings.TrimSpace(keyword)
+	if len(keyword) == 0 {
+		return hits, nil
+	}
+
+	// 哈希和地址统一为不带0x前缀的小写hex
+	hexKeyword := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(keyword, "0x"), "0X"))
+
+	var tx dbModel.Transaction
+
+	found, err := firstRow(gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)).
+		Where("tx_id = ?", keyword), &tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if found {
+		hits = append(hits, &SearchHit{
+			Type:        SearchHitType_Tx,
+			Id:          tx.ID,
+			Value:       tx.TxId,
+			BlockHeight: tx.BlockHeight,
+			Rank:        searchRank_Hash,
+		})
+	}
+
+	if hex64Regexp.MatchString(hexKeyword) {
+		var block dbModel.Block
+
+		found, err := firstRow(gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_Block, tableNum)).
+			Where("block_hash = ?", hexKeyword), &block)
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			hits = append(hits, &SearchHit{
+				Type:        SearchHitType_Block,
+				Id:          block.ID,
+				Value:       block.BlockHash,
+				BlockHeight: block.BlockHeight,
+				Rank:        searchRank_Hash,
+			})
+		}
+	}
+
+	if height, err := strconv.ParseUint(keyword, 10, 64); err == nil {
+		var block dbModel.Block
+
+		found, err := firstRow(gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_Block, tableNum)).
+			Where("block_height = ?", height), &block)
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			hits = append(hits, &SearchHit{
+				Type:        SearchHitType_Block,
+				Id:          block.ID,
+				Value:       block.BlockHash,
+				BlockHeight: block.BlockHeight,
+				Rank:        searchRank_Height,
+			})
+		}
+	}
+
+	contractDb := gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_Contract, tableNum))
+	if hex40Regexp.MatchString(hexKeyword) {
+		contractDb = contractDb.Where("name = ? OR address = ? OR address = ?", keyword, hexKeyword, "0x"+hexKeyword)
+	} else {
+		contractDb = contractDb.Where("name = ?", keyword)
+	}
+
+	var contract dbModel.Contract
+
+	found, err = firstRow(contractDb, &contract)
+	if err != nil {
+		return nil, err
+	}
+
+	if found {
+		hits = append(hits, &SearchHit{
+			Type:        SearchHitType_Contract,
+			Id:          contract.ID,
+			Value:       contract.Name,
+			BlockHeight: contract.Height,
+			Rank:        searchRank_Contract,
+		})
+	}
+
+	if hex40Regexp.MatchString(hexKeyword) {
+		var sender dbModel.Transaction
+
+		found, err := firstRow(gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)).
+			Where("(sender_address = ? OR sender_evm_address = ?)", hexKeyword, hexKeyword), &sender)
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			hits = append(hits, &SearchHit{
+				Type:  SearchHitType_Address,
+				Value: hexKeyword,
+				Rank:  searchRank_Address,
+			})
+		}
+	}
+
+	// 组织ID按交易表的sender_org_id_index索引查找，命中一条即返回
+	var orgs []string
+
+	err = gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)).
+		Where("sender_org_id = ?", keyword).Limit(1).Pluck("sender_org_id", &orgs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(orgs) != 0 {
+		hits = append(hits, &SearchHit{
+			Type:  SearchHitType_Org,
+			Value: orgs[0],
+			Rank:  searchRank_Org,
+		})
+	}
+
+	return hits, nil
+}
+
+func firstRow(queryDb *gorm.DB, dest interface{}) (bool, error) {
+	err := queryDb.First(dest).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,45 @@
+package dao
+
+import (
+	dbModel "chainmscan/db/model"
+
+	"gorm.io/gorm"
+)
+
+func GetSinkCheckpoint(genHash, sinkName string, gormDb *gorm.DB) (*dbModel.SinkCheckpoint, error) {
+
+	var checkpoint dbModel.SinkCheckpoint
+
+	err := gormDb.Table(dbModel.TableName_SinkCheckpoint).
+		Where("gen_hash = ? AND sink_name = ?", genHash, sinkName).First(&checkpoint).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &checkpoint, nil
+}
+
+// SaveSinkCheckpoint 保存导出检查点，不存在时新建
+func SaveSinkCheckpoint(genHash, sinkName string, nextHeight uint64, lastErr string,
+	gormDb *gorm.DB) error {
+
+	var checkpoint dbModel.SinkCheckpoint
+
+	return gormDb.Table(dbModel.TableName_SinkCheckpoint).
+		Where(dbModel.SinkCheckpoint{GenHash: genHash, SinkName: sinkName}).
+		Assign(map[string]interface{}{
+			"next_height": nextHeight,
+			"last_error":  lastErr,
+		}).FirstOrCreate(&checkpoint).Error
+}
+
+// ClampSinkCheckpoints 重新索引后将链的导出检查点回退到height，高度不超过height的检查点不变
+func ClampSinkCheckpoints(genHash string, height uint64, gormDb *gorm.DB) error {
+	return gormDb.Table(dbModel.TableName_SinkCheckpoint).
+		Where("gen_hash = ? AND next_height > ?", genHash, height).
+		Update("next_height", height).Error
+}
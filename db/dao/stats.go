@@ -0,0 +1,261 @@
+package dao
+
+import (
+	"chainmscan/db"
+	dbModel "chainmscan/db/model"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 统计的时间粒度
+const (
+	StatGranularity_Minute = "minute"
+	StatGranularity_Hour   = "hour"
+	StatGranularity_Day    = "day"
+	StatGranularity_Week   = "week"
+	StatGranularity_Month  = "month"
+)
+
+// 统计指标
+const (
+	StatMetric_TxCount       = "txCount"
+	StatMetric_FailedTxCount = "failedTxCount"
+	StatMetric_BlockCount    = "blockCount"
+	StatMetric_GasUsed       = "gasUsed"
+	StatMetric_ActiveSenders = "activeSenders"
+)
+
+// TxStatusCode_Success 交易执行成功的状态码
+const TxStatusCode_Success = "SUCCESS"
+
+// 固定长度时间粒度的秒数
+var statIntervals = map[string]int64{
+	StatGranularity_Minute: 60,
+	StatGranularity_Hour:   3600,
+	StatGranularity_Day:    86400,
+	StatGranularity_Week:   7 * 86400,
+}
+
+// StatPoint 单个时间段的统计值，Timestamp为时间段起始时间
+type StatPoint struct {
+	Timestamp int64
+	Value     int64
+}
+
+type statBucket struct {
+	Bucket int64
+	Value  int64
+}
+
+// StatBuckets 按时间粒度切分[startTime, endTime]，返回各时间段的起始时间，时间段数超过maxPoints时返回错误。
+// 天、周、月按统计时区对齐，周从周一开始
+func StatBuckets(granularity string, startTime, endTime int64, maxPoints int) ([]int64, error) {
+	if startTime > endTime {
+		return nil, errors.New("the start time is after the end time")
+	}
+
+	buckets := make([]int64, 0)
+
+	if granularity == StatGranularity_Month {
+		t := time.Unix(startTime, 0).In(dbModel.StatLocation())
+		t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, dbModel.StatLocation())
+
+		for ; t.Unix() <= endTime; t = t.AddDate(0, 1, 0) {
+			if len(buckets) >= maxPoints {
+				return nil, errors.New("too many stat points")
+			}
+
+			buckets = append(buckets, t.Unix())
+		}
+
+		return buckets, nil
+	}
+
+	interval, ok := statIntervals[granularity]
+	if !ok {
+		return nil, errors.New("unknown stat granularity")
+	}
+
+	start := alignBucket(startTime, interval, statZoneOffset(granularity))
+
+	if (endTime-start)/interval >= int64(maxPoints) {
+		return nil, errors.New("too many stat points")
+	}
+
+	for t := start; t <= endTime; t += interval {
+		buckets = append(buckets, t)
+	}
+
+	return buckets, nil
+}
+
+// GetTimeSeriesStats 按StatBuckets切分的时间段统计指标，一次分组查询得到全部时间段，没有数据的时间段补零
+func GetTimeSeriesStats(genHash, metric, granularity string, buckets []int64, endTime int64,
+	gormDb *gorm.DB) ([]*StatPoint, error) {
+
+	points := make([]*StatPoint, 0, len(buckets))
+	for _, b := range buckets {
+		points = append(points, &StatPoint{Timestamp: b})
+	}
+
+	if len(buckets) == 0 {
+		return points, nil
+	}
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return nil, err
+	}
+
+	if tableNum == 0 {
+		return points, nil
+	}
+
+	offset := statZoneOffset(granularity)
+
+	// 按小时统计时交易数和燃料消耗从小时汇总表统计
+	useRollup := granularity == StatGranularity_Hour
+
+	// 天及以上粒度从每日统计表统计，去重发送者数不能跨天累加，只在按天统计时使用
+	useDaily := granularity == StatGranularity_Day ||
+		((granularity == StatGranularity_Week || granularity == StatGranularity_Month) &&
+			metric != StatMetric_ActiveSenders)
+
+	var (
+		table, timeColumn, valueExpr string
+		conditions                   []interface{}
+	)
+
+	switch {
+	case useDaily:
+		table = db.ShardTableName(dbModel.TableNamePrefix_ChainDailyStats, tableNum)
+		timeColumn = "stat_date"
+
+		switch metric {
+		case StatMetric_TxCount:
+			valueExpr = "SUM(tx_count)"
+		case StatMetric_FailedTxCount:
+			valueExpr = "SUM(failed_tx_count)"
+		case StatMetric_BlockCount:
+			valueExpr = "SUM(block_count)"
+		case StatMetric_GasUsed:
+			valueExpr = "SUM(gas_used)"
+		case StatMetric_ActiveSenders:
+			valueExpr = "SUM(sender_count)"
+		default:
+			return nil, errors.New("unknown stat metric")
+		}
+
+	case metric == StatMetric_TxCount, metric == StatMetric_GasUsed:
+		if useRollup {
+			table = db.ShardTableName(dbModel.TableNamePrefix_GasRollup, tableNum)
+			timeColumn = "bucket_time"
+			valueExpr = "SUM(tx_count)"
+			if metric == StatMetric_GasUsed {
+				valueExpr = "SUM(gas_used)"
+			}
+			break
+		}
+
+		table = db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)
+		timeColumn = "timestamp"
+		valueExpr = "COUNT(*)"
+		if metric == StatMetric_GasUsed {
+			valueExpr = "SUM(gas_used)"
+		}
+
+	case metric == StatMetric_FailedTxCount:
+		table = db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)
+		timeColumn = "timestamp"
+		valueExpr = "COUNT(*)"
+		conditions = []interface{}{"tx_status_code != ?", TxStatusCode_Success}
+
+	case metric == StatMetric_ActiveSenders:
+		// 无法计算地址的发送者（如证书哈希）不计入
+		table = db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum)
+		timeColumn = "timestamp"
+		valueExpr = "COUNT(DISTINCT sender_address)"
+		conditions = []interface{}{"sender_address != ''"}
+
+	case metric == StatMetric_BlockCount:
+		table = db.ShardTableName(dbModel.TableNamePrefix_Block, tableNum)
+		timeColumn = "block_timestamp"
+		valueExpr = "COUNT(*)"
+
+	default:
+		return nil, errors.New("unknown stat metric")
+	}
+
+	queryDb := gormDb.Table(table).
+		Where(timeColumn+" >= ? AND "+timeColumn+" <= ?", buckets[0], endTime)
+
+	if len(conditions) != 0 {
+		queryDb = queryDb.Where(conditions[0], conditions[1:]...)
+	}
+
+	var list []*statBucket
+
+	err = queryDb.Select(statBucketExpr(granularity, timeColumn, offset) + " AS bucket, " +
+		"COALESCE(" + valueExpr + ", 0) AS value").
+		Group("bucket").
+		Scan(&list).Error
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[int64]int64, len(list))
+	for _, v := range list {
+		values[statBucketTimestamp(granularity, v.Bucket)] = v.Value
+	}
+
+	for _, p := range points {
+		p.Value = values[p.Timestamp]
+	}
+
+	return points, nil
+}
+
+// statZoneOffset 天及以上粒度按统计时区对齐，返回时区相对UTC的偏移秒数
+func statZoneOffset(granularity string) int64 {
+	if granularity == StatGranularity_Minute || granularity == StatGranularity_Hour {
+		return 0
+	}
+
+	return dbModel.StatZoneOffset()
+}
+
+// alignBucket 对齐到时间段起始时间，1970-01-01是周四，按周对齐时偏移到周一
+func alignBucket(t, interval, offset int64) int64 {
+	if interval == statIntervals[StatGranularity_Week] {
+		offset += 3 * 86400
+	}
+
+	return t - ((t+offset)%interval+interval)%interval
+}
+
+// statBucketExpr 时间段的SQL表达式，月粒度为统计时区的年月（如202401），其他为时间段起始时间
+func statBucketExpr(granularity, column string, offset int64) string {
+	if granularity == StatGranularity_Month {
+		return fmt.Sprintf("EXTRACT(YEAR_MONTH FROM TIMESTAMP('1970-01-01') + INTERVAL (%s + %d) SECOND)",
+			column, offset)
+	}
+
+	interval := statIntervals[granularity]
+	if granularity == StatGranularity_Week {
+		offset += 3 * 86400
+	}
+
+	return fmt.Sprintf("%s - MOD(%s + %d, %d)", column, column, offset, interval)
+}
+
+// statBucketTimestamp 将SQL分组的时间段转换为起始时间
+func statBucketTimestamp(granularity string, bucket int64) int64 {
+	if granularity != StatGranularity_Month {
+		return bucket
+	}
+
+	return time.Date(int(bucket/100), time.Month(bucket%100), 1, 0, 0, 0, 0, dbModel.StatLocation()).Unix()
+}
@@ -0,0 +1,207 @@
+package dao
+
+import (
+	"testing"
+
+	dbModel "chainmscan/db/model"
+)
+
+// 2024-01-01 00:00:00 UTC，周一
+const testStatTime = 1704067200
+
+// setStatUtcOffset 设置统计时区，测试结束后恢复为UTC
+func setStatUtcOffset(t *testing.T, minutes int) {
+	t.Helper()
+
+	dbModel.SetStatUtcOffset(minutes)
+	t.Cleanup(func() {
+		dbModel.SetStatUtcOffset(0)
+	})
+}
+
+func TestStatBuckets(t *testing.T) {
+	const east8 = 8 * 3600
+
+	cases := []struct {
+		name        string
+		offset      int
+		granularity string
+		start, end  int64
+		maxPoints   int
+		want        []int64
+		wantErr     bool
+	}{
+		{
+			name:        "minute",
+			granularity: StatGranularity_Minute,
+			start:       testStatTime + 90,
+			end:         testStatTime + 180,
+			maxPoints:   10,
+			want:        []int64{testStatTime + 60, testStatTime + 120, testStatTime + 180},
+		},
+		{
+			name:        "hour ignores the offset",
+			offset:      8 * 60,
+			granularity: StatGranularity_Hour,
+			start:       testStatTime + 100,
+			end:         testStatTime + 3600,
+			maxPoints:   10,
+			want:        []int64{testStatTime, testStatTime + 3600},
+		},
+		{
+			name:        "day utc",
+			granularity: StatGranularity_Day,
+			start:       testStatTime + 3600,
+			end:         testStatTime + 86400,
+			maxPoints:   10,
+			want:        []int64{testStatTime, testStatTime + 86400},
+		},
+		{
+			// UTC零点是东八区8点，所在自然日从前一天16点（UTC）开始
+			name:        "day east8",
+			offset:      8 * 60,
+			granularity: StatGranularity_Day,
+			start:       testStatTime,
+			end:         testStatTime + 2*86400,
+			maxPoints:   10,
+			want:        []int64{testStatTime - east8, testStatTime + 86400 - east8, testStatTime + 2*86400 - east8},
+		},
+		{
+			name:        "day west5",
+			offset:      -5 * 60,
+			granularity: StatGranularity_Day,
+			start:       testStatTime,
+			end:         testStatTime,
+			maxPoints:   10,
+			want:        []int64{testStatTime - 86400 + 5*3600},
+		},
+		{
+			name:        "week starts on monday",
+			granularity: StatGranularity_Week,
+			start:       testStatTime + 2*86400,
+			end:         testStatTime + 8*86400,
+			maxPoints:   10,
+			want:        []int64{testStatTime, testStatTime + 7*86400},
+		},
+		{
+			name:        "month east8",
+			offset:      8 * 60,
+			granularity: StatGranularity_Month,
+			start:       testStatTime + 14*86400,
+			end:         1709251200, // 2024-03-01 00:00:00 UTC
+			maxPoints:   10,
+			// 2024-01-01、2024-02-01、2024-03-01 东八区零点
+			want: []int64{testStatTime - east8, 1706745600 - east8, 1709251200 - east8},
+		},
+		{
+			name:        "start after end",
+			granularity: StatGranularity_Day,
+			start:       testStatTime + 1,
+			end:         testStatTime,
+			maxPoints:   10,
+			wantErr:     true,
+		},
+		{
+			name:        "unknown granularity",
+			granularity: "year",
+			start:       testStatTime,
+			end:         testStatTime,
+			maxPoints:   10,
+			wantErr:     true,
+		},
+		{
+			name:        "too many points",
+			granularity: StatGranularity_Minute,
+			start:       testStatTime,
+			end:         testStatTime + 180,
+			maxPoints:   3,
+			wantErr:     true,
+		},
+		{
+			name:        "too many months",
+			granularity: StatGranularity_Month,
+			start:       testStatTime,
+			end:         1709251200,
+			maxPoints:   2,
+			wantErr:     true,
+		},
+	}
+
+	for _, c := range cases {
+		setStatUtcOffset(t, c.offset)
+
+		got, err := StatBuckets(c.granularity, c.start, c.end, c.maxPoints)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: want an error, got %v", c.name, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: [%s]", c.name, err.Error())
+			continue
+		}
+
+		if len(got) != len(c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+			continue
+		}
+
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestAlignBucket(t *testing.T) {
+	week := statIntervals[StatGranularity_Week]
+
+	cases := []struct {
+		name                string
+		t, interval, offset int64
+		want                int64
+	}{
+		{"aligned", 120, 60, 0, 120},
+		{"minute", 179, 60, 0, 120},
+		{"negative time", -1, 60, 0, -60},
+		{"day offset", testStatTime, 86400, 8 * 3600, testStatTime - 8*3600},
+		{"negative offset", testStatTime, 86400, -5 * 3600, testStatTime - 86400 + 5*3600},
+		// 1970-01-01是周四，所在周从1969-12-29（周一）开始
+		{"week of epoch", 0, week, 0, -3 * 86400},
+		{"week monday", testStatTime + 6*86400, week, 0, testStatTime},
+		{"week offset", testStatTime, week, 8 * 3600, testStatTime - 8*3600},
+	}
+
+	for _, c := range cases {
+		if got := alignBucket(c.t, c.interval, c.offset); got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestStatBucketTimestamp(t *testing.T) {
+	cases := []struct {
+		name        string
+		offset      int
+		granularity string
+		bucket      int64
+		want        int64
+	}{
+		{"day", 8 * 60, StatGranularity_Day, testStatTime, testStatTime},
+		{"month utc", 0, StatGranularity_Month, 202401, testStatTime},
+		{"month east8", 8 * 60, StatGranularity_Month, 202402, 1706745600 - 8*3600},
+		{"december", 0, StatGranularity_Month, 202312, testStatTime - 31*86400},
+	}
+
+	for _, c := range cases {
+		setStatUtcOffset(t, c.offset)
+
+		if got := statBucketTimestamp(c.granularity, c.bucket); got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+}
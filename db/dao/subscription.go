@@ -45,8 +45,57 @@ func GetInfoOfSubscription(genHash string, gormDb *gorm.DB) (*dbModel.Subscripti
 }
 
 func DeleteSubscription(genHash string, gormDb *gorm.DB) error {
-	return gormDb.Where("gen_hash = ?", genHash).
-		Delete(&dbModel.Subscription{}).Error
+	return gormDb.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("gen_hash = ?", genHash).
+			Delete(&dbModel.SubscriptionNode{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("gen_hash = ?", genHash).
+			Delete(&dbModel.Subscription{}).Error
+	})
+}
+
+// SaveSubscription 在同一事务中保存订阅配置并覆盖保存节点列表
+func SaveSubscription(sub *dbModel.Subscription, nodes []*dbModel.SubscriptionNode, gormDb *gorm.DB) error {
+	return gormDb.Transaction(func(tx *gorm.DB) error {
+		err := SaveInfoOfSubscription(sub, tx)
+		if err != nil {
+			return err
+		}
+
+		return SaveSubscriptionNodes(sub.GenHash, nodes, tx)
+	})
+}
+
+// SaveSubscriptionNodes 覆盖保存订阅链的节点列表
+func SaveSubscriptionNodes(genHash string, nodes []*dbModel.SubscriptionNode,
+	gormDb *gorm.DB) error {
+	return gormDb.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("gen_hash = ?", genHash).
+			Delete(&dbModel.SubscriptionNode{}).Error
+		if err != nil {
+			return err
+		}
+
+		if len(nodes) == 0 {
+			return nil
+		}
+
+		return tx.Create(nodes).Error
+	})
+}
+
+func GetSubscriptionNodes(genHash string, gormDb *gorm.DB) ([]*dbModel.SubscriptionNode, error) {
+	var res []*dbModel.SubscriptionNode
+	err := gormDb.Table(dbModel.TableName_SubscriptionNode).
+		Where("gen_hash = ?", genHash).Order("node_index").Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
 }
 
 func GetAllSubscription(gormDb *gorm.DB) ([]*dbModel.Subscription, error) {
@@ -58,3 +107,21 @@ func GetAllSubscription(gormDb *gorm.DB) ([]*dbModel.Subscription, error) {
 
 	return res, nil
 }
+
+// UpdateSubscriptionRetry 记录一次重新订阅尝试，lastErr为空表示重试成功
+func UpdateSubscriptionRetry(genHash string, retryTime int64, lastErr string,
+	gormDb *gorm.DB) error {
+	return gormDb.Table(dbModel.TableName_Subscription).
+		Where("gen_hash = ?", genHash).
+		Updates(map[string]interface{}{
+			"retry_count":     gorm.Expr("retry_count + 1"),
+			"last_retry_time": retryTime,
+			"last_error":      lastErr,
+		}).Error
+}
+
+func UpdateSubscriptionPaused(genHash string, paused bool, gormDb *gorm.DB) error {
+	return gormDb.Table(dbModel.TableName_Subscription).
+		Where("gen_hash = ?", genHash).
+		Update("paused", paused).Error
+}
@@ -1,14 +1,14 @@
 package dao
 
 import (
-	"chainmscan/db/model"
+	"chainmscan/db"
 	dbModel "chainmscan/db/model"
 	"fmt"
 
 	"gorm.io/gorm"
 )
 
-func GetTxList(genHash string, page, pageSize int32, blockHeight int64,
+func GetTxList(genHash string, page, pageSize int32, blockHeight int64, asc bool,
 	gormDb *gorm.DB) ([]*dbModel.Transaction, error) {
 
 	var list []*dbModel.Transaction
@@ -30,7 +30,7 @@ func GetTxList(genHash string, page, pageSize int32, blockHeight int64,
 		queryDb = queryDb.Where("block_height = ?", blockHeight)
 	}
 
-	err = queryDb.Limit(int(pageSize)).Offset(int(offset)).Order("timestamp desc").
+	err = queryDb.Limit(int(pageSize)).Offset(int(offset)).Order("timestamp " + sortOrder(asc)).
 		Find(&list).Error
 
 	if err != nil {
@@ -40,62 +40,124 @@ func GetTxList(genHash string, page, pageSize int32, blockHeight int64,
 	return list, nil
 }
 
-func GetLatestTxListByContractName(genHash string, contractName string, page, pageSize int32,
-	gormDb *gorm.DB) ([]*dbModel.Transaction, error) {
+// TxFilter 交易列表的过滤条件，零值的条件不生效，高度和时间范围为闭区间
+type TxFilter struct {
+	ContractName string
+	Method       string
+	TxType       string
+	TxStatusCode string
+	SenderOrgId  string
+	// ChainMaker地址或EVM地址
+	SenderAddress string
+	StartHeight   int64
+	EndHeight     int64
+	StartTime     int64
+	EndTime       int64
+}
 
-	var list []*dbModel.Transaction
+// IsEmpty 是否没有任何过滤条件
+func (f *TxFilter) IsEmpty() bool {
+	return *f == TxFilter{}
+}
 
-	tableNum, err := getChainTableNum(genHash, gormDb)
-	if err != nil {
-		return list, err
+func (f *TxFilter) apply(queryDb *gorm.DB) *gorm.DB {
+	if len(f.ContractName) != 0 {
+		queryDb = queryDb.Where("contract_name = ?", f.ContractName)
 	}
 
-	if tableNum == 0 {
-		return nil, nil
+	if len(f.Method) != 0 {
+		queryDb = queryDb.Where("method = ?", f.Method)
 	}
 
-	offset := (page - 1) * pageSize
+	if len(f.TxType) != 0 {
+		queryDb = queryDb.Where("tx_type = ?", f.TxType)
+	}
 
-	queryDb := gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_Transaction+"_%02d", tableNum))
+	if len(f.TxStatusCode) != 0 {
+		queryDb = queryDb.Where("tx_status_code = ?", f.TxStatusCode)
+	}
 
-	if len(contractName) != 0 {
-		queryDb = queryDb.Where("contract_name = ?", contractName)
+	if len(f.SenderOrgId) != 0 {
+		queryDb = queryDb.Where("sender_org_id = ?", f.SenderOrgId)
 	}
 
-	err = queryDb.Limit(int(pageSize)).Offset(int(offset)).Order("timestamp desc").Find(&list).Error
-	if err != nil {
-		return list, err
+	if len(f.SenderAddress) != 0 {
+		queryDb = queryDb.Where("(sender_address = ? OR sender_evm_address = ?)", f.SenderAddress, f.SenderAddress)
 	}
 
-	return list, nil
+	if f.StartHeight > 0 {
+		queryDb = queryDb.Where("block_height >= ?", f.StartHeight)
+	}
+
+	if f.EndHeight > 0 {
+		queryDb = queryDb.Where("block_height <= ?", f.EndHeight)
+	}
+
+	if f.StartTime > 0 {
+		queryDb = queryDb.Where("timestamp >= ?", f.StartTime)
+	}
+
+	if f.EndTime > 0 {
+		queryDb = queryDb.Where("timestamp <= ?", f.EndTime)
+	}
+
+	return queryDb
 }
 
-func GetLatestTxCountByContractName(genHash string, contractName string, limit int,
-	gormDb *gorm.DB) (int, error) {
+// GetFilteredTxList 按过滤条件查询交易列表及满足条件的交易总数，按时间排序
+func GetFilteredTxList(genHash string, filter *TxFilter, page, pageSize int32, asc bool,
+	gormDb *gorm.DB) ([]*dbModel.Transaction, int64, error) {
 
-	var txList []*model.Transaction
+	var list []*dbModel.Transaction
 
 	tableNum, err := getChainTableNum(genHash, gormDb)
 	if err != nil {
-		return 0, err
+		return list, 0, err
 	}
 
-	queryDb := gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_Transaction+"_%02d", tableNum))
+	if tableNum == 0 {
+		return nil, 0, nil
+	}
+
+	queryDb := filter.apply(gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum))).
+		Session(&gorm.Session{})
+
+	var total int64
+
+	err = queryDb.Count(&total).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	offset := (page - 1) * pageSize
 
-	if len(contractName) != 0 {
-		queryDb = queryDb.Where("contract_name = ?", contractName)
+	err = queryDb.Limit(int(pageSize)).Offset(int(offset)).
+		Order("timestamp " + sortOrder(asc)).Order("id " + sortOrder(asc)).
+		Find(&list).Error
+	if err != nil {
+		return list, 0, err
 	}
 
-	err = queryDb.Limit(limit).Find(&txList).Error
+	return list, total, nil
+}
+
+// GetTxListByCursor 按过滤条件以区块高度游标分页查询交易列表
+func GetTxListByCursor(genHash string, filter *TxFilter, p *CursorPage,
+	gormDb *gorm.DB) ([]*dbModel.Transaction, *CursorResult, error) {
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
 	if err != nil {
-		return 0, err
+		return nil, nil, err
 	}
 
-	if len(txList) < limit {
-		return len(txList), nil
+	if tableNum == 0 {
+		return nil, new(CursorResult), nil
 	}
 
-	return limit, nil
+	return findByCursor(filter.apply(gormDb.Table(db.ShardTableName(dbModel.TableNamePrefix_Transaction, tableNum))),
+		"block_height", p, func(t *dbModel.Transaction) (int64, uint) {
+			return int64(t.BlockHeight), t.ID
+		})
 }
 
 func GetTxInfo(genHash string, txId string, id int,
@@ -0,0 +1,46 @@
+package dao
+
+import (
+	dbModel "chainmscan/db/model"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// GetKeyWriteHistory 查询合约某个键的写入历史，按区块高度倒序
+func GetKeyWriteHistory(genHash string, contractName string, stateKey []byte, page, pageSize int32,
+	gormDb *gorm.DB) ([]*dbModel.TxWrite, int64, error) {
+
+	var list []*dbModel.TxWrite
+
+	tableNum, err := getChainTableNum(genHash, gormDb)
+	if err != nil {
+		return list, 0, err
+	}
+
+	if tableNum == 0 {
+		return nil, 0, nil
+	}
+
+	queryDb := gormDb.Table(fmt.Sprintf(dbModel.TableNamePrefix_TxWrite+"_%02d", tableNum)).
+		Where("contract_name = ? AND state_key = ?", contractName, stateKey).
+		Session(&gorm.Session{})
+
+	var total int64
+
+	err = queryDb.Count(&total).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+
+	err = queryDb.Limit(int(pageSize)).Offset(int(offset)).
+		Order("block_height desc").Order("id desc").
+		Find(&list).Error
+	if err != nil {
+		return list, 0, err
+	}
+
+	return list, total, nil
+}
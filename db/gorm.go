@@ -16,6 +16,9 @@ type DbModel interface {
 // TableSlice gorm自动建表模型列表
 var TableSlice = make([]interface{}, 0)
 
+// ShardTableSlice 按链分表的模型列表，表名为模型表名加"_%02d"序号后缀
+var ShardTableSlice = make([]DbModel, 0)
+
 type CommonField struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	CreatedAt time.Time `json:"createdAt"`
@@ -0,0 +1,24 @@
+package model
+
+import "chainmscan/db"
+
+const TableName_BackfillRange = "backfill_range"
+
+// BackfillRange 历史区块回填的高度区间，NextHeight为区间内下一个待回填的高度
+type BackfillRange struct {
+	db.CommonField
+	GenHash     string `gorm:"index:gen_hash_index"`
+	StartHeight uint64
+	EndHeight   uint64
+	NextHeight  uint64
+	Done        bool
+}
+
+func (t BackfillRange) TableName() string {
+	return TableName_BackfillRange
+}
+
+func init() {
+	t := new(BackfillRange)
+	db.TableSlice = append(db.TableSlice, t)
+}
@@ -23,10 +23,15 @@ CREATE TABLE `block` (
   `block_timestamp` bigint DEFAULT NULL,
   `proposer_org_id` varchar(256) DEFAULT NULL,
   `consensus_args` varchar(256) DEFAULT NULL,
+  `proposer_id` varchar(191) DEFAULT NULL,
+  `proposer_name` varchar(256) DEFAULT NULL,
+  `consensus_round` bigint DEFAULT NULL,
+  `consensus_args_hex` text,
   PRIMARY KEY (`id`),
   UNIQUE INDEX `block_hash_index` (`block_hash`),
   INDEX `block_timestamp_index` (`block_timestamp`),
-  INDEX `block_height_index` (`block_height`)
+  INDEX `block_height_index` (`block_height`),
+  INDEX `proposer_id_index` (`proposer_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 */
 
@@ -46,13 +51,19 @@ type Block struct {
 	BlockTimestamp int64  `json:"blockTimestamp" gorm:"index:block_timestamp_index"`
 	ProposerOrgId  string `json:"proposerOrgId"`
 	ConsensusArgs  string `json:"consensusArgs"`
+	// 出块节点的标识（节点地址）和证书CN
+	ProposerId     string `json:"proposerId" gorm:"size:191;index:proposer_id_index"`
+	ProposerName   string `json:"proposerName"`
+	ConsensusRound int64  `json:"consensusRound"`
+	// 共识参数的十六进制，旧版本入库的区块为空，ConsensusArgs保持原始内容不变
+	ConsensusArgsHex string `json:"consensusArgsHex" gorm:"type:text"`
 }
 
 func (t Block) TableName() string {
 	return TableNamePrefix_Block
 }
 
-// func init() {
-// 	t := new(Block)
-// 	db.TableSlice = append(db.TableSlice, t)
-// }
+func init() {
+	t := new(Block)
+	db.ShardTableSlice = append(db.ShardTableSlice, t)
+}
@@ -13,6 +13,7 @@ CREATE TABLE `block_details` (
   `proposer_bytes` longblob,
   `proposer_signature` longtext,
   `dag` longtext,
+  `consensus_info` longtext,
   PRIMARY KEY (`id`),
   UNIQUE INDEX `block_hash_index` (`block_hash`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -24,13 +25,15 @@ type BlockDetails struct {
 	ProposerBytes     []byte `gorm:"type:longblob"`
 	ProposerSignature string `gorm:"type:longtext"`
 	Dag               string `gorm:"type:longtext"`
+	// 解析后的共识参数和共识投票JSON
+	ConsensusInfo string `gorm:"type:longtext"`
 }
 
 func (t BlockDetails) TableName() string {
 	return TableNamePrefix_BlockDetails
 }
 
-// func init() {
-// 	t := new(BlockDetails)
-// 	db.TableSlice = append(db.TableSlice, t)
-// }
+func init() {
+	t := new(BlockDetails)
+	db.ShardTableSlice = append(db.ShardTableSlice, t)
+}
@@ -0,0 +1,60 @@
+package model
+
+import "chainmscan/db"
+
+const TableNamePrefix_ChainConfig = "chain_config"
+
+/*
+CREATE TABLE `chain_config` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  `block_height` bigint unsigned DEFAULT NULL,
+  `tx_id` varchar(256) DEFAULT NULL,
+  `method` varchar(256) DEFAULT NULL,
+  `sequence` bigint unsigned DEFAULT NULL,
+  `version` varchar(256) DEFAULT NULL,
+  `auth_type` varchar(256) DEFAULT NULL,
+  `consensus_type` varchar(256) DEFAULT NULL,
+  `consensus_node_count` int DEFAULT NULL,
+  `trust_root_count` int DEFAULT NULL,
+  `block_tx_capacity` int unsigned DEFAULT NULL,
+  `block_size` int unsigned DEFAULT NULL,
+  `block_interval` int unsigned DEFAULT NULL,
+  `tx_timeout` int unsigned DEFAULT NULL,
+  `config_json` longtext,
+  `timestamp` bigint DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `block_height_index` (`block_height`),
+  INDEX `tx_id_index` (`tx_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+*/
+
+// ChainConfig 链配置变更记录，ConfigJson为该交易执行后完整链配置的JSON
+type ChainConfig struct {
+	db.CommonField
+	BlockHeight        uint64 `json:"blockHeight" gorm:"index:block_height_index"`
+	TxId               string `json:"txId" gorm:"index:tx_id_index"`
+	Method             string `json:"method"`
+	Sequence           uint64 `json:"sequence"`
+	Version            string `json:"version"`
+	AuthType           string `json:"authType"`
+	ConsensusType      string `json:"consensusType"`
+	ConsensusNodeCount int    `json:"consensusNodeCount"`
+	TrustRootCount     int    `json:"trustRootCount"`
+	BlockTxCapacity    uint32 `json:"blockTxCapacity"`
+	BlockSize          uint32 `json:"blockSize"`
+	BlockInterval      uint32 `json:"blockInterval"`
+	TxTimeout          uint32 `json:"txTimeout"`
+	ConfigJson         string `json:"configJson" gorm:"type:longtext"`
+	Timestamp          int64  `json:"timestamp"`
+}
+
+func (t ChainConfig) TableName() string {
+	return TableNamePrefix_ChainConfig
+}
+
+func init() {
+	t := new(ChainConfig)
+	db.ShardTableSlice = append(db.ShardTableSlice, t)
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"chainmscan/db"
+	"time"
+)
+
+const (
+	TableNamePrefix_ChainDailyStats  = "chain_daily_stats"
+	TableNamePrefix_ChainDailySender = "chain_daily_sender"
+)
+
+/*
+CREATE TABLE `chain_daily_stats` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  `stat_date` bigint DEFAULT NULL,
+  `block_count` bigint DEFAULT NULL,
+  `tx_count` bigint DEFAULT NULL,
+  `failed_tx_count` bigint DEFAULT NULL,
+  `gas_used` bigint unsigned DEFAULT NULL,
+  `sender_count` bigint DEFAULT NULL,
+  `new_contract_count` bigint DEFAULT NULL,
+  `first_block_time` bigint DEFAULT NULL,
+  `last_block_time` bigint DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `stat_date_index` (`stat_date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+*/
+
+// ChainDailyStats 按自然日汇总的链统计，交易和合约按所在区块的时间归属，入库时增量累加
+type ChainDailyStats struct {
+	db.CommonField
+	// 当天零点（统计时区）的时间戳
+	StatDate         int64  `json:"statDate" gorm:"uniqueIndex:stat_date_index"`
+	BlockCount       int64  `json:"blockCount"`
+	TxCount          int64  `json:"txCount"`
+	FailedTxCount    int64  `json:"failedTxCount"`
+	GasUsed          uint64 `json:"gasUsed"`
+	SenderCount      int64  `json:"senderCount"`
+	NewContractCount int64  `json:"newContractCount"`
+	// 当天第一个和最后一个区块的时间戳，用于计算平均出块间隔
+	FirstBlockTime int64 `json:"firstBlockTime"`
+	LastBlockTime  int64 `json:"lastBlockTime"`
+}
+
+func (t ChainDailyStats) TableName() string {
+	return TableNamePrefix_ChainDailyStats
+}
+
+// AvgBlockInterval 当天的平均出块间隔，单位秒
+func (t *ChainDailyStats) AvgBlockInterval() float64 {
+	if t.BlockCount < 2 {
+		return 0
+	}
+
+	return float64(t.LastBlockTime-t.FirstBlockTime) / float64(t.BlockCount-1)
+}
+
+/*
+CREATE TABLE `chain_daily_sender` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  `stat_date` bigint DEFAULT NULL,
+  `sender_address` varchar(191) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `date_sender_index` (`stat_date`,`sender_address`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+*/
+
+// ChainDailySender 每天发送过交易的地址，用于增量统计当天去重后的发送者数
+type ChainDailySender struct {
+	db.CommonField
+	StatDate      int64  `json:"statDate" gorm:"uniqueIndex:date_sender_index,priority:1"`
+	SenderAddress string `json:"senderAddress" gorm:"size:191;uniqueIndex:date_sender_index,priority:2"`
+}
+
+func (t ChainDailySender) TableName() string {
+	return TableNamePrefix_ChainDailySender
+}
+
+// statLocation 统计切分自然日的时区，为固定的UTC偏移，Go与SQL按同一偏移计算，默认UTC
+var statLocation = time.UTC
+
+// SetStatUtcOffset 设置统计时区相对UTC的偏移（分钟），需在入库和查询统计前设置
+func SetStatUtcOffset(minutes int) {
+	statLocation = time.FixedZone("stats", minutes*60)
+}
+
+// StatLocation 统计时区
+func StatLocation() *time.Location {
+	return statLocation
+}
+
+// StatZoneOffset 统计时区相对UTC的偏移秒数
+func StatZoneOffset() int64 {
+	_, offset := time.Unix(0, 0).In(statLocation).Zone()
+
+	return int64(offset)
+}
+
+// DailyStatDate 时间戳所在自然日（统计时区）零点的时间戳
+func DailyStatDate(timestamp int64) int64 {
+	offset := StatZoneOffset()
+
+	return timestamp - ((timestamp+offset)%86400+86400)%86400
+}
+
+func init() {
+	t := new(ChainDailyStats)
+	db.ShardTableSlice = append(db.ShardTableSlice, t)
+
+	sender := new(ChainDailySender)
+	db.ShardTableSlice = append(db.ShardTableSlice, sender)
+}
@@ -11,6 +11,8 @@ type ChainInfo struct {
 	TableNum    int    `json:"tableNum"`
 	TxAmount    int    `json:"txAmount"`
 	BlockAmount int    `json:"blockAmount"`
+	// 旧版本入库的交易是否已补齐发送者地址
+	SenderAddressMigrated bool `json:"-"`
 }
 
 func (t ChainInfo) TableName() string {
@@ -20,31 +20,40 @@ CREATE TABLE `contract` (
   `height` bigint unsigned DEFAULT NULL,
   `tx_timestamp` bigint DEFAULT NULL,
   `creator_bytes` longblob,
+  `update_tx_id` varchar(256) DEFAULT NULL,
+  `update_height` bigint unsigned DEFAULT NULL,
+  `update_timestamp` bigint DEFAULT NULL,
   PRIMARY KEY (`id`),
+  UNIQUE INDEX `name_index` (`name`),
   INDEX `tx_timestamp_index` (`tx_timestamp`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 */
 
+// Contract 合约当前状态，每个合约一条记录，由合约生命周期记录推导。
+// TxId、Height、TxTimestamp及创建者为创建合约时的信息，Update*为最近一次生命周期操作的信息
 type Contract struct {
 	db.CommonField
-	Name         string `json:"name"`
-	Version      string `json:"version"`
-	ChainId      string `json:"chainId"`
-	RuntimeType  string `json:"runtimeType"`
-	State        string `json:"state"`
-	CreatorOrgId string `json:"creatorOrgId"`
-	Address      string `json:"address"`
-	TxId         string `json:"txId"`
-	Height       uint64 `json:"height"`
-	TxTimestamp  int64  `json:"txTimestamp" gorm:"index:tx_timestamp_index"`
-	CreatorBytes []byte `json:"creatorBytes" gorm:"type:longblob"`
+	Name            string `json:"name" gorm:"uniqueIndex:name_index"`
+	Version         string `json:"version"`
+	ChainId         string `json:"chainId"`
+	RuntimeType     string `json:"runtimeType"`
+	State           string `json:"state"`
+	CreatorOrgId    string `json:"creatorOrgId"`
+	Address         string `json:"address"`
+	TxId            string `json:"txId"`
+	Height          uint64 `json:"height"`
+	TxTimestamp     int64  `json:"txTimestamp" gorm:"index:tx_timestamp_index"`
+	CreatorBytes    []byte `json:"creatorBytes" gorm:"type:longblob"`
+	UpdateTxId      string `json:"updateTxId"`
+	UpdateHeight    uint64 `json:"updateHeight"`
+	UpdateTimestamp int64  `json:"updateTimestamp"`
 }
 
 func (t Contract) TableName() string {
 	return TableNamePrefix_Contract
 }
 
-// func init() {
-// 	t := new(Contract)
-// 	db.TableSlice = append(db.TableSlice, t)
-// }
+func init() {
+	t := new(Contract)
+	db.ShardTableSlice = append(db.ShardTableSlice, t)
+}
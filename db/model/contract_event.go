@@ -0,0 +1,48 @@
+package model
+
+import "chainmscan/db"
+
+const TableNamePrefix_ContractEvent = "contract_event"
+
+/*
+CREATE TABLE `contract_event` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  `tx_id` varchar(256) DEFAULT NULL,
+  `block_height` bigint unsigned DEFAULT NULL,
+  `contract_name` varchar(256) DEFAULT NULL,
+  `contract_version` varchar(256) DEFAULT NULL,
+  `topic` varchar(256) DEFAULT NULL,
+  `event_index` int DEFAULT NULL,
+  `event_data` longtext,
+  `timestamp` bigint DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `tx_id_index` (`tx_id`),
+  INDEX `contract_topic_index` (`contract_name`,`topic`,`block_height`),
+  INDEX `block_height_index` (`block_height`),
+  INDEX `timestamp_index` (`timestamp`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+*/
+
+// ContractEvent 合约事件，EventData为事件数据数组的JSON
+type ContractEvent struct {
+	db.CommonField
+	TxId            string `json:"txId" gorm:"index:tx_id_index"`
+	BlockHeight     uint64 `json:"blockHeight" gorm:"index:contract_topic_index,priority:3;index:block_height_index"`
+	ContractName    string `json:"contractName" gorm:"index:contract_topic_index,priority:1"`
+	ContractVersion string `json:"contractVersion"`
+	Topic           string `json:"topic" gorm:"index:contract_topic_index,priority:2"`
+	EventIndex      int    `json:"eventIndex"`
+	EventData       string `json:"eventData" gorm:"type:longtext"`
+	Timestamp       int64  `json:"timestamp" gorm:"index:timestamp_index"`
+}
+
+func (t ContractEvent) TableName() string {
+	return TableNamePrefix_ContractEvent
+}
+
+func init() {
+	t := new(ContractEvent)
+	db.ShardTableSlice = append(db.ShardTableSlice, t)
+}
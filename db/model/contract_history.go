@@ -0,0 +1,72 @@
+package model
+
+import "chainmscan/db"
+
+const TableNamePrefix_ContractHistory = "contract_history"
+
+// 合约生命周期操作
+const (
+	ContractOperation_Init     = "init"
+	ContractOperation_Upgrade  = "upgrade"
+	ContractOperation_Freeze   = "freeze"
+	ContractOperation_Unfreeze = "unfreeze"
+	ContractOperation_Revoke   = "revoke"
+)
+
+/*
+CREATE TABLE `contract_history` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  `name` varchar(256) DEFAULT NULL,
+  `operation` varchar(256) DEFAULT NULL,
+  `version` varchar(256) DEFAULT NULL,
+  `chain_id` varchar(256) DEFAULT NULL,
+  `runtime_type` varchar(256) DEFAULT NULL,
+  `state` varchar(256) DEFAULT NULL,
+  `creator_org_id` varchar(256) DEFAULT NULL,
+  `operator_org_id` varchar(256) DEFAULT NULL,
+  `address` varchar(256) DEFAULT NULL,
+  `tx_id` varchar(256) DEFAULT NULL,
+  `block_height` bigint unsigned DEFAULT NULL,
+  `timestamp` bigint DEFAULT NULL,
+  `creator_bytes` longblob,
+  `operator_address` varchar(256) DEFAULT NULL,
+  `operator_evm_address` varchar(256) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `name_height_index` (`name`,`block_height`),
+  INDEX `tx_id_index` (`tx_id`),
+  INDEX `operator_address_index` (`operator_address`),
+  INDEX `operator_evm_address_index` (`operator_evm_address`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+*/
+
+// ContractHistory 合约生命周期记录，每次成功的合约管理操作一条，版本、状态等为操作后的合约信息
+type ContractHistory struct {
+	db.CommonField
+	Name          string `json:"name" gorm:"index:name_height_index,priority:1"`
+	Operation     string `json:"operation"`
+	Version       string `json:"version"`
+	ChainId       string `json:"chainId"`
+	RuntimeType   string `json:"runtimeType"`
+	State         string `json:"state"`
+	CreatorOrgId  string `json:"creatorOrgId"`
+	OperatorOrgId string `json:"operatorOrgId"`
+	Address       string `json:"address"`
+	TxId          string `json:"txId" gorm:"index:tx_id_index"`
+	BlockHeight   uint64 `json:"blockHeight" gorm:"index:name_height_index,priority:2"`
+	Timestamp     int64  `json:"timestamp"`
+	CreatorBytes  []byte `json:"creatorBytes" gorm:"type:longblob"`
+	// 操作者的ChainMaker格式地址和EVM格式地址
+	OperatorAddress    string `json:"operatorAddress" gorm:"index:operator_address_index"`
+	OperatorEvmAddress string `json:"operatorEvmAddress" gorm:"index:operator_evm_address_index"`
+}
+
+func (t ContractHistory) TableName() string {
+	return TableNamePrefix_ContractHistory
+}
+
+func init() {
+	t := new(ContractHistory)
+	db.ShardTableSlice = append(db.ShardTableSlice, t)
+}
@@ -0,0 +1,47 @@
+package model
+
+import "chainmscan/db"
+
+const TableNamePrefix_GasRollup = "gas_rollup"
+
+// GasRollupInterval 燃料统计的时间粒度，单位秒
+const GasRollupInterval = 3600
+
+/*
+CREATE TABLE `gas_rollup` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  `bucket_time` bigint DEFAULT NULL,
+  `contract_name` varchar(191) DEFAULT NULL,
+  `method` varchar(191) DEFAULT NULL,
+  `sender_org_id` varchar(191) DEFAULT NULL,
+  `tx_count` bigint DEFAULT NULL,
+  `gas_used` bigint unsigned DEFAULT NULL,
+  `gas_limit` bigint unsigned DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `rollup_index` (`bucket_time`,`contract_name`,`method`,`sender_org_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+*/
+
+// GasRollup 按小时、合约、方法、发送者组织汇总的燃料消耗，入库时增量累加。
+// 唯一索引包含三个字符串列，限制为191字符以免超过InnoDB索引长度上限
+type GasRollup struct {
+	db.CommonField
+	BucketTime   int64  `json:"bucketTime" gorm:"uniqueIndex:rollup_index,priority:1"`
+	ContractName string `json:"contractName" gorm:"size:191;uniqueIndex:rollup_index,priority:2"`
+	Method       string `json:"method" gorm:"size:191;uniqueIndex:rollup_index,priority:3"`
+	SenderOrgId  string `json:"senderOrgId" gorm:"size:191;uniqueIndex:rollup_index,priority:4"`
+	TxCount      int64  `json:"txCount"`
+	GasUsed      uint64 `json:"gasUsed"`
+	GasLimit     uint64 `json:"gasLimit"`
+}
+
+func (t GasRollup) TableName() string {
+	return TableNamePrefix_GasRollup
+}
+
+func init() {
+	t := new(GasRollup)
+	db.ShardTableSlice = append(db.ShardTableSlice, t)
+}
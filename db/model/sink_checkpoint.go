@@ -0,0 +1,23 @@
+package model
+
+import "chainmscan/db"
+
+const TableName_SinkCheckpoint = "sink_checkpoint"
+
+// SinkCheckpoint 区块导出目标的检查点，NextHeight为下一个待导出的高度
+type SinkCheckpoint struct {
+	db.CommonField
+	GenHash    string `gorm:"uniqueIndex:gen_hash_sink_name_index"`
+	SinkName   string `gorm:"uniqueIndex:gen_hash_sink_name_index"`
+	NextHeight uint64
+	LastError  string `gorm:"type:text"`
+}
+
+func (t SinkCheckpoint) TableName() string {
+	return TableName_SinkCheckpoint
+}
+
+func init() {
+	t := new(SinkCheckpoint)
+	db.TableSlice = append(db.TableSlice, t)
+}
@@ -6,10 +6,11 @@ const TableName_Subscription = "subscription"
 
 type Subscription struct {
 	db.CommonField
-	GenHash          string `gorm:"uniqueIndex:gen_hash_index"`
-	ChainName        string
-	ChainId          string
-	OrgId            string
+	GenHash   string `gorm:"uniqueIndex:gen_hash_index"`
+	ChainName string
+	ChainId   string
+	OrgId     string
+	// 旧版本的单节点配置，新订阅的节点配置保存在subscription_node
 	NodeAddr         string
 	NodeCaCertPem    string `gorm:"type:longtext"`
 	NodeTlsHostName  string
@@ -19,6 +20,17 @@ type Subscription struct {
 	TlsCertPem       string `gorm:"type:longtext"`
 	TlsKeyPem        string `gorm:"type:longtext"`
 	ArchiveCenterUrl string
+	// 是否索引交易读写集
+	WithRwSet bool
+	// 重新订阅次数、最近一次重试时间及错误信息
+	RetryCount    int
+	LastRetryTime int64
+	LastError     string `gorm:"type:text"`
+	// 是否已暂停同步
+	Paused bool
+	// 身份认证模式及公钥模式的哈希算法
+	AuthType      string
+	HashAlgorithm string
 }
 
 func (t Subscription) TableName() string {
@@ -0,0 +1,28 @@
+package model
+
+import "chainmscan/db"
+
+const TableName_SubscriptionNode = "subscription_node"
+
+// SubscriptionNode 订阅链的节点列表，建立客户端时全部交给SDK的节点连接池
+type SubscriptionNode struct {
+	db.CommonField
+	GenHash   string `gorm:"index:gen_hash_index"`
+	NodeIndex int
+	Addr      string
+	// 节点的CA证书列表
+	CaCertPems []string `gorm:"serializer:json;type:longtext"`
+	// 旧版本按换行拼接的CA证书，CaCertPems为空时读取
+	CaCertPem   string `gorm:"type:longtext"`
+	TlsHostName string
+	UseTls      bool
+}
+
+func (t SubscriptionNode) TableName() string {
+	return TableName_SubscriptionNode
+}
+
+func init() {
+	t := new(SubscriptionNode)
+	db.TableSlice = append(db.TableSlice, t)
+}
@@ -19,13 +19,22 @@ CREATE TABLE `transaction` (
   `expiration_time` bigint DEFAULT NULL,
   `sequence` bigint unsigned DEFAULT NULL,
   `gas_limit` bigint unsigned DEFAULT NULL,
+  `gas_used` bigint unsigned DEFAULT NULL,
   `sender_org_id` varchar(256) DEFAULT NULL,
   `tx_status_code` varchar(256) DEFAULT NULL,
+  `sender_address` varchar(256) DEFAULT NULL,
+  `sender_evm_address` varchar(256) DEFAULT NULL,
   PRIMARY KEY (`id`),
   UNIQUE INDEX `tx_id_index` (`tx_id`),
   INDEX `block_height_index` (`block_height`),
   INDEX `contract_name_index` (`contract_name`),
-  INDEX `timestamp_index` (`timestamp`)
+  INDEX `timestamp_index` (`timestamp`),
+  INDEX `sender_address_index` (`sender_address`),
+  INDEX `sender_evm_address_index` (`sender_evm_address`),
+  INDEX `contract_method_index` (`contract_name`, `method`),
+  INDEX `tx_type_index` (`tx_type`),
+  INDEX `sender_org_id_index` (`sender_org_id`),
+  INDEX `tx_status_code_index` (`tx_status_code`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 */
 
@@ -34,22 +43,26 @@ type Transaction struct {
 	TxId           string `json:"txId" gorm:"uniqueIndex:tx_id_index"`
 	BlockHeight    uint64 `json:"blockHeight" gorm:"index:block_height_index"`
 	ChainId        string `json:"chainId"`
-	ContractName   string `json:"contractName" gorm:"index:contract_name_index"`
-	Method         string `json:"method"`
-	TxType         string `json:"txType"`
+	ContractName   string `json:"contractName" gorm:"index:contract_name_index;index:contract_method_index,priority:1"`
+	Method         string `json:"method" gorm:"index:contract_method_index,priority:2"`
+	TxType         string `json:"txType" gorm:"index:tx_type_index"`
 	Timestamp      int64  `json:"timestamp" gorm:"index:timestamp_index"`
 	ExpirationTime int64  `json:"expirationTime"`
 	Sequence       uint64 `json:"sequence"`
 	GasLimit       uint64 `json:"gasLimit"`
-	SenderOrgId    string `json:"senderOrgId"`
-	TxStatusCode   string `json:"txStatusCode"`
+	GasUsed        uint64 `json:"gasUsed"`
+	SenderOrgId    string `json:"senderOrgId" gorm:"index:sender_org_id_index"`
+	TxStatusCode   string `json:"txStatusCode" gorm:"index:tx_status_code_index"`
+	// 发送者的ChainMaker格式地址和EVM格式地址
+	SenderAddress    string `json:"senderAddress" gorm:"index:sender_address_index"`
+	SenderEvmAddress string `json:"senderEvmAddress" gorm:"index:sender_evm_address_index"`
 }
 
 func (t Transaction) TableName() string {
 	return TableNamePrefix_Transaction
 }
 
-// func init() {
-// 	t := new(Transaction)
-// 	db.TableSlice = append(db.TableSlice, t)
-// }
+func init() {
+	t := new(Transaction)
+	db.ShardTableSlice = append(db.ShardTableSlice, t)
+}
@@ -50,7 +50,7 @@ func (t TxDetails) TableName() string {
 	return TableNamePrefix_TxDetails
 }
 
-// func init() {
-// 	t := new(TxDetails)
-// 	db.TableSlice = append(db.TableSlice, t)
-// }
+func init() {
+	t := new(TxDetails)
+	db.ShardTableSlice = append(db.ShardTableSlice, t)
+}
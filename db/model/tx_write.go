@@ -0,0 +1,49 @@
+package model
+
+import "chainmscan/db"
+
+const TableNamePrefix_TxWrite = "tx_write"
+
+/*
+CREATE TABLE `tx_write` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime DEFAULT NULL,
+  `updated_at` datetime DEFAULT NULL,
+  `tx_id` varchar(256) DEFAULT NULL,
+  `block_height` bigint unsigned DEFAULT NULL,
+  `write_index` int DEFAULT NULL,
+  `contract_name` varchar(256) DEFAULT NULL,
+  `state_key` varbinary(1024) DEFAULT NULL,
+  `state_value` longblob,
+  `timestamp` bigint DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `tx_id_index` (`tx_id`),
+  INDEX `contract_key_index` (`contract_name`,`state_key`,`block_height`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+*/
+
+// StateKeyMaxSize 状态键的最大长度，超长的键不计入状态历史
+const StateKeyMaxSize = 1024
+
+// TxWrite 交易写集索引，每条记录对应一次合约状态写入。
+// 状态键可能是二进制数据（如EVM存储槽），按原始字节存储为varbinary，
+// 旧版本的varchar列迁移时按原UTF-8字节转换
+type TxWrite struct {
+	db.CommonField
+	TxId         string `json:"txId" gorm:"index:tx_id_index"`
+	BlockHeight  uint64 `json:"blockHeight" gorm:"index:contract_key_index,priority:3"`
+	WriteIndex   int    `json:"writeIndex"`
+	ContractName string `json:"contractName" gorm:"index:contract_key_index,priority:1"`
+	StateKey     []byte `json:"stateKey" gorm:"size:1024;index:contract_key_index,priority:2"`
+	StateValue   []byte `json:"stateValue" gorm:"type:longblob"`
+	Timestamp    int64  `json:"timestamp"`
+}
+
+func (t TxWrite) TableName() string {
+	return TableNamePrefix_TxWrite
+}
+
+func init() {
+	t := new(TxWrite)
+	db.ShardTableSlice = append(db.ShardTableSlice, t)
+}
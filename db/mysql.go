@@ -11,6 +11,12 @@ import (
 	"gorm.io/gorm/schema"
 )
 
+// DefaultStringSize string类型字段的默认长度，索引列的长度需满足InnoDB索引长度上限
+const DefaultStringSize = 256
+
+// MaxIndexKeyBytes InnoDB（DYNAMIC行格式）单个索引的最大长度
+const MaxIndexKeyBytes = 3072
+
 type MysqlConfig struct {
 	User       string `mapstructure:"user"`
 	Password   string `mapstructure:"password"`
@@ -33,11 +39,11 @@ func MysqlInit(mysqlConf *MysqlConfig, gormConfig *GormConfig, tableSlice []inte
 	glogger := logger.NewGormLogger(zaplogger, 200*time.Millisecond, false)
 	gormDb, err := gorm.Open(mysql.New(mysql.Config{
 		DSN:                       getMysqlDns(mysqlConf),
-		DefaultStringSize:         256,   // string 类型字段的默认长度
-		DisableDatetimePrecision:  true,  // 禁用 datetime 精度，MySQL 5.6 之前的数据库不支持
-		DontSupportRenameIndex:    true,  // 重命名索引时采用删除并新建的方式，MySQL 5.7 之前的数据库和 MariaDB 不支持重命名索引
-		DontSupportRenameColumn:   true,  // 用 `change` 重命名列，MySQL 8 之前的数据库和 MariaDB 不支持重命名列
-		SkipInitializeWithVersion: false, // 根据当前 MySQL 版本自动配置
+		DefaultStringSize:         DefaultStringSize, // string 类型字段的默认长度
+		DisableDatetimePrecision:  true,              // 禁用 datetime 精度，MySQL 5.6 之前的数据库不支持
+		DontSupportRenameIndex:    true,              // 重命名索引时采用删除并新建的方式，MySQL 5.7 之前的数据库和 MariaDB 不支持重命名索引
+		DontSupportRenameColumn:   true,              // 用 `change` 重命名列，MySQL 8 之前的数据库和 MariaDB 不支持重命名列
+		SkipInitializeWithVersion: false,             // 根据当前 MySQL 版本自动配置
 	}), &gorm.Config{
 		Logger: glogger,
 		NamingStrategy: schema.NamingStrategy{
@@ -66,3 +72,21 @@ func MysqlInit(mysqlConf *MysqlConfig, gormConfig *GormConfig, tableSlice []inte
 
 	return gormDb, nil
 }
+
+// ShardTableName 链分表表名
+func ShardTableName(tableNamePrefix string, tableNum int) string {
+	return fmt.Sprintf(tableNamePrefix+"_%02d", tableNum)
+}
+
+// MigrateShardTables 创建或迁移链的全部分表及索引
+func MigrateShardTables(gormDb *gorm.DB, tableNum int) error {
+	for _, t := range ShardTableSlice {
+		err := gormDb.Table(ShardTableName(t.TableName(), tableNum)).AutoMigrate(t)
+		if err != nil {
+			return fmt.Errorf("fail to migrate the table [%s], err: [%s]",
+				ShardTableName(t.TableName(), tableNum), err.Error())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package db_test
+
+import (
+	"chainmscan/db"
+	_ "chainmscan/db/model"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm/schema"
+)
+
+// 用于迁移测试的分表序号，测试结束后删除
+const testTableNum = 99
+
+// indexKeyBytes 按utf8mb4估算索引长度，字符串列按声明长度（未声明时为DefaultStringSize）计算
+func indexKeyBytes(idx schema.Index) int {
+	n := 0
+
+	for _, opt := range idx.Fields {
+		f := opt.Field
+
+		switch f.DataType {
+		case schema.String:
+			size := f.Size
+			if size == 0 {
+				size = db.DefaultStringSize
+			}
+			n += size * 4
+		case schema.Bytes:
+			n += f.Size
+		case schema.Time:
+			n += 5
+		default:
+			n += f.Size / 8
+		}
+	}
+
+	return n
+}
+
+func TestIndexKeyLength(t *testing.T) {
+	models := make([]interface{}, 0, len(db.TableSlice)+len(db.ShardTableSlice))
+	models = append(models, db.TableSlice...)
+	for _, m := range db.ShardTableSlice {
+		models = append(models, m)
+	}
+
+	cache := &sync.Map{}
+
+	for _, m := range models {
+		s, err := schema.Parse(m, cache, schema.NamingStrategy{SingularTable: true})
+		if err != nil {
+			t.Fatalf("fail to parse the model [%s], err: [%s]", reflect.TypeOf(m), err.Error())
+		}
+
+		for _, idx := range s.ParseIndexes() {
+			if n := indexKeyBytes(idx); n > db.MaxIndexKeyBytes {
+				t.Errorf("the index [%s] of table [%s] is too long, %d bytes", idx.Name, s.Table, n)
+			}
+		}
+	}
+}
+
+// TestMigrateShardTables 在真实的MySQL上建表并重复迁移，设置CHAINMSCAN_TEST_MYSQL_HOST等环境变量后执行
+func TestMigrateShardTables(t *testing.T) {
+	host := os.Getenv("CHAINMSCAN_TEST_MYSQL_HOST")
+	if len(host) == 0 {
+		t.Skip("CHAINMSCAN_TEST_MYSQL_HOST is not set")
+	}
+
+	mysqlConf := &db.MysqlConfig{
+		User:       os.Getenv("CHAINMSCAN_TEST_MYSQL_USER"),
+		Password:   os.Getenv("CHAINMSCAN_TEST_MYSQL_PASSWORD"),
+		Host:       host,
+		Port:       os.Getenv("CHAINMSCAN_TEST_MYSQL_PORT"),
+		DbName:     os.Getenv("CHAINMSCAN_TEST_MYSQL_DBNAME"),
+		Parameters: "charset=utf8mb4&parseTime=True&loc=Local",
+	}
+
+	gormConf := &db.GormConfig{
+		MaxLifetime:       int(time.Minute / time.Second),
+		MaxOpenConns:      10,
+		MaxIdleConns:      2,
+		EnableAutoMigrate: true,
+	}
+
+	gormDb, err := db.MysqlInit(mysqlConf, gormConf, db.TableSlice, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatalf("fail to init mysql, err: [%s]", err.Error())
+	}
+
+	defer func() {
+		for _, m := range db.ShardTableSlice {
+			err := gormDb.Migrator().DropTable(db.ShardTableName(m.TableName(), testTableNum))
+			if err != nil {
+				t.Errorf("fail to drop the table, err: [%s]", err.Error())
+			}
+		}
+	}()
+
+	// 第二次迁移校验已存在的表和索引
+	for i := 0; i < 2; i++ {
+		err = db.MigrateShardTables(gormDb, testTableNum)
+		if err != nil {
+			t.Fatalf("fail to migrate the shard tables, err: [%s]", err.Error())
+		}
+	}
+
+	for _, m := range db.ShardTableSlice {
+		if !gormDb.Migrator().HasTable(db.ShardTableName(m.TableName(), testTableNum)) {
+			t.Errorf("the table [%s] was not created", db.ShardTableName(m.TableName(), testTableNum))
+		}
+	}
+}
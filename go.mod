@@ -5,6 +5,7 @@ go 1.21
 toolchain go1.22.7
 
 require (
+	chainmaker.org/chainmaker/common/v2 v2.3.5
 	chainmaker.org/chainmaker/pb-go/v2 v2.3.6
 	chainmaker.org/chainmaker/sdk-go/v2 v2.3.4
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
@@ -22,7 +23,6 @@ require (
 )
 
 require (
-	chainmaker.org/chainmaker/common/v2 v2.3.5 // indirect
 	chainmaker.org/chainmaker/protocol/v2 v2.3.6 // indirect
 	chainmaker.org/chainmaker/utils/v2 v2.3.5 // indirect
 	cloud.google.com/go/iam v1.2.2 // indirect
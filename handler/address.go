@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"chainmscan/blockchain"
+	"chainmscan/db/dao"
+	"chainmscan/server"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AddressDetailsHandler struct {
+}
+
+type AddressDetailsReq struct {
+	GenHash string `json:"genHash"`
+	// ChainMaker地址或EVM地址，可带0x前缀
+	Address string `json:"address"`
+}
+
+type AddressDetailsResp struct {
+	Address              string `json:"address"`
+	EvmAddress           string `json:"evmAddress"`
+	OrgId                string `json:"orgId"`
+	TxCount              int64  `json:"txCount"`
+	FirstSeen            int64  `json:"firstSeen"`
+	LastSeen             int64  `json:"lastSeen"`
+	CreatedContractCount int64  `json:"createdContractCount"`
+	CalledContractCount  int64  `json:"calledContractCount"`
+}
+
+func (h *AddressDetailsHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(AddressDetailsReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash, req.Address)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		log, err := s.GetZapLogger("AddressDetailsHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		address := blockchain.NormalizeAddress(req.Address)
+
+		summary, err := dao.GetAddressSummary(req.GenHash, address, s.Db())
+		if err != nil {
+			log.Errorf("fail to get address summary, err: [%s], genHash: [%s], address: [%s]\n",
+				err.Error(), req.GenHash, address)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		if summary == nil {
+			SuccessfulJSONResp(&AddressDetailsResp{}, "", c)
+			return
+		}
+
+		createdCount, err := dao.CountAddressCreatedContracts(req.GenHash, address, s.Db())
+		if err != nil {
+			log.Errorf("fail to count created contracts, err: [%s], genHash: [%s], address: [%s]\n",
+				err.Error(), req.GenHash, address)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		_, calledCount, err := dao.GetAddressCalledContracts(req.GenHash, address, 1, 1, s.Db())
+		if err != nil {
+			log.Errorf("fail to count called contracts, err: [%s], genHash: [%s], address: [%s]\n",
+				err.Error(), req.GenHash, address)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		resp := &AddressDetailsResp{
+			Address:              summary.SenderAddress,
+			EvmAddress:           summary.SenderEvmAddress,
+			OrgId:                summary.SenderOrgId,
+			TxCount:              summary.TxCount,
+			FirstSeen:            summary.FirstSeen,
+			LastSeen:             summary.LastSeen,
+			CreatedContractCount: createdCount,
+			CalledContractCount:  calledCount,
+		}
+
+		SuccessfulJSONResp(resp, "", c)
+	}
+}
+
+type AddressTxListHandler struct {
+}
+
+type AddressTxListReq struct {
+	PageReq
+	GenHash string `json:"genHash"`
+	Address string `json:"address"`
+}
+
+func (h *AddressTxListHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(AddressTxListReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash, req.Address)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		checkPageReq(&req.PageReq)
+
+		log, err := s.GetZapLogger("AddressTxListHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		address := blockchain.NormalizeAddress(req.Address)
+
+		list, total, err := dao.GetAddressTxList(req.GenHash, address, req.Page, req.PageSize, s.Db())
+		if err != nil {
+			log.Errorf("fail to get address tx list, err: [%s], genHash: [%s], address: [%s]\n",
+				err.Error(), req.GenHash, address)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		resp := make([]*TxListResp, 0)
+
+		for _, v := range list {
+			resp = append(resp, &TxListResp{
+				Id:               v.ID,
+				TxId:             v.TxId,
+				BlockHeight:      v.BlockHeight,
+				ChainId:          v.ChainId,
+				ContractName:     v.ContractName,
+				Method:           v.Method,
+				TxType:           v.TxType,
+				Timestamp:        v.Timestamp,
+				SenderOrgId:      v.SenderOrgId,
+				SenderAddress:    v.SenderAddress,
+				SenderEvmAddress: v.SenderEvmAddress,
+				TxStatusCode:     v.TxStatusCode,
+			})
+		}
+
+		SuccessfulJSONRespWithPage(resp, total, c)
+	}
+}
+
+type AddressCreatedContractsHandler struct {
+}
+
+type AddressContractsReq struct {
+	PageReq
+	GenHash string `json:"genHash"`
+	Address string `json:"address"`
+}
+
+func (h *AddressCreatedContractsHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(AddressContractsReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash, req.Address)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		checkPageReq(&req.PageReq)
+
+		log, err := s.GetZapLogger("AddressCreatedContractsHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		address := blockchain.NormalizeAddress(req.Address)
+
+		list, total, err := dao.GetAddressCreatedContracts(req.GenHash, address, req.Page, req.PageSize, s.Db())
+		if err != nil {
+			log.Errorf("fail to get created contracts, err: [%s], genHash: [%s], address: [%s]\n",
+				err.Error(), req.GenHash, address)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		resp := make([]*ContractHistoryResp, 0)
+
+		for _, v := range list {
+			resp = append(resp, &ContractHistoryResp{
+				Id:              v.ID,
+				Name:            v.Name,
+				Operation:       v.Operation,
+				Version:         v.Version,
+				RuntimeType:     v.RuntimeType,
+				State:           v.State,
+				OperatorOrgId:   v.OperatorOrgId,
+				OperatorAddress: v.OperatorAddress,
+				TxId:            v.TxId,
+				BlockHeight:     v.BlockHeight,
+				Timestamp:       v.Timestamp,
+			})
+		}
+
+		SuccessfulJSONRespWithPage(resp, total, c)
+	}
+}
+
+type AddressCalledContractsHandler struct {
+}
+
+type AddressCalledContractsResp struct {
+	ContractName  string `json:"contractName"`
+	TxCount       int64  `json:"txCount"`
+	FirstCallTime int64  `json:"firstCallTime"`
+	LastCallTime  int64  `json:"lastCallTime"`
+}
+
+func (h *AddressCalledContractsHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(AddressContractsReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash, req.Address)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		checkPageReq(&req.PageReq)
+
+		log, err := s.GetZapLogger("AddressCalledContractsHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		address := blockchain.NormalizeAddress(req.Address)
+
+		list, total, err := dao.GetAddressCalledContracts(req.GenHash, address, req.Page, req.PageSize, s.Db())
+		if err != nil {
+			log.Errorf("fail to get called contracts, err: [%s], genHash: [%s], address: [%s]\n",
+				err.Error(), req.GenHash, address)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		resp := make([]*AddressCalledContractsResp, 0)
+
+		for _, v := range list {
+			resp = append(resp, &AddressCalledContractsResp{
+				ContractName:  v.ContractName,
+				TxCount:       v.TxCount,
+				FirstCallTime: v.FirstCallTime,
+				LastCallTime:  v.LastCallTime,
+			})
+		}
+
+		SuccessfulJSONRespWithPage(resp, total, c)
+	}
+}
@@ -1,12 +1,14 @@
 package handler
 
 import (
+	"chainmscan/blockchain"
 	"chainmscan/db/dao"
+	dbModel "chainmscan/db/model"
 	"chainmscan/server"
+	"encoding/hex"
+	"encoding/json"
 
-	"chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
 	"github.com/gin-gonic/gin"
-	"github.com/gogo/protobuf/proto"
 )
 
 type BlockListHandler struct {
@@ -46,23 +48,39 @@ func (h *BlockListHandler) Handle(s *server.Server) gin.HandlerFunc {
 
 		checkPageReq(&req.PageReq)
 
+		cursorPage, err := req.cursorPage()
+		if err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
 		log, err := s.GetZapLogger("BlockListHandler")
 		if err != nil {
 			FailedJSONResp(RespMsgLogServerError, c)
 			return
 		}
 
-		list, err := dao.GetBlockList(req.GenHash, req.Page, req.PageSize, s.Db())
+		if cursorPage != nil {
+			list, cursor, err := dao.GetBlockListByCursor(req.GenHash, cursorPage, s.Db())
+			if err != nil {
+				log.Errorf("fail to get block list by cursor, err: [%s], genHash: [%s]\n", err.Error(), req.GenHash)
+				FailedJSONResp(RespMsgServerError, c)
+				return
+			}
+
+			SuccessfulJSONRespWithCursor(newBlockListResp(list), cursor, c)
+			return
+		}
+
+		list, err := dao.GetBlockList(req.GenHash, req.Page, req.PageSize, req.isAsc(), s.Db())
 		if err != nil {
 			log.Errorf("fail to get block list, err: [%s], genHash: [%s]\n", err.Error(), req.GenHash)
 			FailedJSONResp(RespMsgServerError, c)
 			return
 		}
 
-		resp := make([]*BlockListResp, 0)
-
 		if list == nil {
-			SuccessfulJSONRespWithPage(resp, 0, c)
+			SuccessfulJSONRespWithPage(make([]*BlockListResp, 0), 0, c)
 			return
 		}
 
@@ -73,23 +91,29 @@ func (h *BlockListHandler) Handle(s *server.Server) gin.HandlerFunc {
 			return
 		}
 
-		for _, v := range list {
-			bl := &BlockListResp{
-				Id:             v.ID,
-				BlockHeight:    v.BlockHeight,
-				BlockHash:      v.BlockHash,
-				ChainId:        v.ChainId,
-				PreBlockHash:   v.PreBlockHash,
-				TxCount:        v.TxCount,
-				TxRoot:         v.TxRoot,
-				BlockTimestamp: v.BlockTimestamp,
-				ProposerOrgId:  v.ProposerOrgId,
-			}
-			resp = append(resp, bl)
-		}
+		SuccessfulJSONRespWithPage(newBlockListResp(list), int64(chainInfo.BlockAmount), c)
+	}
+}
+
+func newBlockListResp(list []*dbModel.Block) []*BlockListResp {
+	resp := make([]*BlockListResp, 0, len(list))
 
-		SuccessfulJSONRespWithPage(resp, int64(chainInfo.BlockAmount), c)
+	for _, v := range list {
+		bl := &BlockListResp{
+			Id:             v.ID,
+			BlockHeight:    v.BlockHeight,
+			BlockHash:      v.BlockHash,
+			ChainId:        v.ChainId,
+			PreBlockHash:   v.PreBlockHash,
+			TxCount:        v.TxCount,
+			TxRoot:         v.TxRoot,
+			BlockTimestamp: v.BlockTimestamp,
+			ProposerOrgId:  v.ProposerOrgId,
+		}
+		resp = append(resp, bl)
 	}
+
+	return resp
 }
 
 type BlockDetailsHandler struct {
@@ -117,6 +141,20 @@ type BlockDetailsResp struct {
 	BlockTimestamp int64  `json:"blockTimestamp"`
 	ProposerOrgId  string `json:"proposerOrgId"`
 	Proposer       string `json:"proposer"`
+	// 解析后的出块者身份
+	ProposerIdentity *blockchain.Identity `json:"proposerIdentity"`
+	ProposerId       string               `json:"proposerId"`
+	ProposerName     string               `json:"proposerName"`
+	// 区块头共识参数的原始内容、十六进制及解析结果
+	ConsensusArgs    string                    `json:"consensusArgs"`
+	ConsensusArgsHex string                    `json:"consensusArgsHex"`
+	ConsensusInfo    *blockchain.ConsensusInfo `json:"consensusInfo"`
+	// 交易依赖关系及并行度指标，旧版本入库的区块为空
+	Dag *blockchain.BlockDag `json:"dag"`
+	// 旧版本入库的区块保存的DAG文本
+	DagText string `json:"dagText"`
+	// 从节点实时查询、尚未入库的区块，库内最大高度以下的区块随入库队列补齐，更高的区块随实时订阅入库
+	Unindexed bool `json:"unindexed"`
 }
 
 func (h *BlockDetailsHandler) Handle(s *server.Server) gin.HandlerFunc {
@@ -154,7 +192,23 @@ func (h *BlockDetailsHandler) Handle(s *server.Server) gin.HandlerFunc {
 		}
 
 		if block == nil {
-			SuccessfulJSONResp(&BlockDetailsResp{}, "", nil)
+			var resp *BlockDetailsResp
+
+			// 按库内ID查询时无法从节点查询
+			if req.Id == 0 {
+				resp, err = blockDetailsFromNode(s, req.GenHash, req.BlockHeight, req.BlockHash)
+				if err != nil {
+					log.Errorf("fail to get block from node, err: [%s], req: [%+v]\n", err.Error(), req)
+					FailedJSONResp(RespMsgServerError, c)
+					return
+				}
+			}
+
+			if resp == nil {
+				resp = &BlockDetailsResp{}
+			}
+
+			SuccessfulJSONResp(resp, "", c)
 			return
 		}
 
@@ -165,31 +219,93 @@ func (h *BlockDetailsHandler) Handle(s *server.Server) gin.HandlerFunc {
 			return
 		}
 
-		var member accesscontrol.Member
-		err = proto.Unmarshal(details.ProposerBytes, &member)
+		resp, err := newBlockDetailsResp(block, details)
 		if err != nil {
-			log.Errorf("fail to unmarshal the proposer, err: [%s], req: [%+v]\n", err.Error(), req)
+			log.Errorf("fail to decode the proposer, err: [%s], req: [%+v]\n", err.Error(), req)
 			FailedJSONResp(RespMsgServerError, c)
 			return
 		}
 
-		resp := &BlockDetailsResp{
-			BlockHeight:    block.BlockHeight,
-			BlockHash:      block.BlockHash,
-			ChainId:        block.ChainId,
-			PreBlockHash:   block.PreBlockHash,
-			BlockType:      block.BlockType,
-			BlockVersion:   block.BlockVersion,
-			PreConfHeight:  block.PreConfHeight,
-			TxCount:        block.TxCount,
-			TxRoot:         block.TxRoot,
-			DagHash:        block.DagHash,
-			RwSetRoot:      block.RwSetRoot,
-			BlockTimestamp: block.BlockTimestamp,
-			ProposerOrgId:  block.ProposerOrgId,
-			Proposer:       string(member.MemberInfo),
+		SuccessfulJSONResp(resp, "", c)
+	}
+}
+
+// newBlockDetailsResp 组装区块详情，解析出块者身份、共识信息和DAG
+func newBlockDetailsResp(block *dbModel.Block, details *dbModel.BlockDetails) (*BlockDetailsResp, error) {
+	if details == nil {
+		details = &dbModel.BlockDetails{}
+	}
+
+	proposer, err := blockchain.DecodeMemberBytes(details.ProposerBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &BlockDetailsResp{
+		BlockHeight:      block.BlockHeight,
+		BlockHash:        block.BlockHash,
+		ChainId:          block.ChainId,
+		PreBlockHash:     block.PreBlockHash,
+		BlockType:        block.BlockType,
+		BlockVersion:     block.BlockVersion,
+		PreConfHeight:    block.PreConfHeight,
+		TxCount:          block.TxCount,
+		TxRoot:           block.TxRoot,
+		DagHash:          block.DagHash,
+		RwSetRoot:        block.RwSetRoot,
+		BlockTimestamp:   block.BlockTimestamp,
+		ProposerOrgId:    block.ProposerOrgId,
+		ProposerIdentity: proposer,
+		ProposerId:       block.ProposerId,
+		ProposerName:     block.ProposerName,
+		ConsensusArgs:    block.ConsensusArgs,
+		ConsensusArgsHex: block.ConsensusArgsHex,
+	}
+
+	// 旧版本入库的区块按原始内容计算
+	if len(resp.ConsensusArgsHex) == 0 {
+		resp.ConsensusArgsHex = hex.EncodeToString([]byte(block.ConsensusArgs))
+	}
+
+	if proposer != nil {
+		resp.Proposer = proposer.MemberInfo()
+	}
+
+	if len(details.ConsensusInfo) != 0 {
+		var consensusInfo blockchain.ConsensusInfo
+		if err := json.Unmarshal([]byte(details.ConsensusInfo), &consensusInfo); err == nil {
+			resp.ConsensusInfo = &consensusInfo
 		}
+	}
 
-		SuccessfulJSONResp(resp, "", c)
+	if len(details.Dag) != 0 {
+		var dag blockchain.BlockDag
+		if err := json.Unmarshal([]byte(details.Dag), &dag); err == nil {
+			dag.ResolveDependencyTxIds()
+			resp.Dag = &dag
+		} else {
+			resp.DagText = details.Dag
+		}
+	}
+
+	return resp, nil
+}
+
+// blockDetailsFromNode 库内没有的区块从节点查询，未开启实时查询或未查到时返回nil
+func blockDetailsFromNode(s *server.Server, genHash string, blockHeight int64,
+	blockHash string) (*BlockDetailsResp, error) {
+
+	blockData, err := s.FetchBlockFromNode(genHash, blockHeight, blockHash)
+	if err != nil || blockData == nil {
+		return nil, err
+	}
+
+	resp, err := newBlockDetailsResp(blockData.Block, blockData.BlockDetails)
+	if err != nil {
+		return nil, err
 	}
+
+	resp.Unindexed = true
+
+	return resp, nil
 }
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"chainmscan/blockchain"
+	"chainmscan/db/dao"
+	"chainmscan/server"
+	"encoding/json"
+
+	dbModel "chainmscan/db/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ChainConfigSource_Index = "index"
+	ChainConfigSource_Node  = "node"
+)
+
+type ChainConfigSummaryResp struct {
+	BlockHeight        uint64 `json:"blockHeight"`
+	TxId               string `json:"txId"`
+	Method             string `json:"method"`
+	Sequence           uint64 `json:"sequence"`
+	Version            string `json:"version"`
+	AuthType           string `json:"authType"`
+	ConsensusType      string `json:"consensusType"`
+	ConsensusNodeCount int    `json:"consensusNodeCount"`
+	TrustRootCount     int    `json:"trustRootCount"`
+	BlockTxCapacity    uint32 `json:"blockTxCapacity"`
+	BlockSize          uint32 `json:"blockSize"`
+	BlockInterval      uint32 `json:"blockInterval"`
+	TxTimeout          uint32 `json:"txTimeout"`
+	Timestamp          int64  `json:"timestamp"`
+	// 配置来源：index为索引的变更记录，node为从节点查询
+	Source string `json:"source"`
+}
+
+func newChainConfigSummaryResp(v *dbModel.ChainConfig, fromNode bool) *ChainConfigSummaryResp {
+	r := &ChainConfigSummaryResp{
+		BlockHeight:        v.BlockHeight,
+		TxId:               v.TxId,
+		Method:             v.Method,
+		Sequence:           v.Sequence,
+		Version:            v.Version,
+		AuthType:           v.AuthType,
+		ConsensusType:      v.ConsensusType,
+		ConsensusNodeCount: v.ConsensusNodeCount,
+		TrustRootCount:     v.TrustRootCount,
+		BlockTxCapacity:    v.BlockTxCapacity,
+		BlockSize:          v.BlockSize,
+		BlockInterval:      v.BlockInterval,
+		TxTimeout:          v.TxTimeout,
+		Timestamp:          v.Timestamp,
+		Source:             ChainConfigSource_Index,
+	}
+
+	if fromNode {
+		r.Source = ChainConfigSource_Node
+	}
+
+	return r
+}
+
+type ChainConfigHandler struct {
+}
+
+type ChainConfigReq struct {
+	GenHash string `json:"genHash"`
+	// 小于0时查询最新配置
+	BlockHeight int64 `json:"blockHeight"`
+}
+
+type ChainConfigResp struct {
+	*ChainConfigSummaryResp
+	Config json.RawMessage `json:"config"`
+}
+
+func (h *ChainConfigHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(ChainConfigReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		log, err := s.GetZapLogger("ChainConfigHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		record, fromNode, err := s.GetChainConfigAtHeight(req.GenHash, req.BlockHeight)
+		if err != nil {
+			log.Errorf("fail to get chain config, err: [%s], genHash: [%s], blockHeight: [%d]\n",
+				err.Error(), req.GenHash, req.BlockHeight)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		if record == nil {
+			SuccessfulJSONResp(nil, "", c)
+			return
+		}
+
+		resp := &ChainConfigResp{
+			ChainConfigSummaryResp: newChainConfigSummaryResp(record, fromNode),
+			Config:                 json.RawMessage(record.ConfigJson),
+		}
+
+		SuccessfulJSONResp(resp, "", c)
+	}
+}
+
+type ChainConfigListHandler struct {
+}
+
+type ChainConfigListReq struct {
+	PageReq
+	GenHash string `json:"genHash"`
+}
+
+func (h *ChainConfigListHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(ChainConfigListReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		checkPageReq(&req.PageReq)
+
+		log, err := s.GetZapLogger("ChainConfigListHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		list, total, err := dao.GetChainConfigList(req.GenHash, req.Page, req.PageSize, s.Db())
+		if err != nil {
+			log.Errorf("fail to get chain config list, err: [%s], genHash: [%s]\n",
+				err.Error(), req.GenHash)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		resp := make([]*ChainConfigSummaryResp, 0)
+
+		for _, v := range list {
+			resp = append(resp, newChainConfigSummaryResp(v, false))
+		}
+
+		SuccessfulJSONRespWithPage(resp, total, c)
+	}
+}
+
+type ChainConfigDiffHandler struct {
+}
+
+type ChainConfigDiffReq struct {
+	GenHash    string `json:"genHash"`
+	FromHeight int64  `json:"fromHeight"`
+	// 小于0时与最新配置比较
+	ToHeight int64 `json:"toHeight"`
+}
+
+type ChainConfigDiffResp struct {
+	From    *ChainConfigSummaryResp    `json:"from"`
+	To      *ChainConfigSummaryResp    `json:"to"`
+	Changes []*blockchain.ConfigChange `json:"changes"`
+}
+
+func (h *ChainConfigDiffHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(ChainConfigDiffReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		log, err := s.GetZapLogger("ChainConfigDiffHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		from, fromNode, err := s.GetChainConfigAtHeight(req.GenHash, req.FromHeight)
+		if err != nil {
+			log.Errorf("fail to get chain config, err: [%s], genHash: [%s], blockHeight: [%d]\n",
+				err.Error(), req.GenHash, req.FromHeight)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		to, toNode, err := s.GetChainConfigAtHeight(req.GenHash, req.ToHeight)
+		if err != nil {
+			log.Errorf("fail to get chain config, err: [%s], genHash: [%s], blockHeight: [%d]\n",
+				err.Error(), req.GenHash, req.ToHeight)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		if from == nil || to == nil {
+			SuccessfulJSONResp(nil, "", c)
+			return
+		}
+
+		changes, err := blockchain.DiffChainConfig(from.ConfigJson, to.ConfigJson)
+		if err != nil {
+			log.Errorf("fail to diff chain config, err: [%s], genHash: [%s]\n",
+				err.Error(), req.GenHash)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		resp := &ChainConfigDiffResp{
+			From:    newChainConfigSummaryResp(from, fromNode),
+			To:      newChainConfigSummaryResp(to, toNode),
+			Changes: changes,
+		}
+
+		SuccessfulJSONResp(resp, "", c)
+	}
+}
@@ -1,12 +1,12 @@
 package handler
 
 import (
+	"chainmscan/blockchain"
 	"chainmscan/db/dao"
+	dbModel "chainmscan/db/model"
 	"chainmscan/server"
 
-	"chainmaker.org/chainmaker/pb-go/v2/accesscontrol"
 	"github.com/gin-gonic/gin"
-	"github.com/gogo/protobuf/proto"
 )
 
 type ContractListHandler struct {
@@ -18,15 +18,17 @@ type ContractListReq struct {
 }
 
 type ContractListResp struct {
-	Id           uint   `json:"id"`
-	Name         string `json:"name"`
-	Version      string `json:"version"`
-	ChainId      string `json:"chainId"`
-	RuntimeType  string `json:"runtimeType"`
-	State        string `json:"state"`
-	CreatorOrgId string `json:"creatorOrgId"`
-	Height       uint64 `json:"height"`
-	TxTimestamp  int64  `json:"txTimestamp"`
+	Id              uint   `json:"id"`
+	Name            string `json:"name"`
+	Version         string `json:"version"`
+	ChainId         string `json:"chainId"`
+	RuntimeType     string `json:"runtimeType"`
+	State           string `json:"state"`
+	CreatorOrgId    string `json:"creatorOrgId"`
+	Height          uint64 `json:"height"`
+	TxTimestamp     int64  `json:"txTimestamp"`
+	UpdateHeight    uint64 `json:"updateHeight"`
+	UpdateTimestamp int64  `json:"updateTimestamp"`
 }
 
 func (h *ContractListHandler) Handle(s *server.Server) gin.HandlerFunc {
@@ -46,43 +48,62 @@ func (h *ContractListHandler) Handle(s *server.Server) gin.HandlerFunc {
 
 		checkPageReq(&req.PageReq)
 
+		cursorPage, err := req.cursorPage()
+		if err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
 		log, err := s.GetZapLogger("ContractListHandler")
 		if err != nil {
 			FailedJSONResp(RespMsgLogServerError, c)
 			return
 		}
 
-		list, total, err := dao.GetContractList(req.GenHash, req.Page, req.PageSize, s.Db())
+		if cursorPage != nil {
+			list, cursor, err := dao.GetContractListByCursor(req.GenHash, cursorPage, s.Db())
+			if err != nil {
+				log.Errorf("fail to get contract list by cursor, err: [%s], genHash: [%s]\n", err.Error(), req.GenHash)
+				FailedJSONResp(RespMsgServerError, c)
+				return
+			}
+
+			SuccessfulJSONRespWithCursor(newContractListResp(list), cursor, c)
+			return
+		}
+
+		list, total, err := dao.GetContractList(req.GenHash, req.Page, req.PageSize, req.isAsc(), s.Db())
 		if err != nil {
 			log.Errorf("fail to get contract list, err: [%s], genHash: [%s]\n", err.Error(), req.GenHash)
 			FailedJSONResp(RespMsgServerError, c)
 			return
 		}
 
-		resp := make([]*ContractListResp, 0)
+		SuccessfulJSONRespWithPage(newContractListResp(list), total, c)
+	}
+}
 
-		if list == nil {
-			SuccessfulJSONRespWithPage(resp, 0, c)
-			return
-		}
+func newContractListResp(list []*dbModel.Contract) []*ContractListResp {
+	resp := make([]*ContractListResp, 0, len(list))
 
-		for _, v := range list {
-			bl := &ContractListResp{
-				Id:           v.ID,
-				Name:         v.Name,
-				Version:      v.Version,
-				ChainId:      v.ChainId,
-				RuntimeType:  v.RuntimeType,
-				State:        v.State,
-				CreatorOrgId: v.CreatorOrgId,
-				Height:       v.Height,
-				TxTimestamp:  v.TxTimestamp,
-			}
-			resp = append(resp, bl)
+	for _, v := range list {
+		bl := &ContractListResp{
+			Id:              v.ID,
+			Name:            v.Name,
+			Version:         v.Version,
+			ChainId:         v.ChainId,
+			RuntimeType:     v.RuntimeType,
+			State:           v.State,
+			CreatorOrgId:    v.CreatorOrgId,
+			Height:          v.Height,
+			TxTimestamp:     v.TxTimestamp,
+			UpdateHeight:    v.UpdateHeight,
+			UpdateTimestamp: v.UpdateTimestamp,
 		}
-
-		SuccessfulJSONRespWithPage(resp, total, c)
+		resp = append(resp, bl)
 	}
+
+	return resp
 }
 
 type ContractDetailsHandler struct {
@@ -95,17 +116,22 @@ type ContractDetailsReq struct {
 }
 
 type ContractDetailsResp struct {
-	Name         string `json:"name"`
-	Version      string `json:"version"`
-	ChainId      string `json:"chainId"`
-	RuntimeType  string `json:"runtimeType"`
-	State        string `json:"state"`
-	CreatorOrgId string `json:"creatorOrgId"`
-	Address      string `json:"address"`
-	TxId         string `json:"txId"`
-	Height       uint64 `json:"height"`
-	TxTimestamp  int64  `json:"txTimestamp"`
-	Creator      string `json:"creator"`
+	Name            string `json:"name"`
+	Version         string `json:"version"`
+	ChainId         string `json:"chainId"`
+	RuntimeType     string `json:"runtimeType"`
+	State           string `json:"state"`
+	CreatorOrgId    string `json:"creatorOrgId"`
+	Address         string `json:"address"`
+	TxId            string `json:"txId"`
+	Height          uint64 `json:"height"`
+	TxTimestamp     int64  `json:"txTimestamp"`
+	Creator         string `json:"creator"`
+	UpdateTxId      string `json:"updateTxId"`
+	UpdateHeight    uint64 `json:"updateHeight"`
+	UpdateTimestamp int64  `json:"updateTimestamp"`
+	// 解析后的合约创建者身份
+	CreatorIdentity *blockchain.Identity `json:"creatorIdentity"`
 }
 
 func (h *ContractDetailsHandler) Handle(s *server.Server) gin.HandlerFunc {
@@ -142,30 +168,111 @@ func (h *ContractDetailsHandler) Handle(s *server.Server) gin.HandlerFunc {
 			return
 		}
 
-		var creator accesscontrol.MemberFull
-
-		err = proto.Unmarshal(contract.CreatorBytes, &creator)
+		creator, err := blockchain.DecodeMemberFullBytes(contract.CreatorBytes)
 		if err != nil {
-			log.Errorf("fail to unmarshal contract creator, err: [%s], contractId: [%d]\n", err.Error(),
+			log.Errorf("fail to decode contract creator, err: [%s], contractId: [%d]\n", err.Error(),
 				req.GenHash, req.ContractId)
 			FailedJSONResp(RespMsgServerError, c)
 			return
 		}
 
 		resp := &ContractDetailsResp{
-			Name:         contract.Name,
-			Version:      contract.Version,
-			ChainId:      contract.ChainId,
-			RuntimeType:  contract.RuntimeType,
-			State:        contract.State,
-			CreatorOrgId: contract.CreatorOrgId,
-			Address:      contract.Address,
-			TxId:         contract.TxId,
-			Height:       contract.Height,
-			TxTimestamp:  contract.TxTimestamp,
-			Creator:      string(creator.MemberInfo),
+			Name:            contract.Name,
+			Version:         contract.Version,
+			ChainId:         contract.ChainId,
+			RuntimeType:     contract.RuntimeType,
+			State:           contract.State,
+			CreatorOrgId:    contract.CreatorOrgId,
+			Address:         contract.Address,
+			TxId:            contract.TxId,
+			Height:          contract.Height,
+			TxTimestamp:     contract.TxTimestamp,
+			UpdateTxId:      contract.UpdateTxId,
+			UpdateHeight:    contract.UpdateHeight,
+			UpdateTimestamp: contract.UpdateTimestamp,
+			CreatorIdentity: creator,
+		}
+
+		if creator != nil {
+			resp.Creator = creator.MemberInfo()
 		}
 
 		SuccessfulJSONResp(resp, "", c)
 	}
 }
+
+type ContractHistoryHandler struct {
+}
+
+type ContractHistoryReq struct {
+	PageReq
+	GenHash      string `json:"genHash"`
+	ContractName string `json:"contractName"`
+}
+
+type ContractHistoryResp struct {
+	Id              uint   `json:"id"`
+	Name            string `json:"name"`
+	Operation       string `json:"operation"`
+	Version         string `json:"version"`
+	RuntimeType     string `json:"runtimeType"`
+	State           string `json:"state"`
+	OperatorOrgId   string `json:"operatorOrgId"`
+	OperatorAddress string `json:"operatorAddress"`
+	TxId            string `json:"txId"`
+	BlockHeight     uint64 `json:"blockHeight"`
+	Timestamp       int64  `json:"timestamp"`
+}
+
+func (h *ContractHistoryHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(ContractHistoryReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash, req.ContractName)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		checkPageReq(&req.PageReq)
+
+		log, err := s.GetZapLogger("ContractHistoryHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		list, total, err := dao.GetContractHistory(req.GenHash, req.ContractName, req.Page, req.PageSize, s.Db())
+		if err != nil {
+			log.Errorf("fail to get contract history, err: [%s], genHash: [%s], contractName: [%s]\n",
+				err.Error(), req.GenHash, req.ContractName)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		resp := make([]*ContractHistoryResp, 0)
+
+		for _, v := range list {
+			resp = append(resp, &ContractHistoryResp{
+				Id:              v.ID,
+				Name:            v.Name,
+				Operation:       v.Operation,
+				Version:         v.Version,
+				RuntimeType:     v.RuntimeType,
+				State:           v.State,
+				OperatorOrgId:   v.OperatorOrgId,
+				OperatorAddress: v.OperatorAddress,
+				TxId:            v.TxId,
+				BlockHeight:     v.BlockHeight,
+				Timestamp:       v.Timestamp,
+			})
+		}
+
+		SuccessfulJSONRespWithPage(resp, total, c)
+	}
+}
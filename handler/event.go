@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"chainmscan/db/dao"
+	dbModel "chainmscan/db/model"
+	"chainmscan/server"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EventListHandler struct {
+}
+
+type EventListReq struct {
+	PageReq
+	GenHash      string `json:"genHash"`
+	ContractName string `json:"contractName"`
+	Topic        string `json:"topic"`
+	StartHeight  uint64 `json:"startHeight"`
+	EndHeight    uint64 `json:"endHeight"`
+	StartTime    int64  `json:"startTime"`
+	EndTime      int64  `json:"endTime"`
+}
+
+type EventListResp struct {
+	Id              uint     `json:"id"`
+	TxId            string   `json:"txId"`
+	BlockHeight     uint64   `json:"blockHeight"`
+	ContractName    string   `json:"contractName"`
+	ContractVersion string   `json:"contractVersion"`
+	Topic           string   `json:"topic"`
+	EventIndex      int      `json:"eventIndex"`
+	EventData       []string `json:"eventData"`
+	Timestamp       int64    `json:"timestamp"`
+}
+
+func (h *EventListHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(EventListReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		checkPageReq(&req.PageReq)
+
+		cursorPage, err := req.cursorPage()
+		if err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		log, err := s.GetZapLogger("EventListHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		filter := &dao.ContractEventFilter{
+			ContractName: req.ContractName,
+			Topic:        req.Topic,
+			StartHeight:  req.StartHeight,
+			EndHeight:    req.EndHeight,
+			StartTime:    req.StartTime,
+			EndTime:      req.EndTime,
+		}
+
+		var (
+			list   []*dbModel.ContractEvent
+			total  int64
+			cursor *dao.CursorResult
+		)
+
+		if cursorPage != nil {
+			list, cursor, err = dao.GetContractEventListByCursor(req.GenHash, filter, cursorPage, s.Db())
+		} else {
+			list, total, err = dao.GetContractEventList(req.GenHash, filter, req.Page, req.PageSize, req.isAsc(), s.Db())
+		}
+		if err != nil {
+			log.Errorf("fail to get event list, err: [%s], genHash: [%s], contractName: [%s], topic: [%s]\n",
+				err.Error(), req.GenHash, req.ContractName, req.Topic)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		resp := make([]*EventListResp, 0)
+
+		for _, v := range list {
+			eventData := make([]string, 0)
+			if len(v.EventData) != 0 {
+				err = json.Unmarshal([]byte(v.EventData), &eventData)
+				if err != nil {
+					log.Errorf("fail to unmarshal the event data, err: [%s], genHash: [%s], txId: [%s]\n",
+						err.Error(), req.GenHash, v.TxId)
+					FailedJSONResp(RespMsgServerError, c)
+					return
+				}
+			}
+
+			resp = append(resp, &EventListResp{
+				Id:              v.ID,
+				TxId:            v.TxId,
+				BlockHeight:     v.BlockHeight,
+				ContractName:    v.ContractName,
+				ContractVersion: v.ContractVersion,
+				Topic:           v.Topic,
+				EventIndex:      v.EventIndex,
+				EventData:       eventData,
+				Timestamp:       v.Timestamp,
+			})
+		}
+
+		if cursorPage != nil {
+			SuccessfulJSONRespWithCursor(resp, cursor, c)
+			return
+		}
+
+		SuccessfulJSONRespWithPage(resp, total, c)
+	}
+}
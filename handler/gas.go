@@ -0,0 +1,292 @@
+package handler
+
+import (
+	"chainmscan/db/dao"
+	"chainmscan/server"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 燃料消耗排行默认和最大返回条数
+	GasTopDefaultLimit = 10
+	GasTopMaxLimit     = 100
+	// 燃料时间序列最多返回的时间段数
+	GasTimeSeriesMaxPoints = 1000
+)
+
+// 燃料时间序列的时间粒度
+// gasGranularities 燃料时间序列支持的时间粒度及其秒数
+var gasGranularities = map[string]int64{
+	dao.StatGranularity_Hour: 3600,
+	dao.StatGranularity_Day:  86400,
+}
+
+type GasTopConsumersHandler struct {
+}
+
+type GasTopConsumersReq struct {
+	GenHash string `json:"genHash"`
+	// 统计维度：contract、method、senderOrg
+	Dimension string `json:"dimension"`
+	StartTime int64  `json:"startTime"`
+	EndTime   int64  `json:"endTime"`
+	Limit     int    `json:"limit"`
+}
+
+type GasTopConsumersResp struct {
+	ContractName string `json:"contractName"`
+	Method       string `json:"method"`
+	SenderOrgId  string `json:"senderOrgId"`
+	TxCount      int64  `json:"txCount"`
+	GasUsed      uint64 `json:"gasUsed"`
+	GasLimit     uint64 `json:"gasLimit"`
+	AvgGasUsed   uint64 `json:"avgGasUsed"`
+}
+
+func (h *GasTopConsumersHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(GasTopConsumersReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		if len(req.Dimension) == 0 {
+			req.Dimension = dao.GasDimension_Contract
+		}
+
+		switch req.Dimension {
+		case dao.GasDimension_Contract, dao.GasDimension_Method, dao.GasDimension_SenderOrg:
+		default:
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		if req.Limit <= 0 {
+			req.Limit = GasTopDefaultLimit
+		}
+
+		if req.Limit > GasTopMaxLimit {
+			req.Limit = GasTopMaxLimit
+		}
+
+		log, err := s.GetZapLogger("GasTopConsumersHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		list, err := dao.GetTopGasConsumers(req.GenHash, req.Dimension, req.StartTime, req.EndTime,
+			req.Limit, s.Db())
+		if err != nil {
+			log.Errorf("fail to get top gas consumers, err: [%s], genHash: [%s], dimension: [%s]\n",
+				err.Error(), req.GenHash, req.Dimension)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		resp := make([]*GasTopConsumersResp, 0)
+
+		for _, v := range list {
+			r := &GasTopConsumersResp{
+				ContractName: v.ContractName,
+				Method:       v.Method,
+				SenderOrgId:  v.SenderOrgId,
+				TxCount:      v.TxCount,
+				GasUsed:      v.GasUsed,
+				GasLimit:     v.GasLimit,
+			}
+
+			if v.TxCount > 0 {
+				r.AvgGasUsed = v.GasUsed / uint64(v.TxCount)
+			}
+
+			resp = append(resp, r)
+		}
+
+		SuccessfulJSONResp(resp, "", c)
+	}
+}
+
+type GasTimeSeriesHandler struct {
+}
+
+type GasTimeSeriesReq struct {
+	GenHash      string `json:"genHash"`
+	ContractName string `json:"contractName"`
+	// 时间粒度：hour、day，默认hour
+	Granularity string `json:"granularity"`
+	StartTime   int64  `json:"startTime"`
+	EndTime     int64  `json:"endTime"`
+}
+
+type GasTimeSeriesResp struct {
+	Timestamp int64  `json:"timestamp"`
+	TxCount   int64  `json:"txCount"`
+	GasUsed   uint64 `json:"gasUsed"`
+	GasLimit  uint64 `json:"gasLimit"`
+}
+
+func (h *GasTimeSeriesHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(GasTimeSeriesReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		if len(req.Granularity) == 0 {
+			req.Granularity = dao.StatGranularity_Hour
+		}
+
+		interval, ok := gasGranularities[req.Granularity]
+		if !ok {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		// 默认查询最近24个时间段
+		if req.EndTime <= 0 {
+			req.EndTime = time.Now().Unix()
+		}
+
+		if req.StartTime <= 0 {
+			req.StartTime = req.EndTime - 23*interval
+		}
+
+		// 按天统计时与每日统计相同，按统计时区对齐
+		buckets, err := dao.StatBuckets(req.Granularity, req.StartTime, req.EndTime, GasTimeSeriesMaxPoints)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		log, err := s.GetZapLogger("GasTimeSeriesHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		list, err := dao.GetGasTimeSeries(req.GenHash, req.ContractName, req.Granularity, buckets,
+			req.EndTime, s.Db())
+		if err != nil {
+			log.Errorf("fail to get gas time series, err: [%s], genHash: [%s], contractName: [%s]\n",
+				err.Error(), req.GenHash, req.ContractName)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		points := make(map[int64]*dao.GasTimePoint, len(list))
+		for _, v := range list {
+			points[v.Bucket] = v
+		}
+
+		// 没有交易的时间段补零
+		resp := make([]*GasTimeSeriesResp, 0)
+
+		for _, t := range buckets {
+			r := &GasTimeSeriesResp{Timestamp: t}
+
+			if p, ok := points[t]; ok {
+				r.TxCount = p.TxCount
+				r.GasUsed = p.GasUsed
+				r.GasLimit = p.GasLimit
+			}
+
+			resp = append(resp, r)
+		}
+
+		SuccessfulJSONResp(resp, "", c)
+	}
+}
+
+type TxGasEfficiencyHandler struct {
+}
+
+type TxGasEfficiencyReq struct {
+	PageReq
+	GenHash      string `json:"genHash"`
+	ContractName string `json:"contractName"`
+	Method       string `json:"method"`
+}
+
+type TxGasEfficiencyResp struct {
+	Id           uint   `json:"id"`
+	TxId         string `json:"txId"`
+	BlockHeight  uint64 `json:"blockHeight"`
+	ContractName string `json:"contractName"`
+	Method       string `json:"method"`
+	Timestamp    int64  `json:"timestamp"`
+	GasUsed      uint64 `json:"gasUsed"`
+	GasLimit     uint64 `json:"gasLimit"`
+	// 燃料使用率，gasUsed/gasLimit
+	Efficiency float64 `json:"efficiency"`
+}
+
+func (h *TxGasEfficiencyHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(TxGasEfficiencyReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		checkPageReq(&req.PageReq)
+
+		log, err := s.GetZapLogger("TxGasEfficiencyHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		list, total, err := dao.GetTxGasList(req.GenHash, req.ContractName, req.Method,
+			req.Page, req.PageSize, s.Db())
+		if err != nil {
+			log.Errorf("fail to get tx gas list, err: [%s], genHash: [%s], contractName: [%s]\n",
+				err.Error(), req.GenHash, req.ContractName)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		resp := make([]*TxGasEfficiencyResp, 0)
+
+		for _, v := range list {
+			resp = append(resp, &TxGasEfficiencyResp{
+				Id:           v.ID,
+				TxId:         v.TxId,
+				BlockHeight:  v.BlockHeight,
+				ContractName: v.ContractName,
+				Method:       v.Method,
+				Timestamp:    v.Timestamp,
+				GasUsed:      v.GasUsed,
+				GasLimit:     v.GasLimit,
+				Efficiency:   float64(v.GasUsed) / float64(v.GasLimit),
+			})
+		}
+
+		SuccessfulJSONRespWithPage(resp, total, c)
+	}
+}
@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"chainmscan/db/dao"
 	"chainmscan/server"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,8 +35,17 @@ type PageReq struct {
 	PageSize int32  `json:"pageSize"`
 	Page     int32  `json:"page"`
 	SortType string `json:"sortType"`
+	// 游标分页：useCursor为true或cursor不为空时使用，cursor为上次返回的nextCursor或prevCursor
+	UseCursor bool   `json:"useCursor"`
+	Cursor    string `json:"cursor"`
 }
 
+// 列表排序方向，默认倒序
+const (
+	SortType_Asc  = "asc"
+	SortType_Desc = "desc"
+)
+
 type StandardResp struct {
 	Code int32       `json:"code"`
 	Msg  string      `json:"msg"`
@@ -71,6 +84,29 @@ func SuccessfulJSONRespWithPage(data interface{}, total int64, c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+type StandardRespWithCursor struct {
+	StandardResp
+	NextCursor string `json:"nextCursor"`
+	PrevCursor string `json:"prevCursor"`
+}
+
+// SuccessfulJSONRespWithCursor gin成功返回数据包装（游标分页）
+func SuccessfulJSONRespWithCursor(data interface{}, cursor *dao.CursorResult, c *gin.Context) {
+	resp := StandardRespWithCursor{
+		StandardResp: StandardResp{
+			Code: RespCodeSuccess,
+			Data: data,
+		},
+	}
+
+	if cursor != nil {
+		resp.NextCursor = encodeCursor(cursor.Next)
+		resp.PrevCursor = encodeCursor(cursor.Prev)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
 // FailedJSONResp gin失败返回数据包装
 func FailedJSONResp(msg string, c *gin.Context) {
 	resp := StandardResp{
@@ -124,3 +160,60 @@ func checkPageReq(p *PageReq) {
 		p.PageSize = 10
 	}
 }
+
+// isAsc 是否按正序排列
+func (p *PageReq) isAsc() bool {
+	return strings.EqualFold(p.SortType, SortType_Asc)
+}
+
+// cursorPage 游标分页参数，未使用游标分页时返回nil，需在checkPageReq之后调用
+func (p *PageReq) cursorPage() (*dao.CursorPage, error) {
+	if !p.UseCursor && len(p.Cursor) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := decodeCursor(p.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dao.CursorPage{
+		Cursor: cursor,
+		Asc:    p.isAsc(),
+		Limit:  int(p.PageSize),
+	}, nil
+}
+
+// encodeCursor 游标编码为不透明的字符串，游标为空时返回空字符串
+func encodeCursor(cursor *dao.Cursor) string {
+	if cursor == nil {
+		return ""
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*dao.Cursor, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := new(dao.Cursor)
+
+	err = json.Unmarshal(data, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"chainmscan/server"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChainIntegrityHandler struct {
+}
+
+type ChainIntegrityReq struct {
+	GenHash string `json:"genHash"`
+	Repair  bool   `json:"repair"`
+}
+
+func (h *ChainIntegrityHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(ChainIntegrityReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		log, err := s.GetZapLogger("ChainIntegrityHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		report, err := s.CheckChainIntegrity(req.GenHash, req.Repair)
+		if err != nil {
+			log.Errorf("fail to check the chain integrity, err: [%s], genHash: [%s]\n",
+				err.Error(), req.GenHash)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		SuccessfulJSONResp(report, "", c)
+	}
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"chainmscan/server"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PauseSubscriptionHandler struct {
+}
+
+type PauseSubscriptionReq struct {
+	GenHash string `json:"genHash"`
+}
+
+func (h *PauseSubscriptionHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := new(PauseSubscriptionReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		log, err := s.GetZapLogger("PauseSubscriptionHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		err = s.PauseSubscription(req.GenHash)
+		if err != nil {
+			log.Errorf("fail to pause the subscription, err: [%s], genHash: [%s]\n",
+				err.Error(), req.GenHash)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		SuccessfulJSONResp(req.GenHash, "", c)
+	}
+}
+
+type ResumeSubscriptionHandler struct {
+}
+
+type ResumeSubscriptionReq struct {
+	GenHash string `json:"genHash"`
+}
+
+func (h *ResumeSubscriptionHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := new(ResumeSubscriptionReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		log, err := s.GetZapLogger("ResumeSubscriptionHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		err = s.ResumeSubscription(req.GenHash)
+		if err != nil {
+			log.Errorf("fail to resume the subscription, err: [%s], genHash: [%s]\n",
+				err.Error(), req.GenHash)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		SuccessfulJSONResp(req.GenHash, "", c)
+	}
+}
+
+type ReindexChainHandler struct {
+}
+
+type ReindexChainReq struct {
+	GenHash string `json:"genHash"`
+	// 从该高度开始重建索引，为0时清空全部数据从创世区块开始
+	FromHeight uint64 `json:"fromHeight"`
+}
+
+func (h *ReindexChainHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := new(ReindexChainReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		log, err := s.GetZapLogger("ReindexChainHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		err = s.ReindexChain(req.GenHash, req.FromHeight)
+		if err != nil {
+			log.Errorf("fail to reindex the chain, err: [%s], genHash: [%s], fromHeight: [%d]\n",
+				err.Error(), req.GenHash, req.FromHeight)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		SuccessfulJSONResp(req.GenHash, "", c)
+	}
+}
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"chainmscan/blockchain"
+	"chainmscan/db/dao"
+	"chainmscan/server"
+	"encoding/json"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 出块统计默认和最大的区块窗口
+	ProposerStatsDefaultBlockCount = 1000
+	ProposerStatsMaxBlockCount     = 10000
+)
+
+type ProposerStatsHandler struct {
+}
+
+type ProposerStatsReq struct {
+	GenHash string `json:"genHash"`
+	// 统计维度：node、org，默认node
+	GroupBy string `json:"groupBy"`
+	// 统计截止高度之前的blockCount个区块，endHeight不大于0时为最新高度
+	BlockCount int64 `json:"blockCount"`
+	EndHeight  int64 `json:"endHeight"`
+}
+
+type ProposerStatsResp struct {
+	StartHeight uint64              `json:"startHeight"`
+	EndHeight   uint64              `json:"endHeight"`
+	BlockCount  int64               `json:"blockCount"`
+	Proposers   []*ProposerStatResp `json:"proposers"`
+	// 窗口内携带共识投票的区块数及各投票节点的参与情况
+	VotedBlockCount int64            `json:"votedBlockCount"`
+	Voters          []*VoterStatResp `json:"voters"`
+}
+
+type ProposerStatResp struct {
+	ProposerId    string  `json:"proposerId"`
+	ProposerName  string  `json:"proposerName"`
+	ProposerOrgId string  `json:"proposerOrgId"`
+	BlockCount    int64   `json:"blockCount"`
+	Share         float64 `json:"share"`
+	TxCount       int64   `json:"txCount"`
+	AvgTxCount    float64 `json:"avgTxCount"`
+	AvgInterval   float64 `json:"avgInterval"`
+	AvgRound      float64 `json:"avgRound"`
+	LastHeight    uint64  `json:"lastHeight"`
+	LastTimestamp int64   `json:"lastTimestamp"`
+	// 最近一次出块之后窗口内的区块数，用于发现停止出块的节点
+	BlocksSinceLast uint64 `json:"blocksSinceLast"`
+}
+
+type VoterStatResp struct {
+	Voter         string  `json:"voter"`
+	VoteCount     int64   `json:"voteCount"`
+	Participation float64 `json:"participation"`
+}
+
+func (h *ProposerStatsHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(ProposerStatsReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		if len(req.GroupBy) == 0 {
+			req.GroupBy = dao.ProposerGroup_Node
+		}
+
+		if req.GroupBy != dao.ProposerGroup_Node && req.GroupBy != dao.ProposerGroup_Org {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		if req.BlockCount <= 0 {
+			req.BlockCount = ProposerStatsDefaultBlockCount
+		}
+
+		if req.BlockCount > ProposerStatsMaxBlockCount {
+			req.BlockCount = ProposerStatsMaxBlockCount
+		}
+
+		log, err := s.GetZapLogger("ProposerStatsHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		maxHeight, err := dao.MaxBlockHeightInDb(req.GenHash, s.Db())
+		if err != nil {
+			log.Errorf("fail to get max block height, err: [%s], genHash: [%s]\n", err.Error(), req.GenHash)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		resp := &ProposerStatsResp{
+			Proposers: make([]*ProposerStatResp, 0),
+			Voters:    make([]*VoterStatResp, 0),
+		}
+
+		if maxHeight < 0 {
+			SuccessfulJSONResp(resp, "", c)
+			return
+		}
+
+		if req.EndHeight <= 0 || req.EndHeight > maxHeight {
+			req.EndHeight = maxHeight
+		}
+
+		startHeight := req.EndHeight - req.BlockCount + 1
+		if startHeight < 0 {
+			startHeight = 0
+		}
+
+		resp.StartHeight = uint64(startHeight)
+		resp.EndHeight = uint64(req.EndHeight)
+
+		list, err := dao.GetProposerStats(req.GenHash, req.GroupBy, resp.StartHeight, resp.EndHeight, s.Db())
+		if err != nil {
+			log.Errorf("fail to get proposer stats, err: [%s], genHash: [%s]\n", err.Error(), req.GenHash)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		for _, v := range list {
+			resp.BlockCount += v.BlockCount
+		}
+
+		for _, v := range list {
+			r := &ProposerStatResp{
+				ProposerId:      v.ProposerId,
+				ProposerName:    v.ProposerName,
+				ProposerOrgId:   v.ProposerOrgId,
+				BlockCount:      v.BlockCount,
+				TxCount:         v.TxCount,
+				AvgInterval:     v.AvgInterval,
+				AvgRound:        v.AvgRound,
+				LastHeight:      v.LastHeight,
+				LastTimestamp:   v.LastTimestamp,
+				BlocksSinceLast: resp.EndHeight - v.LastHeight,
+			}
+
+			if v.BlockCount > 0 {
+				r.AvgTxCount = float64(v.TxCount) / float64(v.BlockCount)
+				r.Share = float64(v.BlockCount) / float64(resp.BlockCount)
+			}
+
+			resp.Proposers = append(resp.Proposers, r)
+		}
+
+		infos, err := dao.GetBlockConsensusInfos(req.GenHash, resp.StartHeight, resp.EndHeight, s.Db())
+		if err != nil {
+			log.Errorf("fail to get block consensus infos, err: [%s], genHash: [%s]\n", err.Error(), req.GenHash)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		voteCounts := make(map[string]int64)
+
+		for _, v := range infos {
+			var info blockchain.ConsensusInfo
+			if err := json.Unmarshal([]byte(v), &info); err != nil || len(info.Votes) == 0 {
+				continue
+			}
+
+			resp.VotedBlockCount++
+
+			// 同一节点对一个区块只计一次
+			voted := make(map[string]bool, len(info.Votes))
+			for _, vote := range info.Votes {
+				if !voted[vote.Voter] {
+					voted[vote.Voter] = true
+					voteCounts[vote.Voter]++
+				}
+			}
+		}
+
+		for voter, count := range voteCounts {
+			resp.Voters = append(resp.Voters, &VoterStatResp{
+				Voter:         voter,
+				VoteCount:     count,
+				Participation: float64(count) / float64(resp.VotedBlockCount),
+			})
+		}
+
+		sort.Slice(resp.Voters, func(i, j int) bool {
+			if resp.Voters[i].VoteCount != resp.Voters[j].VoteCount {
+				return resp.Voters[i].VoteCount > resp.Voters[j].VoteCount
+			}
+			return resp.Voters[i].Voter < resp.Voters[j].Voter
+		})
+
+		SuccessfulJSONResp(resp, "", c)
+	}
+}
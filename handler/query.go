@@ -2,8 +2,12 @@ package handler
 
 import (
 	"chainmscan/db/dao"
+	dbModel "chainmscan/db/model"
 	"chainmscan/server"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,6 +30,9 @@ const (
 type SearchResp struct {
 	Type uint32 `json:"type"`
 	Id   uint   `json:"id"`
+	// 从节点实时查询、尚未入库的交易或区块没有ID，按区块高度或交易ID查询详情
+	Unindexed   bool   `json:"unindexed"`
+	BlockHeight uint64 `json:"blockHeight"`
 }
 
 func (h *SearchHandler) Handle(s *server.Server) gin.HandlerFunc {
@@ -85,12 +92,179 @@ func (h *SearchHandler) Handle(s *server.Server) gin.HandlerFunc {
 			}
 		}
 
+		resp := searchFromNode(s, req.GenHash, req.Keyword)
+		if resp != nil {
+			SuccessfulJSONResp(resp, "", c)
+			return
+		}
+
 		SuccessfulJSONResp(&SearchResp{
 			Type: SearchType_Unknown,
 		}, "", c)
 	}
 }
 
+// searchFromNode 库内未搜索到时从节点查询交易或区块，查询失败按未找到处理
+func searchFromNode(s *server.Server, genHash, keyword string) *SearchResp {
+	if len(keyword) != 64 {
+		blockHeight, err := strconv.ParseInt(keyword, 10, 64)
+		if err != nil || blockHeight < 0 {
+			return nil
+		}
+
+		blockData, _ := s.FetchBlockFromNode(genHash, blockHeight, "")
+		if blockData == nil {
+			return nil
+		}
+
+		return &SearchResp{
+			Type:        SearchType_Block,
+			Unindexed:   true,
+			BlockHeight: blockData.Block.BlockHeight,
+		}
+	}
+
+	blockData, _ := s.FetchTxFromNode(genHash, keyword)
+	if blockData != nil {
+		return &SearchResp{
+			Type:        SearchType_Tx,
+			Unindexed:   true,
+			BlockHeight: blockData.Block.BlockHeight,
+		}
+	}
+
+	blockData, _ = s.FetchBlockFromNode(genHash, -1, keyword)
+	if blockData != nil {
+		return &SearchResp{
+			Type:        SearchType_Block,
+			Unindexed:   true,
+			BlockHeight: blockData.Block.BlockHeight,
+		}
+	}
+
+	return nil
+}
+
+type GlobalSearchHandler struct {
+}
+
+type GlobalSearchReq struct {
+	Keyword string `json:"keyword"`
+}
+
+type GlobalSearchResp struct {
+	// 结果类型：tx、block、contract、address、org
+	Type        string `json:"type"`
+	GenHash     string `json:"genHash"`
+	ChainId     string `json:"chainId"`
+	Id          uint   `json:"id"`
+	Value       string `json:"value"`
+	BlockHeight uint64 `json:"blockHeight"`
+}
+
+func (h *GlobalSearchHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(GlobalSearchReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		req.Keyword = strings.TrimSpace(req.Keyword)
+
+		err := checkStringParamsEmpty(req.Keyword)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		log, err := s.GetZapLogger("GlobalSearchHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		type chainHits struct {
+			chain *dbModel.ChainInfo
+			hits  []*dao.SearchHit
+		}
+
+		chainList := s.GetChainList()
+		results := make([]*chainHits, len(chainList))
+
+		var wg sync.WaitGroup
+
+		// 各链并行搜索，单条链查询失败不影响其他链的结果
+		for i, genHash := range chainList {
+			wg.Add(1)
+			go func(i int, genHash string) {
+				defer wg.Done()
+
+				chain, err := dao.GetChainInfo(genHash, s.Db())
+				if err != nil || chain == nil {
+					if err != nil {
+						log.Errorf("fail to get chain info, err: [%s], genHash: [%s]\n", err.Error(), genHash)
+					}
+					return
+				}
+
+				hits, err := dao.SearchChain(chain.TableNum, req.Keyword, s.Db())
+				if err != nil {
+					log.Errorf("fail to search the chain, err: [%s], genHash: [%s], keyword: [%s]\n",
+						err.Error(), genHash, req.Keyword)
+					return
+				}
+
+				results[i] = &chainHits{chain: chain, hits: hits}
+			}(i, genHash)
+		}
+
+		wg.Wait()
+
+		type rankedResp struct {
+			*GlobalSearchResp
+			rank int
+		}
+
+		ranked := make([]*rankedResp, 0)
+
+		for _, r := range results {
+			if r == nil {
+				continue
+			}
+
+			for _, hit := range r.hits {
+				ranked = append(ranked, &rankedResp{
+					GlobalSearchResp: &GlobalSearchResp{
+						Type:        hit.Type,
+						GenHash:     r.chain.GenHash,
+						ChainId:     r.chain.ChainId,
+						Id:          hit.Id,
+						Value:       hit.Value,
+						BlockHeight: hit.BlockHeight,
+					},
+					rank: hit.Rank,
+				})
+			}
+		}
+
+		sort.SliceStable(ranked, func(i, j int) bool {
+			if ranked[i].rank != ranked[j].rank {
+				return ranked[i].rank < ranked[j].rank
+			}
+			return ranked[i].ChainId < ranked[j].ChainId
+		})
+
+		resp := make([]*GlobalSearchResp, 0, len(ranked))
+		for _, r := range ranked {
+			resp = append(resp, r.GlobalSearchResp)
+		}
+
+		SuccessfulJSONResp(resp, "", c)
+	}
+}
+
 type OverviewHandler struct {
 }
 
@@ -137,7 +311,7 @@ func (h *OverviewHandler) Handle(s *server.Server) gin.HandlerFunc {
 			return
 		}
 
-		_, contractAmount, err := dao.GetContractList(req.GenHash, 1, 10, s.Db())
+		_, contractAmount, err := dao.GetContractList(req.GenHash, 1, 10, false, s.Db())
 		if err != nil {
 			log.Errorf("fail to get contract list, err: [%s], genHash: [%s]\n", err.Error(), req.GenHash)
 			FailedJSONResp(RespMsgServerError, c)
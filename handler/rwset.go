@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"chainmscan/db/dao"
+	"chainmscan/server"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+	"github.com/gin-gonic/gin"
+)
+
+type TxRwSetHandler struct {
+}
+
+type TxRwSetReq struct {
+	GenHash string `json:"genHash"`
+	TxId    string `json:"txId"`
+}
+
+// TxReadResp 读集，键和值可能是二进制数据，以hex编码返回
+type TxReadResp struct {
+	ContractName string `json:"contractName"`
+	Key          string `json:"key"`
+	Value        string `json:"value"`
+}
+
+// TxWriteResp 写集，键和值以hex编码返回
+type TxWriteResp struct {
+	ContractName string `json:"contractName"`
+	Key          string `json:"key"`
+	Value        string `json:"value"`
+}
+
+type TxRwSetResp struct {
+	TxId   string         `json:"txId"`
+	Reads  []*TxReadResp  `json:"reads"`
+	Writes []*TxWriteResp `json:"writes"`
+}
+
+func (h *TxRwSetHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(TxRwSetReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash, req.TxId)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		log, err := s.GetZapLogger("TxRwSetHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		resp := &TxRwSetResp{
+			TxId:   req.TxId,
+			Reads:  make([]*TxReadResp, 0),
+			Writes: make([]*TxWriteResp, 0),
+		}
+
+		txInfo, tableNum, err := dao.GetTxInfo(req.GenHash, req.TxId, 0, s.Db())
+		if err != nil {
+			log.Errorf("fail to get tx info, err: [%s], genHash: [%s], txId: [%s]\n",
+				err.Error(), req.GenHash, req.TxId)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		if txInfo == nil {
+			SuccessfulJSONResp(resp, "", c)
+			return
+		}
+
+		txDetails, err := dao.GetTxDetails(txInfo.TxId, tableNum, s.Db())
+		if err != nil {
+			log.Errorf("fail to get tx details, err: [%s], genHash: [%s], txId: [%s]\n",
+				err.Error(), req.GenHash, req.TxId)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		if txDetails == nil {
+			SuccessfulJSONResp(resp, "", c)
+			return
+		}
+
+		if len(txDetails.TxReadsBytes) != 0 {
+			var reads []*common.TxRead
+			err = json.Unmarshal(txDetails.TxReadsBytes, &reads)
+			if err != nil {
+				log.Errorf("fail to unmarshal the tx reads, err: [%s], genHash: [%s], txId: [%s]\n",
+					err.Error(), req.GenHash, req.TxId)
+				FailedJSONResp(RespMsgServerError, c)
+				return
+			}
+
+			for _, r := range reads {
+				resp.Reads = append(resp.Reads, &TxReadResp{
+					ContractName: r.ContractName,
+					Key:          hex.EncodeToString(r.Key),
+					Value:        hex.EncodeToString(r.Value),
+				})
+			}
+		}
+
+		if len(txDetails.TxWritesBytes) != 0 {
+			var writes []*common.TxWrite
+			err = json.Unmarshal(txDetails.TxWritesBytes, &writes)
+			if err != nil {
+				log.Errorf("fail to unmarshal the tx writes, err: [%s], genHash: [%s], txId: [%s]\n",
+					err.Error(), req.GenHash, req.TxId)
+				FailedJSONResp(RespMsgServerError, c)
+				return
+			}
+
+			for _, w := range writes {
+				resp.Writes = append(resp.Writes, &TxWriteResp{
+					ContractName: w.ContractName,
+					Key:          hex.EncodeToString(w.Key),
+					Value:        hex.EncodeToString(w.Value),
+				})
+			}
+		}
+
+		SuccessfulJSONResp(resp, "", c)
+	}
+}
+
+type KeyHistoryHandler struct {
+}
+
+type KeyHistoryReq struct {
+	PageReq
+	GenHash      string `json:"genHash"`
+	ContractName string `json:"contractName"`
+	// hex编码的状态键，可带0x前缀
+	Key string `json:"key"`
+}
+
+// KeyHistoryResp 键的写入记录，值以hex编码返回
+type KeyHistoryResp struct {
+	TxId        string `json:"txId"`
+	BlockHeight uint64 `json:"blockHeight"`
+	Timestamp   int64  `json:"timestamp"`
+	Value       string `json:"value"`
+}
+
+func (h *KeyHistoryHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(KeyHistoryReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash, req.ContractName, req.Key)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		stateKey, err := hex.DecodeString(strings.TrimPrefix(req.Key, "0x"))
+		if err != nil || len(stateKey) == 0 {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		checkPageReq(&req.PageReq)
+
+		log, err := s.GetZapLogger("KeyHistoryHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		list, total, err := dao.GetKeyWriteHistory(req.GenHash, req.ContractName, stateKey,
+			req.Page, req.PageSize, s.Db())
+		if err != nil {
+			log.Errorf("fail to get key history, err: [%s], genHash: [%s], contractName: [%s], key: [%s]\n",
+				err.Error(), req.GenHash, req.ContractName, req.Key)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		resp := make([]*KeyHistoryResp, 0)
+
+		for _, v := range list {
+			resp = append(resp, &KeyHistoryResp{
+				TxId:        v.TxId,
+				BlockHeight: v.BlockHeight,
+				Timestamp:   v.Timestamp,
+				Value:       hex.EncodeToString(v.StateValue),
+			})
+		}
+
+		SuccessfulJSONRespWithPage(resp, total, c)
+	}
+}
@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"chainmscan/db/dao"
+	"chainmscan/server"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimeSeriesStatsMaxPoints 时间序列统计最多返回的时间段数
+const TimeSeriesStatsMaxPoints = 1000
+
+// 时间序列统计支持的指标
+var statMetrics = map[string]bool{
+	dao.StatMetric_TxCount:       true,
+	dao.StatMetric_FailedTxCount: true,
+	dao.StatMetric_BlockCount:    true,
+	dao.StatMetric_GasUsed:       true,
+	dao.StatMetric_ActiveSenders: true,
+}
+
+// TimeSeriesStatsHandler 时间序列统计。按小时统计交易数和燃料消耗时查询燃料汇总表，天及以上粒度查询每日统计表，
+// 旧版本入库的链需开启enable_auto_migrate启动一次补齐汇总数据，或调用rebuildDailyStats重建每日统计。
+// getTxAmountByTime仍直接查询交易表，返回结果与旧版本一致
+type TimeSeriesStatsHandler struct {
+}
+
+type TimeSeriesStatsReq struct {
+	GenHash string `json:"genHash"`
+	// 指标：txCount、failedTxCount、blockCount、gasUsed、activeSenders，默认txCount
+	Metric string `json:"metric"`
+	// 时间粒度：minute、hour、day、week、month，默认hour
+	Granularity string `json:"granularity"`
+	StartTime   int64  `json:"startTime"`
+	EndTime     int64  `json:"endTime"`
+}
+
+type TimeSeriesStatsResp struct {
+	Timestamp int64 `json:"timestamp"`
+	Value     int64 `json:"value"`
+}
+
+func (h *TimeSeriesStatsHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(TimeSeriesStatsReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		if len(req.Metric) == 0 {
+			req.Metric = dao.StatMetric_TxCount
+		}
+
+		if len(req.Granularity) == 0 {
+			req.Granularity = dao.StatGranularity_Hour
+		}
+
+		if !statMetrics[req.Metric] {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		// 默认查询最近24个时间段
+		if req.EndTime <= 0 {
+			req.EndTime = time.Now().Unix()
+		}
+
+		if req.StartTime <= 0 {
+			req.StartTime = defaultStatStartTime(req.Granularity, req.EndTime)
+		}
+
+		buckets, err := dao.StatBuckets(req.Granularity, req.StartTime, req.EndTime, TimeSeriesStatsMaxPoints)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		log, err := s.GetZapLogger("TimeSeriesStatsHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		points, err := dao.GetTimeSeriesStats(req.GenHash, req.Metric, req.Granularity, buckets,
+			req.EndTime, s.Db())
+		if err != nil {
+			log.Errorf("fail to get time series stats, err: [%s], genHash: [%s], metric: [%s]\n",
+				err.Error(), req.GenHash, req.Metric)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		resp := make([]*TimeSeriesStatsResp, 0, len(points))
+		for _, p := range points {
+			resp = append(resp, &TimeSeriesStatsResp{
+				Timestamp: p.Timestamp,
+				Value:     p.Value,
+			})
+		}
+
+		SuccessfulJSONResp(resp, "", c)
+	}
+}
+
+// defaultStatStartTime 默认起始时间为endTime所在时间段往前第23个时间段内
+func defaultStatStartTime(granularity string, endTime int64) int64 {
+	t := time.Unix(endTime, 0)
+
+	switch granularity {
+	case dao.StatGranularity_Minute:
+		return t.Add(-23 * time.Minute).Unix()
+	case dao.StatGranularity_Day:
+		return t.AddDate(0, 0, -23).Unix()
+	case dao.StatGranularity_Week:
+		return t.AddDate(0, 0, -23*7).Unix()
+	case dao.StatGranularity_Month:
+		return t.AddDate(0, -23, 0).Unix()
+	default:
+		return t.Add(-23 * time.Hour).Unix()
+	}
+}
+
+// DailyStatsMaxDays 每日统计最多返回的天数
+const DailyStatsMaxDays = 366
+
+type DailyStatsHandler struct {
+}
+
+type DailyStatsReq struct {
+	GenHash   string `json:"genHash"`
+	StartTime int64  `json:"startTime"`
+	EndTime   int64  `json:"endTime"`
+}
+
+type DailyStatsResp struct {
+	Timestamp        int64  `json:"timestamp"`
+	BlockCount       int64  `json:"blockCount"`
+	TxCount          int64  `json:"txCount"`
+	FailedTxCount    int64  `json:"failedTxCount"`
+	GasUsed          uint64 `json:"gasUsed"`
+	SenderCount      int64  `json:"senderCount"`
+	NewContractCount int64  `json:"newContractCount"`
+	// 平均出块间隔，单位秒
+	AvgBlockInterval float64 `json:"avgBlockInterval"`
+}
+
+func (h *DailyStatsHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(DailyStatsReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		// 默认查询最近30天
+		if req.EndTime <= 0 {
+			req.EndTime = time.Now().Unix()
+		}
+
+		if req.StartTime <= 0 {
+			req.StartTime = time.Unix(req.EndTime, 0).AddDate(0, 0, -29).Unix()
+		}
+
+		days, err := dao.StatBuckets(dao.StatGranularity_Day, req.StartTime, req.EndTime, DailyStatsMaxDays)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		log, err := s.GetZapLogger("DailyStatsHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		list, err := dao.GetDailyStats(req.GenHash, req.StartTime, req.EndTime, s.Db())
+		if err != nil {
+			log.Errorf("fail to get daily stats, err: [%s], genHash: [%s]\n", err.Error(), req.GenHash)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		stats := make(map[int64]*DailyStatsResp, len(list))
+		for _, v := range list {
+			stats[v.StatDate] = &DailyStatsResp{
+				Timestamp:        v.StatDate,
+				BlockCount:       v.BlockCount,
+				TxCount:          v.TxCount,
+				FailedTxCount:    v.FailedTxCount,
+				GasUsed:          v.GasUsed,
+				SenderCount:      v.SenderCount,
+				NewContractCount: v.NewContractCount,
+				AvgBlockInterval: v.AvgBlockInterval(),
+			}
+		}
+
+		// 没有区块的日期补零
+		resp := make([]*DailyStatsResp, 0, len(days))
+		for _, d := range days {
+			r, ok := stats[d]
+			if !ok {
+				r = &DailyStatsResp{Timestamp: d}
+			}
+
+			resp = append(resp, r)
+		}
+
+		SuccessfulJSONResp(resp, "", c)
+	}
+}
+
+type RebuildDailyStatsHandler struct {
+}
+
+type RebuildDailyStatsReq struct {
+	GenHash string `json:"genHash"`
+	// 从该时间所在自然日开始重新汇总，为0时全部重建
+	FromTime int64 `json:"fromTime"`
+}
+
+func (h *RebuildDailyStatsHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := new(RebuildDailyStatsReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		err := checkStringParamsEmpty(req.GenHash)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		if req.FromTime < 0 {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		log, err := s.GetZapLogger("RebuildDailyStatsHandler")
+		if err != nil {
+			FailedJSONResp(RespMsgLogServerError, c)
+			return
+		}
+
+		err = s.RebuildDailyStats(req.GenHash, req.FromTime)
+		if err != nil {
+			log.Errorf("fail to rebuild the daily stats, err: [%s], genHash: [%s], fromTime: [%d]\n",
+				err.Error(), req.GenHash, req.FromTime)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
+
+		SuccessfulJSONResp(req.GenHash, "", c)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"chainmscan/blockchain"
 	"chainmscan/db/dao"
 	"chainmscan/server"
+	"errors"
 	"os"
 	"path"
 
@@ -14,18 +15,66 @@ type SubscribeHandler struct {
 }
 
 type SubscribeReq struct {
-	ChainId          string `json:"chainId"`
-	ChainName        string `json:"chainName"`
-	OrgId            string `json:"orgId"`
-	NodeAddr         string `json:"nodeAddr"`
-	NodeCaCertPem    string `json:"nodeCaCertPem"`
-	NodeTlsHostName  string `json:"nodeTlsHostName"`
-	NodeUseTls       bool   `json:"nodeUseTls"`
-	SignCertPem      string `json:"signCertPem"`
-	SignKeyPem       string `json:"signKeyPem"`
-	TlsCertPem       string `json:"tlsCertPem"`
-	TlsKeyPem        string `json:"tlsKeyPem"`
-	ArchiveCenterUrl string `json:"archiveCenterUrl"`
+	ChainId          string     `json:"chainId"`
+	ChainName        string     `json:"chainName"`
+	OrgId            string     `json:"orgId"`
+	NodeAddr         string     `json:"nodeAddr"`
+	NodeCaCertPem    string     `json:"nodeCaCertPem"`
+	NodeTlsHostName  string     `json:"nodeTlsHostName"`
+	NodeUseTls       bool       `json:"nodeUseTls"`
+	Nodes            []*NodeReq `json:"nodes"`
+	SignCertPem      string     `json:"signCertPem"`
+	SignKeyPem       string     `json:"signKeyPem"`
+	TlsCertPem       string     `json:"tlsCertPem"`
+	TlsKeyPem        string     `json:"tlsKeyPem"`
+	ArchiveCenterUrl string     `json:"archiveCenterUrl"`
+	WithRwSet        bool       `json:"withRwSet"`
+	// 身份认证模式：permissionedWithCert、permissionedWithKey、public，
+	// 公钥模式无需签名证书，需指定哈希算法：SHA256、SHA3_256、SM3
+	AuthType      string `json:"authType"`
+	HashAlgorithm string `json:"hashAlgorithm"`
+}
+
+// NodeReq 订阅链的节点配置，多节点时由SDK的节点连接池选择可用节点，
+// nodeCaCertPem可包含多个拼接在一起的PEM证书
+type NodeReq struct {
+	NodeAddr        string `json:"nodeAddr"`
+	NodeCaCertPem   string `json:"nodeCaCertPem"`
+	NodeTlsHostName string `json:"nodeTlsHostName"`
+	NodeUseTls      bool   `json:"nodeUseTls"`
+}
+
+// nodeConfs 兼容单节点参数，nodes为空时使用nodeAddr等单节点配置
+func (req *SubscribeReq) nodeConfs() ([]*blockchain.NodeConnConfig, error) {
+	nodes := req.Nodes
+	if len(nodes) == 0 {
+		nodes = []*NodeReq{
+			{
+				NodeAddr:        req.NodeAddr,
+				NodeCaCertPem:   req.NodeCaCertPem,
+				NodeTlsHostName: req.NodeTlsHostName,
+				NodeUseTls:      req.NodeUseTls,
+			},
+		}
+	}
+
+	confs := make([]*blockchain.NodeConnConfig, 0, len(nodes))
+
+	for _, n := range nodes {
+		err := checkStringParamsEmpty(n.NodeAddr)
+		if err != nil {
+			return nil, err
+		}
+
+		confs = append(confs, &blockchain.NodeConnConfig{
+			Addr:        n.NodeAddr,
+			CaCertPem:   blockchain.SplitCertPems(n.NodeCaCertPem),
+			TlsHostName: n.NodeTlsHostName,
+			UseTls:      n.NodeUseTls,
+		})
+	}
+
+	return confs, nil
 }
 
 func (h *SubscribeHandler) Handle(s *server.Server) gin.HandlerFunc {
@@ -36,13 +85,24 @@ func (h *SubscribeHandler) Handle(s *server.Server) gin.HandlerFunc {
 			return
 		}
 
-		err := checkStringParamsEmpty(req.ChainId, req.ChainName, req.OrgId, req.SignKeyPem,
-			req.NodeAddr)
+		err := checkStringParamsEmpty(req.ChainId, req.ChainName, req.OrgId, req.SignKeyPem)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsMissing, c)
+			return
+		}
+
+		nodeConfs, err := req.nodeConfs()
 		if err != nil {
 			FailedJSONResp(RespMsgParamsMissing, c)
 			return
 		}
 
+		authType, err := checkAuthParams(req.AuthType, req.HashAlgorithm, len(req.SignCertPem) != 0)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
 		log, err := s.GetZapLogger("SubscribeHandler")
 		if err != nil {
 			FailedJSONResp(RespMsgLogServerError, c)
@@ -51,22 +111,17 @@ func (h *SubscribeHandler) Handle(s *server.Server) gin.HandlerFunc {
 
 		// TODO client 加缓存？
 		config := &blockchain.ClientConfig{
-			ChainId:       req.ChainId,
-			OrgId:         req.OrgId,
-			SignKeyBytes:  []byte(req.SignKeyPem),
-			SignCertBytes: []byte(req.SignCertPem),
-			NodeConfs: []*blockchain.NodeConnConfig{
-				{
-					Addr:        req.NodeAddr,
-					CaCertPem:   []string{req.NodeCaCertPem},
-					TlsHostName: req.NodeTlsHostName,
-					UseTls:      req.NodeUseTls,
-				},
-			},
+			ChainId:          req.ChainId,
+			OrgId:            req.OrgId,
+			SignKeyBytes:     []byte(req.SignKeyPem),
+			SignCertBytes:    []byte(req.SignCertPem),
+			NodeConfs:        nodeConfs,
 			TlsKeyBytes:      []byte(req.TlsKeyPem),
 			TlsCertBytes:     []byte(req.TlsCertPem),
 			ArchiveCenterUrl: req.ArchiveCenterUrl,
 			Logger:           log,
+			AuthType:         authType,
+			HashAlgorithm:    req.HashAlgorithm,
 		}
 
 		client, err := blockchain.NewChainmakerClient(config)
@@ -77,7 +132,7 @@ func (h *SubscribeHandler) Handle(s *server.Server) gin.HandlerFunc {
 			return
 		}
 
-		err = s.Subscribe(client, req.ChainName)
+		err = s.Subscribe(client, req.ChainName, req.WithRwSet)
 		if err != nil {
 			log.Errorf("fail to subscribe, err: [%s], genHash: [%s]\n",
 				err.Error(), client.GetChainGenHash())
@@ -132,18 +187,30 @@ type SubscribeByFileHandler struct {
 }
 
 type SubscribeByFileReq struct {
-	ChainId          string `json:"chainId"`
-	ChainName        string `json:"chainName"`
-	OrgId            string `json:"orgId"`
+	ChainId          string         `json:"chainId"`
+	ChainName        string         `json:"chainName"`
+	OrgId            string         `json:"orgId"`
+	NodeAddr         string         `json:"nodeAddr"`
+	NodeCaCertFileId string         `json:"nodeCaCertFileId"`
+	NodeTlsHostName  string         `json:"nodeTlsHostName"`
+	NodeUseTls       bool           `json:"nodeUseTls"`
+	Nodes            []*NodeFileReq `json:"nodes"`
+	SignCertFileId   string         `json:"signCertFileId"`
+	SignKeyFileId    string         `json:"signKeyFileId"`
+	TlsCertFileId    string         `json:"tlsCertFileId"`
+	TlsKeyFileId     string         `json:"tlsKeyFileId"`
+	ArchiveCenterUrl string         `json:"archiveCenterUrl"`
+	WithRwSet        bool           `json:"withRwSet"`
+	AuthType         string         `json:"authType"`
+	HashAlgorithm    string         `json:"hashAlgorithm"`
+}
+
+// NodeFileReq 通过上传文件指定节点CA证书的节点配置
+type NodeFileReq struct {
 	NodeAddr         string `json:"nodeAddr"`
 	NodeCaCertFileId string `json:"nodeCaCertFileId"`
 	NodeTlsHostName  string `json:"nodeTlsHostName"`
 	NodeUseTls       bool   `json:"nodeUseTls"`
-	SignCertFileId   string `json:"signCertFileId"`
-	SignKeyFileId    string `json:"signKeyFileId"`
-	TlsCertFileId    string `json:"tlsCertFileId"`
-	TlsKeyFileId     string `json:"tlsKeyFileId"`
-	ArchiveCenterUrl string `json:"archiveCenterUrl"`
 }
 
 func (h *SubscribeByFileHandler) Handle(s *server.Server) gin.HandlerFunc {
@@ -154,13 +221,38 @@ func (h *SubscribeByFileHandler) Handle(s *server.Server) gin.HandlerFunc {
 			return
 		}
 
+		if len(req.Nodes) == 0 {
+			req.Nodes = []*NodeFileReq{
+				{
+					NodeAddr:         req.NodeAddr,
+					NodeCaCertFileId: req.NodeCaCertFileId,
+					NodeTlsHostName:  req.NodeTlsHostName,
+					NodeUseTls:       req.NodeUseTls,
+				},
+			}
+		}
+
 		err := checkStringParamsEmpty(req.ChainId, req.OrgId,
-			req.ChainName, req.SignKeyFileId, req.NodeAddr)
+			req.ChainName, req.SignKeyFileId)
 		if err != nil {
 			FailedJSONResp(RespMsgParamsMissing, c)
 			return
 		}
 
+		for _, n := range req.Nodes {
+			err = checkStringParamsEmpty(n.NodeAddr)
+			if err != nil {
+				FailedJSONResp(RespMsgParamsMissing, c)
+				return
+			}
+		}
+
+		authType, err := checkAuthParams(req.AuthType, req.HashAlgorithm, len(req.SignCertFileId) != 0)
+		if err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
 		log, err := s.GetZapLogger("SubscribeHandler")
 		if err != nil {
 			FailedJSONResp(RespMsgLogServerError, c)
@@ -195,32 +287,42 @@ func (h *SubscribeByFileHandler) Handle(s *server.Server) gin.HandlerFunc {
 			FailedJSONResp(RespMsgServerError, c)
 			return
 		}
-		nodeCaCertPem, err := readFileBytes(req.NodeCaCertFileId, s.UploadFilePath())
-		if err != nil {
-			log.Errorf("fail to read file, err: [%s], fileId: [%s]\n",
-				err.Error(), req.NodeCaCertFileId)
-			FailedJSONResp(RespMsgServerError, c)
-			return
+
+		nodeConfs := make([]*blockchain.NodeConnConfig, 0, len(req.Nodes))
+		for _, n := range req.Nodes {
+			conf := &blockchain.NodeConnConfig{
+				Addr:        n.NodeAddr,
+				TlsHostName: n.NodeTlsHostName,
+				UseTls:      n.NodeUseTls,
+			}
+
+			if n.NodeUseTls {
+				nodeCaCertPem, err := readFileBytes(n.NodeCaCertFileId, s.UploadFilePath())
+				if err != nil {
+					log.Errorf("fail to read file, err: [%s], fileId: [%s]\n",
+						err.Error(), n.NodeCaCertFileId)
+					FailedJSONResp(RespMsgServerError, c)
+					return
+				}
+				conf.CaCertPem = blockchain.SplitCertPems(string(nodeCaCertPem))
+			}
+
+			nodeConfs = append(nodeConfs, conf)
 		}
 
 		// TODO client 加缓存？
 		config := &blockchain.ClientConfig{
-			ChainId:       req.ChainId,
-			OrgId:         req.OrgId,
-			SignKeyBytes:  signKeyPem,
-			SignCertBytes: SignCertPem,
-			NodeConfs: []*blockchain.NodeConnConfig{
-				{
-					Addr:        req.NodeAddr,
-					CaCertPem:   []string{string(nodeCaCertPem)},
-					TlsHostName: req.NodeTlsHostName,
-					UseTls:      req.NodeUseTls,
-				},
-			},
+			ChainId:          req.ChainId,
+			OrgId:            req.OrgId,
+			SignKeyBytes:     signKeyPem,
+			SignCertBytes:    SignCertPem,
+			NodeConfs:        nodeConfs,
 			TlsKeyBytes:      tlsKeyPem,
 			TlsCertBytes:     tlsCertPem,
 			ArchiveCenterUrl: req.ArchiveCenterUrl,
 			Logger:           log,
+			AuthType:         authType,
+			HashAlgorithm:    req.HashAlgorithm,
 		}
 
 		client, err := blockchain.NewChainmakerClient(config)
@@ -231,7 +333,7 @@ func (h *SubscribeByFileHandler) Handle(s *server.Server) gin.HandlerFunc {
 			return
 		}
 
-		err = s.Subscribe(client, req.ChainName)
+		err = s.Subscribe(client, req.ChainName, req.WithRwSet)
 		if err != nil {
 			log.Errorf("fail to subscribe, err: [%s], genHash: [%s]\n",
 				err.Error(), client.GetChainGenHash())
@@ -247,8 +349,12 @@ type SubscriptionListHandler struct {
 }
 
 type SubscriptionListResp struct {
-	GenHash string `json:"genHash"`
-	ChainId string `json:"chainId"`
+	GenHash       string             `json:"genHash"`
+	ChainId       string             `json:"chainId"`
+	RetryCount    int                `json:"retryCount"`
+	LastRetryTime int64              `json:"lastRetryTime"`
+	LastError     string             `json:"lastError"`
+	SyncStatus    *server.SyncStatus `json:"syncStatus"`
 }
 
 func (h *SubscriptionListHandler) Handle(s *server.Server) gin.HandlerFunc {
@@ -279,18 +385,63 @@ func (h *SubscriptionListHandler) Handle(s *server.Server) gin.HandlerFunc {
 				return
 			}
 
-			resp = append(resp, &SubscriptionListResp{
+			sub, err := dao.GetInfoOfSubscription(v, s.Db())
+			if err != nil {
+				log.Errorf("fail to get subscription, err: [%s], genHash: [%s]\n",
+					err.Error(), v)
+				FailedJSONResp(RespMsgServerError, c)
+				return
+			}
+
+			r := &SubscriptionListResp{
 				GenHash: chain.GenHash,
 				ChainId: chain.ChainId + "@" + chain.GenHash,
-			})
+			}
+
+			if sub != nil {
+				r.RetryCount = sub.RetryCount
+				r.LastRetryTime = sub.LastRetryTime
+				r.LastError = sub.LastError
+			}
+
+			r.SyncStatus, _ = s.GetSyncStatus(v)
+
+			resp = append(resp, r)
 		}
 
 		SuccessfulJSONResp(resp, "", c)
 	}
 }
 
+// checkAuthParams 校验身份认证模式，证书模式需要签名证书，公钥模式需要哈希算法
+func checkAuthParams(authType, hashAlgorithm string, hasSignCert bool) (string, error) {
+	authType, err := blockchain.NormalizeAuthType(authType, hasSignCert)
+	if err != nil {
+		return "", err
+	}
+
+	if authType == blockchain.AuthType_PermissionedWithCert {
+		if !hasSignCert {
+			return "", errors.New("the sign cert is required")
+		}
+
+		return authType, nil
+	}
+
+	if len(hashAlgorithm) == 0 {
+		return "", errors.New("the hash algorithm is required")
+	}
+
+	return authType, nil
+}
+
+// readFileBytes 读取上传的文件后删除，文件ID为空时返回空内容
 func readFileBytes(filedId, dirPath string) ([]byte, error) {
 
+	if len(filedId) == 0 {
+		return nil, nil
+	}
+
 	filePath := path.Join(dirPath, filedId)
 
 	defer os.RemoveAll(filePath)
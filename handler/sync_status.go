@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"chainmscan/server"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SyncStatusHandler struct {
+}
+
+type SyncStatusReq struct {
+	// 为空时返回全部订阅链
+	GenHash string `json:"genHash"`
+}
+
+func (h *SyncStatusHandler) Handle(s *server.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		req := new(SyncStatusReq)
+		if err := c.ShouldBindJSON(req); err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
+		chainList := s.GetChainList()
+		if len(req.GenHash) != 0 {
+			chainList = []string{req.GenHash}
+		}
+
+		resp := make([]*server.SyncStatus, 0)
+
+		for _, v := range chainList {
+			status, ok := s.GetSyncStatus(v)
+			if !ok {
+				continue
+			}
+
+			resp = append(resp, status)
+		}
+
+		SuccessfulJSONResp(resp, "", c)
+	}
+}
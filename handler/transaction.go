@@ -1,38 +1,49 @@
 package handler
 
 import (
+	"chainmscan/blockchain"
 	"chainmscan/db/dao"
+	dbModel "chainmscan/db/model"
 	"chainmscan/server"
+	"errors"
 	"time"
 
-	"chainmaker.org/chainmaker/pb-go/v2/common"
 	"github.com/gin-gonic/gin"
-	"github.com/gogo/protobuf/proto"
 )
 
 type TxListHandler struct {
 }
 
-const TxListByContractNameCount = 100
-
 type TxListReq struct {
 	PageReq
 	GenHash      string `json:"genHash"`
 	BlockHeight  int64  `json:"blockHeight"`
 	ContractName string `json:"contractName"`
+	// 以下过滤条件可任意组合，高度和时间范围为闭区间
+	Method        string `json:"method"`
+	TxType        string `json:"txType"`
+	TxStatusCode  string `json:"txStatusCode"`
+	SenderOrgId   string `json:"senderOrgId"`
+	SenderAddress string `json:"senderAddress"`
+	StartHeight   int64  `json:"startHeight"`
+	EndHeight     int64  `json:"endHeight"`
+	StartTime     int64  `json:"startTime"`
+	EndTime       int64  `json:"endTime"`
 }
 
 type TxListResp struct {
-	Id           uint   `json:"id"`
-	TxId         string `json:"txId"`
-	BlockHeight  uint64 `json:"blockHeight"`
-	ChainId      string `json:"chainId"`
-	ContractName string `json:"contractName"`
-	Method       string `json:"method"`
-	TxType       string `json:"txType"`
-	Timestamp    int64  `json:"timestamp"`
-	SenderOrgId  string `json:"senderOrgId"`
-	TxStatusCode string `json:"txStatusCode"`
+	Id               uint   `json:"id"`
+	TxId             string `json:"txId"`
+	BlockHeight      uint64 `json:"blockHeight"`
+	ChainId          string `json:"chainId"`
+	ContractName     string `json:"contractName"`
+	Method           string `json:"method"`
+	TxType           string `json:"txType"`
+	Timestamp        int64  `json:"timestamp"`
+	SenderOrgId      string `json:"senderOrgId"`
+	SenderAddress    string `json:"senderAddress"`
+	SenderEvmAddress string `json:"senderEvmAddress"`
+	TxStatusCode     string `json:"txStatusCode"`
 }
 
 func (h *TxListHandler) Handle(s *server.Server) gin.HandlerFunc {
@@ -50,118 +61,134 @@ func (h *TxListHandler) Handle(s *server.Server) gin.HandlerFunc {
 			return
 		}
 
+		if (req.StartHeight > 0 && req.EndHeight > 0 && req.StartHeight > req.EndHeight) ||
+			(req.StartTime > 0 && req.EndTime > 0 && req.StartTime > req.EndTime) {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
 		checkPageReq(&req.PageReq)
 
+		cursorPage, err := req.cursorPage()
+		if err != nil {
+			FailedJSONResp(RespMsgParamsTypeError, c)
+			return
+		}
+
 		log, err := s.GetZapLogger("TxListHandler")
 		if err != nil {
 			FailedJSONResp(RespMsgLogServerError, c)
 			return
 		}
 
-		if len(req.ContractName) != 0 {
-			// 通过合约查询交易，考虑性能，只提供最新100条交易列表
-			txList, err := dao.GetLatestTxListByContractName(req.GenHash, req.ContractName, req.Page, req.PageSize, s.Db())
+		filter := &dao.TxFilter{
+			ContractName:  req.ContractName,
+			Method:        req.Method,
+			TxType:        req.TxType,
+			TxStatusCode:  req.TxStatusCode,
+			SenderOrgId:   req.SenderOrgId,
+			SenderAddress: blockchain.NormalizeAddress(req.SenderAddress),
+			StartHeight:   req.StartHeight,
+			EndHeight:     req.EndHeight,
+			StartTime:     req.StartTime,
+			EndTime:       req.EndTime,
+		}
+
+		if cursorPage != nil {
+			if req.BlockHeight > 0 {
+				filter.StartHeight, filter.EndHeight = req.BlockHeight, req.BlockHeight
+			}
+
+			txList, cursor, err := dao.GetTxListByCursor(req.GenHash, filter, cursorPage, s.Db())
 			if err != nil {
-				log.Errorf("fail to get tx by contract, err: [%s], genHash: [%s], contractName: [%s]\n",
-					err.Error(), req.GenHash, req.ContractName)
+				log.Errorf("fail to get tx list by cursor, err: [%s], genHash: [%s], filter: [%+v]\n",
+					err.Error(), req.GenHash, filter)
 				FailedJSONResp(RespMsgServerError, c)
 				return
 			}
 
-			resp := make([]*TxListResp, 0)
+			SuccessfulJSONRespWithCursor(newTxListResp(txList), cursor, c)
+			return
+		}
 
-			if txList == nil {
-				SuccessfulJSONRespWithPage(resp, 0, c)
-				return
+		if !filter.IsEmpty() {
+			if req.BlockHeight > 0 {
+				filter.StartHeight, filter.EndHeight = req.BlockHeight, req.BlockHeight
 			}
 
-			txCount, err := dao.GetLatestTxCountByContractName(req.GenHash, req.ContractName, TxListByContractNameCount, s.Db())
+			txList, total, err := dao.GetFilteredTxList(req.GenHash, filter, req.Page, req.PageSize, req.isAsc(), s.Db())
 			if err != nil {
-				log.Errorf("fail to get tx count by contract, err: [%s], genHash: [%s], contractName: [%s]\n",
-					err.Error(), req.GenHash, req.ContractName)
+				log.Errorf("fail to get filtered tx list, err: [%s], genHash: [%s], filter: [%+v]\n",
+					err.Error(), req.GenHash, filter)
 				FailedJSONResp(RespMsgServerError, c)
 				return
 			}
 
-			for _, v := range txList {
-				tlreq := &TxListResp{
-					Id:           v.ID,
-					TxId:         v.TxId,
-					BlockHeight:  v.BlockHeight,
-					ChainId:      v.ChainId,
-					ContractName: v.ContractName,
-					Method:       v.Method,
-					TxType:       v.TxType,
-					Timestamp:    v.Timestamp,
-					SenderOrgId:  v.SenderOrgId,
-					TxStatusCode: v.TxStatusCode,
-				}
-
-				resp = append(resp, tlreq)
-			}
+			SuccessfulJSONRespWithPage(newTxListResp(txList), total, c)
+			return
+		}
 
-			SuccessfulJSONRespWithPage(resp, int64(txCount), c)
+		txList, err := dao.GetTxList(req.GenHash, req.Page, req.PageSize, req.BlockHeight, req.isAsc(), s.Db())
+		if err != nil {
+			log.Errorf("fail to get tx list, err: [%s], genHash: [%s], height: [%d]\n",
+				err.Error(), req.GenHash, req.BlockHeight)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
 
-		} else {
-			txList, err := dao.GetTxList(req.GenHash, req.Page, req.PageSize, req.BlockHeight, s.Db())
+		if txList == nil {
+			SuccessfulJSONRespWithPage(make([]*TxListResp, 0), 0, c)
+			return
+		}
+
+		var txCount int64
+
+		if req.BlockHeight > 0 {
+			// 考虑性能，从区块中获取交易数量
+			txCount, err = dao.GetBlockTxCount(req.GenHash, req.BlockHeight, s.Db())
 			if err != nil {
-				log.Errorf("fail to get tx list, err: [%s], genHash: [%s], height: [%d]\n",
+				log.Errorf("fail to get tx count, err: [%s], genHash: [%s], height: [%d]\n",
 					err.Error(), req.GenHash, req.BlockHeight)
 				FailedJSONResp(RespMsgServerError, c)
 				return
 			}
-
-			resp := make([]*TxListResp, 0)
-
-			if txList == nil {
-				SuccessfulJSONRespWithPage(resp, 0, c)
+		} else {
+			txCount, err = dao.GetChainTxAmount(req.GenHash, s.Db())
+			if err != nil {
+				log.Errorf("fail to get tx count, err: [%s], genHash: [%s]\n",
+					err.Error(), req.GenHash)
+				FailedJSONResp(RespMsgServerError, c)
 				return
 			}
-
-			for _, v := range txList {
-				tlreq := &TxListResp{
-					Id:           v.ID,
-					TxId:         v.TxId,
-					BlockHeight:  v.BlockHeight,
-					ChainId:      v.ChainId,
-					ContractName: v.ContractName,
-					Method:       v.Method,
-					TxType:       v.TxType,
-					Timestamp:    v.Timestamp,
-					SenderOrgId:  v.SenderOrgId,
-					TxStatusCode: v.TxStatusCode,
-				}
-
-				resp = append(resp, tlreq)
-			}
-
-			var txCount int64
-
-			if req.BlockHeight > 0 {
-				// 考虑性能，从区块中获取交易数量
-				txCount, err = dao.GetBlockTxCount(req.GenHash, req.BlockHeight, s.Db())
-				if err != nil {
-					log.Errorf("fail to get tx count, err: [%s], genHash: [%s], height: [%d]\n",
-						err.Error(), req.GenHash, req.BlockHeight)
-					FailedJSONResp(RespMsgServerError, c)
-					return
-				}
-			} else {
-				txCount, err = dao.GetChainTxAmount(req.GenHash, s.Db())
-				if err != nil {
-					log.Errorf("fail to get tx count, err: [%s], genHash: [%s]\n",
-						err.Error(), req.GenHash)
-					FailedJSONResp(RespMsgServerError, c)
-					return
-				}
-			}
-
-			SuccessfulJSONRespWithPage(resp, txCount, c)
 		}
 
+		SuccessfulJSONRespWithPage(newTxListResp(txList), txCount, c)
 	}
 }
 
+func newTxListResp(txList []*dbModel.Transaction) []*TxListResp {
+	resp := make([]*TxListResp, 0, len(txList))
+
+	for _, v := range txList {
+		resp = append(resp, &TxListResp{
+			Id:               v.ID,
+			TxId:             v.TxId,
+			BlockHeight:      v.BlockHeight,
+			ChainId:          v.ChainId,
+			ContractName:     v.ContractName,
+			Method:           v.Method,
+			TxType:           v.TxType,
+			Timestamp:        v.Timestamp,
+			SenderOrgId:      v.SenderOrgId,
+			SenderAddress:    v.SenderAddress,
+			SenderEvmAddress: v.SenderEvmAddress,
+			TxStatusCode:     v.TxStatusCode,
+		})
+	}
+
+	return resp
+}
+
 type TxDetailsHandler struct {
 }
 
@@ -182,6 +209,8 @@ type TxDetailsResp struct {
 	ExpirationTime        int64  `json:"expirationTime"`
 	GasLimit              uint64 `json:"gasLimit"`
 	SenderOrgId           string `json:"senderOrgId"`
+	SenderAddress         string `json:"senderAddress"`
+	SenderEvmAddress      string `json:"senderEvmAddress"`
 	SenderInfo            string `json:"senderInfo"`
 	TxStatusCode          string `json:"txStatusCode"`
 	TxParameters          string `json:"txParameters"`
@@ -191,6 +220,11 @@ type TxDetailsResp struct {
 	ContractResult        string `json:"contractResult"`
 	ContractResultMessage string `json:"contractResultMessage"`
 	GasUsed               uint64 `json:"gasUsed"`
+	// 解析后的发送者和背书者身份
+	SenderIdentity     *blockchain.Identity   `json:"senderIdentity"`
+	EndorserIdentities []*blockchain.Identity `json:"endorserIdentities"`
+	// 从节点实时查询、尚未入库的交易，库内最大高度以下的区块随入库队列补齐，更高的区块随实时订阅入库
+	Unindexed bool `json:"unindexed"`
 }
 
 func (h *TxDetailsHandler) Handle(s *server.Server) gin.HandlerFunc {
@@ -228,7 +262,19 @@ func (h *TxDetailsHandler) Handle(s *server.Server) gin.HandlerFunc {
 		}
 
 		if txInfo == nil {
-			SuccessfulJSONResp(&TxDetailsResp{}, "", nil)
+			resp, err := txDetailsFromNode(s, req.GenHash, req.TxId)
+			if err != nil {
+				log.Errorf("fail to get tx from node, err: [%s], genHash: [%s], txId: [%s]\n",
+					err.Error(), req.GenHash, req.TxId)
+				FailedJSONResp(RespMsgServerError, c)
+				return
+			}
+
+			if resp == nil {
+				resp = &TxDetailsResp{}
+			}
+
+			SuccessfulJSONResp(resp, "", c)
 			return
 		}
 
@@ -240,42 +286,89 @@ func (h *TxDetailsHandler) Handle(s *server.Server) gin.HandlerFunc {
 			return
 		}
 
-		var sender common.EndorsementEntry
+		resp, err := newTxDetailsResp(txInfo, txDetails)
+		if err != nil {
+			log.Errorf("fail to decode the tx identities, err: [%s], genHash: [%s], txId: [%s]\n",
+				err.Error(), req.GenHash, req.TxId)
+			FailedJSONResp(RespMsgServerError, c)
+			return
+		}
 
-		if len(txDetails.SenderBytes) != 0 {
-			err := proto.Unmarshal(txDetails.SenderBytes, &sender)
-			if err != nil {
-				log.Errorf("fail to unmarshal the sender bytes, err: [%s], genHash: [%s], txId: [%s]\n",
-					err.Error(), req.GenHash, req.TxId)
-				FailedJSONResp(RespMsgServerError, c)
-				return
-			}
+		SuccessfulJSONResp(resp, "", c)
+	}
+}
+
+// newTxDetailsResp 组装交易详情，解析发送者和背书者身份
+func newTxDetailsResp(txInfo *dbModel.Transaction, txDetails *dbModel.TxDetails) (*TxDetailsResp, error) {
+	sender, err := blockchain.DecodeEndorsementBytes(txDetails.SenderBytes)
+	if err != nil {
+		return nil, errors.New("fail to decode the sender, " + err.Error())
+	}
+
+	endorsers, err := blockchain.DecodeEndorsersJson(txDetails.EndorsersBytes)
+	if err != nil {
+		return nil, errors.New("fail to decode the endorsers, " + err.Error())
+	}
+
+	resp := &TxDetailsResp{
+		TxId:                  txInfo.TxId,
+		BlockHeight:           txInfo.BlockHeight,
+		ChainId:               txInfo.ChainId,
+		ContractName:          txInfo.ContractName,
+		Method:                txInfo.Method,
+		TxType:                txInfo.TxType,
+		Timestamp:             txInfo.Timestamp,
+		ExpirationTime:        txInfo.ExpirationTime,
+		GasLimit:              txInfo.GasLimit,
+		SenderOrgId:           txInfo.SenderOrgId,
+		SenderAddress:         txInfo.SenderAddress,
+		SenderEvmAddress:      txInfo.SenderEvmAddress,
+		TxStatusCode:          txInfo.TxStatusCode,
+		TxParameters:          string(txDetails.TxParameters),
+		RwSetHash:             txDetails.RwSetHash,
+		TxMessage:             txDetails.TxMessage,
+		ContractResultCode:    txDetails.ContractResultCode,
+		ContractResult:        string(txDetails.ContractResult),
+		ContractResultMessage: txDetails.ContractResultMessage,
+		GasUsed:               txDetails.GasUsed,
+		SenderIdentity:        sender,
+		EndorserIdentities:    endorsers,
+	}
+
+	if sender != nil {
+		resp.SenderInfo = sender.MemberInfo()
+	}
+
+	return resp, nil
+}
+
+// txDetailsFromNode 库内没有的交易从节点查询，未开启实时查询或未查到时返回nil
+func txDetailsFromNode(s *server.Server, genHash, txId string) (*TxDetailsResp, error) {
+	if len(txId) == 0 {
+		return nil, nil
+	}
+
+	blockData, err := s.FetchTxFromNode(genHash, txId)
+	if err != nil || blockData == nil {
+		return nil, err
+	}
+
+	for i, t := range blockData.Transactions {
+		if t.TxId != txId {
+			continue
 		}
 
-		resp := &TxDetailsResp{
-			TxId:                  txInfo.TxId,
-			BlockHeight:           txInfo.BlockHeight,
-			ChainId:               txInfo.ChainId,
-			ContractName:          txInfo.ContractName,
-			Method:                txInfo.Method,
-			TxType:                txInfo.TxType,
-			Timestamp:             txInfo.Timestamp,
-			ExpirationTime:        txInfo.ExpirationTime,
-			GasLimit:              txInfo.GasLimit,
-			SenderOrgId:           txInfo.SenderOrgId,
-			TxStatusCode:          txInfo.TxStatusCode,
-			TxParameters:          string(txDetails.TxParameters),
-			RwSetHash:             txDetails.RwSetHash,
-			TxMessage:             txDetails.TxMessage,
-			ContractResultCode:    txDetails.ContractResultCode,
-			ContractResult:        string(txDetails.ContractResult),
-			ContractResultMessage: txDetails.ContractResultMessage,
-			GasUsed:               txDetails.GasUsed,
-			SenderInfo:            string(sender.Signer.MemberInfo),
+		resp, err := newTxDetailsResp(t, blockData.TransactionDetails[i])
+		if err != nil {
+			return nil, err
 		}
 
-		SuccessfulJSONResp(resp, "", c)
+		resp.Unindexed = true
+
+		return resp, nil
 	}
+
+	return nil, nil
 }
 
 type TxAmountByTimeHandler struct {
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"chainmscan/blockchain"
+	"chainmscan/db/dao"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+
+	dbModel "chainmscan/db/model"
+)
+
+func (s *Server) backfillEnabled() bool {
+	return s.config.IngestConfig.BackfillWorkers > 0
+}
+
+// planBackfill 将[from, 链上最新高度]按区间切分，记录为待回填区间
+func (s *Server) planBackfill(c *blockchain.BlockChainClient, genHash string, from uint64) error {
+	tip, err := c.GetChainMakerClient().GetCurrentBlockHeight()
+	if err != nil {
+		return errors.New("fail to get current block height, " + err.Error())
+	}
+
+	if tip < from {
+		return nil
+	}
+
+	rangeSize := uint64(s.config.IngestConfig.BackfillRangeSize)
+
+	ranges := make([]*dbModel.BackfillRange, 0, (tip-from)/rangeSize+1)
+
+	for start := from; start <= tip; start += rangeSize {
+		end := start + rangeSize - 1
+		if end > tip {
+			end = tip
+		}
+
+		ranges = append(ranges, &dbModel.BackfillRange{
+			GenHash:     genHash,
+			StartHeight: start,
+			EndHeight:   end,
+			NextHeight:  start,
+		})
+	}
+
+	s.SysLog().Infof("plan the backfill, genHash: [%s], height: [%d-%d], ranges: [%d]\n",
+		genHash, from, tip, len(ranges))
+
+	return dao.InsertBackfillRanges(ranges, s.gormDb)
+}
+
+// backfill 并行回填未完成的区间，区块乱序入库。全部完成后若链上新增区块仍多于一个区间，
+// 则继续规划回填，否则交由实时订阅从库内最大高度+1处接续
+func (s *Server) backfill(ctx context.Context, sub *subscriber, c *blockchain.BlockChainClient) error {
+	for {
+		ranges, err := dao.GetUnfinishedBackfillRanges(sub.genHash, s.gormDb)
+		if err != nil {
+			return errors.New("fail to get the backfill ranges, " + err.Error())
+		}
+
+		if len(ranges) == 0 {
+			return nil
+		}
+
+		err = s.runBackfill(ctx, sub, c, ranges)
+		if err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		maxHeight, err := dao.MaxBlockHeightInDb(sub.genHash, s.gormDb)
+		if err != nil {
+			return errors.New("fail to get max block height in db, " + err.Error())
+		}
+
+		tip, err := c.GetChainMakerClient().GetCurrentBlockHeight()
+		if err != nil {
+			return errors.New("fail to get current block height, " + err.Error())
+		}
+
+		if int64(tip)-maxHeight <= int64(s.config.IngestConfig.BackfillRangeSize) {
+			s.SysLog().Infof("the backfill is finished, switch to the live subscription, genHash: [%s], height: [%d]\n",
+				sub.genHash, maxHeight)
+			return nil
+		}
+
+		err = s.planBackfill(c, sub.genHash, uint64(maxHeight+1))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) runBackfill(ctx context.Context, sub *subscriber, c *blockchain.BlockChainClient,
+	ranges []*dbModel.BackfillRange) error {
+
+	workers := s.config.IngestConfig.BackfillWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > len(ranges) {
+		workers = len(ranges)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rangeC := make(chan *dbModel.BackfillRange)
+	errC := make(chan error, workers)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range rangeC {
+				err := s.backfillRange(ctx, sub, c, r)
+				if err != nil {
+					errC <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, r := range ranges {
+		select {
+		case rangeC <- r:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(rangeC)
+
+	wg.Wait()
+
+	select {
+	case err := <-errC:
+		return err
+	default:
+		return nil
+	}
+}
+
+// backfillRange 按高度拉取区间内的区块，每BatchBlocks个区块与区间进度在同一事务内入库
+func (s *Server) backfillRange(ctx context.Context, sub *subscriber, c *blockchain.BlockChainClient,
+	r *dbModel.BackfillRange) error {
+
+	batchBlocks := s.config.IngestConfig.BatchBlocks
+
+	batch := make([]*blockchain.BlockData, 0, batchBlocks)
+
+	for h := r.NextHeight; h <= r.EndHeight; h++ {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		blockInfo, err := c.GetChainMakerClient().GetBlockByHeight(h, sub.withRWSet)
+		if err != nil {
+			return fmt.Errorf("fail to get block by height, height: [%d], err: [%s]", h, err.Error())
+		}
+
+		blockData, err := blockchain.ParseBlock(blockInfo, c.GetChainHashType(), c.LookupCert)
+		if err != nil {
+			return fmt.Errorf("fail to parse block, height: [%d], err: [%s]", h, err.Error())
+		}
+
+		batch = append(batch, blockData)
+
+		if len(batch) < batchBlocks && h != r.EndHeight {
+			continue
+		}
+
+		nextHeight, done := h+1, h == r.EndHeight
+
+		err = blockchain.StorageBlocks(batch, sub.genHash, sub.tableNum, s.gormDb,
+			func(tx *gorm.DB) error {
+				return dao.UpdateBackfillRangeProgress(r.ID, nextHeight, done, tx)
+			})
+		if err != nil {
+			return fmt.Errorf("fail to storage block, height: [%d-%d], err: [%s]",
+				batch[0].Block.BlockHeight, h, err.Error())
+		}
+
+		batch = batch[:0]
+	}
+
+	return nil
+}
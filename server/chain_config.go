@@ -0,0 +1,68 @@
+package server
+
+import (
+	"chainmscan/blockchain"
+	"chainmscan/db/dao"
+	"errors"
+	"math"
+
+	dbModel "chainmscan/db/model"
+)
+
+// GetChainConfigAtHeight 查询指定高度生效的链配置，blockHeight小于0时查询最新配置。
+// 库内没有不大于该高度的变更记录时（如订阅时未开启读写集，创世配置无法解析）从节点查询，fromNode为true
+func (s *Server) GetChainConfigAtHeight(genHash string,
+	blockHeight int64) (record *dbModel.ChainConfig, fromNode bool, err error) {
+
+	height := uint64(math.MaxUint64)
+	if blockHeight >= 0 {
+		height = uint64(blockHeight)
+	}
+
+	record, err = dao.GetChainConfigAtHeight(genHash, height, s.gormDb)
+	if err != nil {
+		return nil, false, errors.New("fail to get chain config, " + err.Error())
+	}
+
+	if record != nil {
+		return record, false, nil
+	}
+
+	s.chainListMapMutex.Lock()
+	sub, ok := s.chainList[genHash]
+	var c *blockchain.BlockChainClient
+	if ok {
+		c = sub.client
+	}
+	s.chainListMapMutex.Unlock()
+
+	if c == nil {
+		return nil, false, nil
+	}
+
+	if blockHeight < 0 {
+		chainConfig, err := c.GetChainMakerClient().GetChainConfig()
+		if err != nil {
+			return nil, false, errors.New("fail to get chain config from node, " + err.Error())
+		}
+
+		record, err = blockchain.NewChainConfigRecord(chainConfig)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return record, true, nil
+	}
+
+	chainConfig, err := c.GetChainMakerClient().GetChainConfigByBlockHeight(height)
+	if err != nil {
+		return nil, false, errors.New("fail to get chain config from node, " + err.Error())
+	}
+
+	record, err = blockchain.NewChainConfigRecord(chainConfig)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return record, true, nil
+}
@@ -0,0 +1,342 @@
+package server
+
+import (
+	"chainmscan/blockchain"
+	"chainmscan/db/dao"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	dbModel "chainmscan/db/model"
+)
+
+const (
+	// 校验时每次读取的区块数
+	integrityScanPageSize = 5000
+	// 报告中最多列出的异常高度数
+	integrityReportLimit = 1000
+)
+
+// IntegrityReport 链数据完整性校验报告
+type IntegrityReport struct {
+	GenHash          string   `json:"genHash"`
+	CheckedAt        int64    `json:"checkedAt"`
+	Backfilling      bool     `json:"backfilling"`
+	MaxHeight        int64    `json:"maxHeight"`
+	BlockRows        int64    `json:"blockRows"`
+	TxRows           int64    `json:"txRows"`
+	BlockAmount      int      `json:"blockAmount"`
+	TxAmount         int      `json:"txAmount"`
+	MissingCount     int      `json:"missingCount"`
+	MissingHeights   []uint64 `json:"missingHeights"`
+	DuplicateHeights []uint64 `json:"duplicateHeights"`
+	BrokenLinks      []uint64 `json:"brokenLinks"`
+	RepairedHeights  []uint64 `json:"repairedHeights"`
+	RepairedCount    int      `json:"repairedCount"`
+	CountersReset    bool     `json:"countersReset"`
+	RepairError      string   `json:"repairError"`
+	// 高度列表超过integrityReportLimit时只列出前integrityReportLimit个
+	Truncated bool `json:"truncated"`
+}
+
+// CheckChainIntegrity 校验链的区块表是否连续且哈希链接正确，并与链统计信息比对；
+// repair为true时从节点重新拉取缺失区块并按实际行数重置统计信息，已暂停的链不修复。
+// 拉取区块时不持有lifecycleMutex，入库和重置统计信息时持有，避免与暂停、重新索引交错
+func (s *Server) CheckChainIntegrity(genHash string, repair bool) (*IntegrityReport, error) {
+	chainInfo, err := dao.GetChainInfo(genHash, s.gormDb)
+	if err != nil {
+		return nil, errors.New("query chain info err, " + err.Error())
+	}
+
+	if chainInfo == nil {
+		return nil, errors.New("the chain info does not exist")
+	}
+
+	report := &IntegrityReport{
+		GenHash:          genHash,
+		CheckedAt:        time.Now().Unix(),
+		MissingHeights:   make([]uint64, 0),
+		DuplicateHeights: make([]uint64, 0),
+		BrokenLinks:      make([]uint64, 0),
+		RepairedHeights:  make([]uint64, 0),
+	}
+
+	ranges, err := dao.GetUnfinishedBackfillRanges(genHash, s.gormDb)
+	if err != nil {
+		return nil, errors.New("fail to get the backfill ranges, " + err.Error())
+	}
+
+	// 回填过程中区块乱序入库，缺失区块属于正常情况，不做修复
+	report.Backfilling = len(ranges) != 0
+
+	report.MaxHeight, err = dao.MaxBlockHeightInDb(genHash, s.gormDb)
+	if err != nil {
+		return nil, errors.New("fail to get max block height in db, " + err.Error())
+	}
+
+	err = s.scanBlockLinks(chainInfo.TableNum, report)
+	if err != nil {
+		return nil, errors.New("fail to scan the block table, " + err.Error())
+	}
+
+	report.BlockRows, err = dao.CountShardTableRows(dbModel.TableNamePrefix_Block, chainInfo.TableNum, s.gormDb)
+	if err != nil {
+		return nil, errors.New("fail to count the blocks, " + err.Error())
+	}
+
+	report.TxRows, err = dao.CountShardTableRows(dbModel.TableNamePrefix_Transaction, chainInfo.TableNum, s.gormDb)
+	if err != nil {
+		return nil, errors.New("fail to count the transactions, " + err.Error())
+	}
+
+	report.BlockAmount = chainInfo.BlockAmount
+	report.TxAmount = chainInfo.TxAmount
+
+	report.Truncated = report.MissingCount > len(report.MissingHeights) ||
+		len(report.DuplicateHeights) >= integrityReportLimit || len(report.BrokenLinks) >= integrityReportLimit
+
+	if !repair || report.Backfilling {
+		return report, nil
+	}
+
+	if _, running := s.chainState(genHash); !running {
+		report.RepairError = "the chain is paused or not subscribed, skip repairing"
+		return report, nil
+	}
+
+	missing := report.MissingHeights
+	missingCount := report.MissingCount
+
+	// 每次扫描最多得到integrityReportLimit个缺失高度，修复后重新扫描直到没有缺失区块
+	for len(missing) != 0 {
+		err = s.repairMissingBlocks(genHash, chainInfo.TableNum, missing, report)
+		if err != nil {
+			report.RepairError = err.Error()
+			return report, nil
+		}
+
+		if missingCount <= len(missing) {
+			break
+		}
+
+		rescan := &IntegrityReport{}
+		err = s.scanBlockLinks(chainInfo.TableNum, rescan)
+		if err != nil {
+			report.RepairError = "fail to rescan the block table, " + err.Error()
+			return report, nil
+		}
+
+		// 没有进展时停止，避免反复拉取同一批区块
+		if rescan.MissingCount >= missingCount {
+			report.RepairError = fmt.Sprintf("the missing blocks were not repaired, missing: [%d]",
+				rescan.MissingCount)
+			return report, nil
+		}
+
+		missing, missingCount = rescan.MissingHeights, rescan.MissingCount
+	}
+
+	// 修复入库时已累加链统计信息，重新读取后再与实际行数比对
+	if report.RepairedCount != 0 {
+		chainInfo, err = dao.GetChainInfo(genHash, s.gormDb)
+		if err != nil {
+			report.RepairError = "fail to query the chain info after repairing, " + err.Error()
+			return report, nil
+		}
+
+		if chainInfo == nil {
+			report.RepairError = "the chain info does not exist"
+			return report, nil
+		}
+
+		report.BlockAmount = chainInfo.BlockAmount
+		report.TxAmount = chainInfo.TxAmount
+
+		report.BlockRows, err = dao.CountShardTableRows(dbModel.TableNamePrefix_Block, chainInfo.TableNum,
+			s.gormDb)
+		if err != nil {
+			report.RepairError = "fail to count the blocks, " + err.Error()
+			return report, nil
+		}
+
+		report.TxRows, err = dao.CountShardTableRows(dbModel.TableNamePrefix_Transaction, chainInfo.TableNum,
+			s.gormDb)
+		if err != nil {
+			report.RepairError = "fail to count the transactions, " + err.Error()
+			return report, nil
+		}
+	}
+
+	if int64(report.BlockAmount) != report.BlockRows || int64(report.TxAmount) != report.TxRows {
+		s.lifecycleMutex.Lock()
+		if _, running := s.chainState(genHash); running {
+			err = dao.ResetChainTxAndBlockAmount(genHash, chainInfo.TableNum, s.gormDb)
+		} else {
+			err = errors.New("the chain is paused or not subscribed")
+		}
+		s.lifecycleMutex.Unlock()
+		if err != nil {
+			report.RepairError = "fail to reset the chain counters, " + err.Error()
+			return report, nil
+		}
+		report.CountersReset = true
+	}
+
+	return report, nil
+}
+
+// scanBlockLinks 按高度顺序扫描区块表，记录缺失高度、重复高度和哈希链接断裂的高度
+func (s *Server) scanBlockLinks(tableNum int, report *IntegrityReport) error {
+	var (
+		lastHeight uint64
+		lastId     uint
+		prev       *dao.BlockLink
+		expected   uint64
+	)
+
+	for {
+		list, err := dao.GetBlockLinks(tableNum, lastHeight, lastId, integrityScanPageSize, s.gormDb)
+		if err != nil {
+			return err
+		}
+
+		for _, b := range list {
+			if prev != nil && b.BlockHeight == prev.BlockHeight {
+				if len(report.DuplicateHeights) < integrityReportLimit {
+					report.DuplicateHeights = append(report.DuplicateHeights, b.BlockHeight)
+				}
+				continue
+			}
+
+			if b.BlockHeight > expected {
+				report.MissingCount += int(b.BlockHeight - expected)
+				for h := expected; h < b.BlockHeight && len(report.MissingHeights) < integrityReportLimit; h++ {
+					report.MissingHeights = append(report.MissingHeights, h)
+				}
+			}
+
+			if prev != nil && prev.BlockHeight+1 == b.BlockHeight && prev.BlockHash != b.PreBlockHash {
+				if len(report.BrokenLinks) < integrityReportLimit {
+					report.BrokenLinks = append(report.BrokenLinks, b.BlockHeight)
+				}
+			}
+
+			prev = b
+			expected = b.BlockHeight + 1
+		}
+
+		if len(list) < integrityScanPageSize {
+			return nil
+		}
+
+		lastHeight, lastId = list[len(list)-1].BlockHeight, list[len(list)-1].ID
+	}
+}
+
+// repairMissingBlocks 从节点重新拉取缺失的区块入库，修复的高度记录到报告中
+func (s *Server) repairMissingBlocks(genHash string, tableNum int, heights []uint64,
+	report *IntegrityReport) error {
+	s.chainListMapMutex.Lock()
+	sub, ok := s.chainList[genHash]
+	var c *blockchain.BlockChainClient
+	var withRWSet bool
+	if ok {
+		c, withRWSet = sub.client, sub.withRWSet
+	}
+	s.chainListMapMutex.Unlock()
+
+	if c == nil {
+		return errors.New("the chain client is not available")
+	}
+
+	batchBlocks := s.config.IngestConfig.BatchBlocks
+	batch := make([]*blockchain.BlockData, 0, batchBlocks)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		// 拉取期间链可能已暂停或重新索引，入库前重新确认
+		s.lifecycleMutex.Lock()
+		defer s.lifecycleMutex.Unlock()
+
+		if !s.isCurrentSubscriber(sub) {
+			return errors.New("the chain was resubscribed during repairing")
+		}
+
+		if _, running := s.chainState(genHash); !running {
+			return errors.New("the chain was paused during repairing")
+		}
+
+		err := blockchain.StorageBlocks(batch, genHash, tableNum, s.gormDb)
+		if err != nil {
+			return fmt.Errorf("fail to storage block, err: [%s]", err.Error())
+		}
+
+		for _, b := range batch {
+			if len(report.RepairedHeights) < integrityReportLimit {
+				report.RepairedHeights = append(report.RepairedHeights, b.Block.BlockHeight)
+			}
+		}
+		report.RepairedCount += len(batch)
+
+		batch = batch[:0]
+		return nil
+	}
+
+	for _, h := range heights {
+		blockInfo, err := c.GetChainMakerClient().GetBlockByHeight(h, withRWSet)
+		if err != nil {
+			return fmt.Errorf("fail to get block by height, height: [%d], err: [%s]", h, err.Error())
+		}
+
+		blockData, err := blockchain.ParseBlock(blockInfo, c.GetChainHashType(), c.LookupCert)
+		if err != nil {
+			return fmt.Errorf("fail to parse block, height: [%d], err: [%s]", h, err.Error())
+		}
+
+		batch = append(batch, blockData)
+
+		if len(batch) >= batchBlocks {
+			err = flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return flush()
+}
+
+// integrityCheck 定时校验所有订阅链的数据完整性并修复缺失区块
+func (s *Server) integrityCheck(ctx context.Context) error {
+	interval := time.Duration(s.config.IntegrityCheckInterval) * time.Minute
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, genHash := range s.GetChainList() {
+				report, err := s.CheckChainIntegrity(genHash, true)
+				if err != nil {
+					s.SysLog().Errorf("fail to check the chain integrity, err: [%s], genHash: [%s]\n",
+						err.Error(), genHash)
+					continue
+				}
+
+				if report.MissingCount != 0 || len(report.DuplicateHeights) != 0 ||
+					len(report.BrokenLinks) != 0 || report.CountersReset || len(report.RepairError) != 0 {
+					s.SysLog().Warnf("the chain integrity check found problems, report: [%+v]\n", report)
+				}
+			}
+
+		case <-ctx.Done():
+			s.SysLog().Info("the integrity check has been closed ...")
+			return nil
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package server
+
+import (
+	"chainmscan/blockchain"
+	"chainmscan/db/dao"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 等待链任务退出的超时时间
+const stopSubscriberTimeout = time.Minute
+
+// stopSubscriber 取消链的上下文并关闭客户端，等待链的全部任务退出
+func (s *Server) stopSubscriber(sub *subscriber) error {
+	sub.cancel()
+
+	s.chainListMapMutex.Lock()
+	if sub.client != nil {
+		sub.client.GetChainMakerClient().Stop()
+		sub.client = nil
+	}
+	s.chainListMapMutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		sub.tasks.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(stopSubscriberTimeout):
+		return errors.New("the chain tasks did not exit in time")
+	}
+}
+
+// PauseSubscription 暂停链的同步，停止区块处理、回填及导出任务，重启服务后保持暂停
+func (s *Server) PauseSubscription(genHash string) error {
+	s.lifecycleMutex.Lock()
+	defer s.lifecycleMutex.Unlock()
+
+	return s.pause(genHash)
+}
+
+func (s *Server) pause(genHash string) error {
+	s.chainListMapMutex.Lock()
+	sub, ok := s.chainList[genHash]
+	if ok && !sub.paused {
+		sub.paused = true
+	}
+	s.chainListMapMutex.Unlock()
+
+	if !ok {
+		return errors.New("the chain is not subscribed")
+	}
+
+	err := dao.UpdateSubscriptionPaused(genHash, true, s.gormDb)
+	if err != nil {
+		return errors.New("fail to update the subscription, " + err.Error())
+	}
+
+	err = s.stopSubscriber(sub)
+	if err != nil {
+		return err
+	}
+
+	s.SysLog().Infof("the chain subscription has been paused, genHash: [%s]\n", genHash)
+
+	return nil
+}
+
+// ResumeSubscription 恢复已暂停链的同步，从库内最大高度+1处继续
+func (s *Server) ResumeSubscription(genHash string) error {
+	s.lifecycleMutex.Lock()
+	defer s.lifecycleMutex.Unlock()
+
+	return s.resume(genHash, nil)
+}
+
+// resume 重新建立客户端并开启链的全部任务，prepare在任务开启前执行。
+// 调用方需持有lifecycleMutex，连接节点期间不持有chainListMapMutex，避免阻塞读取链列表的请求
+func (s *Server) resume(genHash string, prepare func(c *blockchain.BlockChainClient) error) error {
+	s.chainListMapMutex.Lock()
+	old, ok := s.chainList[genHash]
+	s.chainListMapMutex.Unlock()
+
+	if !ok {
+		return errors.New("the chain is not subscribed")
+	}
+
+	if !old.paused {
+		return errors.New("the chain subscription is not paused")
+	}
+
+	c, err := s.connectFromDb(genHash)
+	if err != nil {
+		return errors.New("fail to connect the chain, " + err.Error())
+	}
+
+	if prepare != nil {
+		err = prepare(c)
+		if err != nil {
+			c.GetChainMakerClient().Stop()
+			return err
+		}
+	}
+
+	err = dao.UpdateSubscriptionPaused(genHash, false, s.gormDb)
+	if err != nil {
+		c.GetChainMakerClient().Stop()
+		return errors.New("fail to update the subscription, " + err.Error())
+	}
+
+	sub := s.newSubscriber(genHash, old.tableNum, old.withRWSet)
+	sub.client = c
+
+	s.chainListMapMutex.Lock()
+	s.chainList[genHash] = sub
+	s.chainListMapMutex.Unlock()
+
+	// 开启区块导出
+	s.startSinks(sub)
+
+	// 开启订阅监听
+	s.submitChainTask(sub, s.listen(sub))
+
+	// 开启区块监听
+	s.submitChainTask(sub, s.startProcess(sub, c))
+
+	// 补齐旧版本交易的发送者地址
+	s.submitChainTask(sub, s.startSenderAddressMigration(sub, c))
+
+	s.SysLog().Infof("the chain subscription has been resumed, genHash: [%s]\n", genHash)
+
+	return nil
+}
+
+// ReindexChain 停止链的同步，删除高度不小于fromHeight的已入库数据（fromHeight为0时清空全部分表），
+// 按实际行数重置链统计信息、回退导出检查点并删除导出目标中的对应区块后重新同步。
+// 已暂停的链重建后保持暂停，由恢复同步时从库内最大高度+1处继续。
+// 删除数据前失败时恢复链的同步，删除数据后失败时链保持暂停，需重新执行重建
+func (s *Server) ReindexChain(genHash string, fromHeight uint64) error {
+	s.lifecycleMutex.Lock()
+	defer s.lifecycleMutex.Unlock()
+
+	chainInfo, err := dao.GetChainInfo(genHash, s.gormDb)
+	if err != nil {
+		return errors.New("query chain info err, " + err.Error())
+	}
+
+	if chainInfo == nil {
+		return errors.New("the chain info does not exist")
+	}
+
+	subscribed, running := s.chainState(genHash)
+	if !subscribed {
+		return errors.New("the chain is not subscribed")
+	}
+
+	if running {
+		err = s.pause(genHash)
+		if err != nil {
+			return errors.New("fail to stop the chain tasks, " + err.Error())
+		}
+	}
+
+	err = dao.ClampBackfillRanges(genHash, fromHeight, s.gormDb)
+	if err != nil {
+		err = errors.New("fail to clamp the backfill ranges, " + err.Error())
+
+		if running {
+			resumeErr := s.resume(genHash, nil)
+			if resumeErr != nil {
+				return fmt.Errorf("%s, and fail to resume the chain tasks, err: [%s]", err.Error(),
+					resumeErr.Error())
+			}
+		}
+
+		return err
+	}
+
+	if fromHeight == 0 {
+		err = dao.TruncateChainData(chainInfo.TableNum, s.gormDb)
+	} else {
+		err = dao.DeleteChainDataFromHeight(chainInfo.TableNum, fromHeight, s.gormDb)
+	}
+	if err != nil {
+		return errors.New("fail to delete the chain data, the chain is paused and the data may be " +
+			"partially deleted, please reindex again, " + err.Error())
+	}
+
+	err = dao.ResetChainTxAndBlockAmount(genHash, chainInfo.TableNum, s.gormDb)
+	if err != nil {
+		return errors.New("fail to reset the chain counters, the chain is paused, please reindex again, " +
+			err.Error())
+	}
+
+	// 重新入库的区块需要重新导出
+	err = dao.ClampSinkCheckpoints(genHash, fromHeight, s.gormDb)
+	if err != nil {
+		return errors.New("fail to reset the sink checkpoints, the chain is paused, please reindex again, " +
+			err.Error())
+	}
+
+	err = s.resetSinks(genHash, fromHeight)
+	if err != nil {
+		return errors.New("fail to reset the sinks, the chain is paused, please reindex again, " +
+			err.Error())
+	}
+
+	s.SysLog().Infof("the chain data has been deleted, reindex from height [%d], genHash: [%s]\n",
+		fromHeight, genHash)
+
+	if !running {
+		return nil
+	}
+
+	return s.resume(genHash, func(c *blockchain.BlockChainClient) error {
+		if !s.backfillEnabled() {
+			return nil
+		}
+
+		maxHeight, err := dao.MaxBlockHeightInDb(genHash, s.gormDb)
+		if err != nil {
+			return errors.New("fail to get max block height in db, " + err.Error())
+		}
+
+		// fromHeight之前保留的回填区间继续回填，新的回填区间从其后开始规划
+		endHeight, err := dao.GetMaxBackfillEndHeight(genHash, s.gormDb)
+		if err != nil {
+			return errors.New("fail to get the backfill ranges, " + err.Error())
+		}
+
+		if endHeight > maxHeight {
+			maxHeight = endHeight
+		}
+
+		err = s.planBackfill(c, genHash, uint64(maxHeight+1))
+		if err != nil {
+			return errors.New("fail to plan the backfill, " + err.Error())
+		}
+
+		return nil
+	})
+}
+
+// chainState 返回链是否已订阅及是否正在同步（未暂停）
+func (s *Server) chainState(genHash string) (bool, bool) {
+	s.chainListMapMutex.Lock()
+	defer s.chainListMapMutex.Unlock()
+
+	sub, ok := s.chainList[genHash]
+
+	return ok, ok && !sub.paused
+}
+
+// RebuildDailyStats 按已入库数据重新汇总fromTime所在自然日及之后的每日统计（fromTime为0时全部重建），
+// 汇总期间暂停链的同步，避免与入库时的增量累加交错
+func (s *Server) RebuildDailyStats(genHash string, fromTime int64) error {
+	s.lifecycleMutex.Lock()
+	defer s.lifecycleMutex.Unlock()
+
+	chainInfo, err := dao.GetChainInfo(genHash, s.gormDb)
+	if err != nil {
+		return errors.New("query chain info err, " + err.Error())
+	}
+
+	if chainInfo == nil {
+		return errors.New("the chain info does not exist")
+	}
+
+	_, running := s.chainState(genHash)
+
+	if running {
+		err = s.pause(genHash)
+		if err != nil {
+			return errors.New("fail to stop the chain tasks, " + err.Error())
+		}
+	}
+
+	err = dao.RebuildDailyStats(chainInfo.TableNum, fromTime, s.gormDb)
+	if err != nil {
+		err = errors.New("fail to rebuild the daily stats, " + err.Error())
+	} else {
+		s.SysLog().Infof("the daily stats have been rebuilt from [%d], genHash: [%s]\n", fromTime, genHash)
+	}
+
+	if running {
+		resumeErr := s.resume(genHash, nil)
+		if resumeErr != nil && err == nil {
+			err = errors.New("fail to resume the chain tasks, " + resumeErr.Error())
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"chainmscan/blockchain"
+	"chainmscan/db/dao"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// LiveIngestQueueSize 实时查询到的未入库区块的入库队列长度，队列满时丢弃
+const LiveIngestQueueSize = 100
+
+// liveIngestTask 待入库的实时查询区块
+type liveIngestTask struct {
+	genHash   string
+	blockData *blockchain.BlockData
+}
+
+func (s *Server) liveFallbackEnabled() bool {
+	return s.config.LiveFallback
+}
+
+// liveClient 获取链当前订阅使用的客户端，未订阅、已暂停或未连接时返回nil
+func (s *Server) liveClient(genHash string) (*blockchain.BlockChainClient, bool) {
+	s.chainListMapMutex.Lock()
+	defer s.chainListMapMutex.Unlock()
+
+	sub, ok := s.chainList[genHash]
+	if !ok || sub.paused || sub.client == nil {
+		return nil, false
+	}
+
+	return sub.client, sub.withRWSet
+}
+
+// FetchBlockFromNode 库内没有的区块从节点查询，blockHash不为空时按哈希查询，否则按高度查询。
+// 配置了归档中心时客户端优先从归档中心查询。查询到的区块加入入库队列（见ingestLiveBlock），未开启实时查询时返回nil
+func (s *Server) FetchBlockFromNode(genHash string, blockHeight int64,
+	blockHash string) (*blockchain.BlockData, error) {
+
+	if !s.liveFallbackEnabled() {
+		return nil, nil
+	}
+
+	c, withRWSet := s.liveClient(genHash)
+	if c == nil {
+		return nil, nil
+	}
+
+	var (
+		blockData *blockchain.BlockData
+		err       error
+	)
+
+	if len(blockHash) != 0 {
+		blockInfo, err := c.GetChainMakerClient().GetBlockByHash(blockHash, withRWSet)
+		if err != nil {
+			return nil, errors.New("fail to get block by hash, " + err.Error())
+		}
+
+		blockData, err = blockchain.ParseBlock(blockInfo, c.GetChainHashType(), c.LookupCert)
+		if err != nil {
+			return nil, errors.New("fail to parse block, " + err.Error())
+		}
+
+	} else if blockHeight > -1 {
+		blockData, err = s.fetchBlockByHeight(c, uint64(blockHeight), withRWSet)
+		if err != nil {
+			return nil, err
+		}
+
+	} else {
+		return nil, nil
+	}
+
+	s.enqueueLiveBlock(genHash, blockData)
+
+	return blockData, nil
+}
+
+// FetchTxFromNode 库内没有的交易从节点查询，返回交易所在的区块并加入入库队列（见ingestLiveBlock），
+// 未开启实时查询时返回nil
+func (s *Server) FetchTxFromNode(genHash, txId string) (*blockchain.BlockData, error) {
+	if !s.liveFallbackEnabled() {
+		return nil, nil
+	}
+
+	c, withRWSet := s.liveClient(genHash)
+	if c == nil {
+		return nil, nil
+	}
+
+	txInfo, err := c.GetChainMakerClient().GetTxByTxId(txId)
+	if err != nil {
+		return nil, errors.New("fail to get tx by tx id, " + err.Error())
+	}
+
+	if txInfo == nil {
+		return nil, nil
+	}
+
+	blockData, err := s.fetchBlockByHeight(c, txInfo.BlockHeight, withRWSet)
+	if err != nil {
+		return nil, err
+	}
+
+	if blockData.Block.BlockHash != hex.EncodeToString(txInfo.BlockHash) {
+		return nil, errors.New("the block hash of the tx does not match")
+	}
+
+	s.enqueueLiveBlock(genHash, blockData)
+
+	return blockData, nil
+}
+
+func (s *Server) fetchBlockByHeight(c *blockchain.BlockChainClient, height uint64,
+	withRWSet bool) (*blockchain.BlockData, error) {
+
+	blockInfo, err := c.GetChainMakerClient().GetBlockByHeight(height, withRWSet)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get block by height, height: [%d], err: [%s]", height, err.Error())
+	}
+
+	blockData, err := blockchain.ParseBlock(blockInfo, c.GetChainHashType(), c.LookupCert)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse block, height: [%d], err: [%s]", height, err.Error())
+	}
+
+	return blockData, nil
+}
+
+// enqueueLiveBlock 区块加入入库队列，队列满时丢弃，由订阅或完整性校验补齐
+func (s *Server) enqueueLiveBlock(genHash string, blockData *blockchain.BlockData) {
+	select {
+	case s.liveIngestC <- &liveIngestTask{genHash: genHash, blockData: blockData}:
+	default:
+		s.SysLog().Warnf("the live ingest queue is full, genHash: [%s], height: [%d]\n",
+			genHash, blockData.Block.BlockHeight)
+	}
+}
+
+// liveIngest 实时查询到的区块入库任务
+func (s *Server) liveIngest(ctx context.Context) error {
+	for {
+		select {
+		case t := <-s.liveIngestC:
+			err := s.ingestLiveBlock(t)
+			if err != nil {
+				s.SysLog().Errorf("fail to ingest the live block, err: [%s], genHash: [%s], height: [%d]\n",
+					err.Error(), t.genHash, t.blockData.Block.BlockHeight)
+			}
+
+		case <-ctx.Done():
+			s.SysLog().Info("the live ingest has been closed ...")
+			return nil
+		}
+	}
+}
+
+// ingestLiveBlock 只补齐库内最大高度以下缺失且不在回填区间内的区块。
+// 高于库内最大高度的区块直接忽略：实时订阅从库内最大高度+1处按序拉取，该区块必然随订阅入库，
+// 在此提前入库反而会使订阅接续时跳过中间的区块
+func (s *Server) ingestLiveBlock(t *liveIngestTask) error {
+	s.lifecycleMutex.Lock()
+	defer s.lifecycleMutex.Unlock()
+
+	s.chainListMapMutex.Lock()
+	sub, ok := s.chainList[t.genHash]
+	s.chainListMapMutex.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	height := t.blockData.Block.BlockHeight
+
+	block, _, err := dao.GetBlockInfo(t.genHash, -1, t.blockData.Block.BlockHash, 0, s.gormDb)
+	if err != nil {
+		return errors.New("fail to get block info, " + err.Error())
+	}
+
+	if block != nil {
+		return nil
+	}
+
+	maxHeight, err := dao.MaxBlockHeightInDb(t.genHash, s.gormDb)
+	if err != nil {
+		return errors.New("fail to get max block height in db, " + err.Error())
+	}
+
+	if int64(height) > maxHeight {
+		return nil
+	}
+
+	ranges, err := dao.GetUnfinishedBackfillRanges(t.genHash, s.gormDb)
+	if err != nil {
+		return errors.New("fail to get the backfill ranges, " + err.Error())
+	}
+
+	for _, r := range ranges {
+		if height >= r.NextHeight && height <= r.EndHeight {
+			return nil
+		}
+	}
+
+	err = blockchain.StorageBlocks([]*blockchain.BlockData{t.blockData}, t.genHash, sub.tableNum, s.gormDb)
+	if err != nil {
+		return err
+	}
+
+	s.SysLog().Infof("the live block has been ingested, genHash: [%s], height: [%d]\n", t.genHash, height)
+
+	return nil
+}
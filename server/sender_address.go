@@ -0,0 +1,110 @@
+package server
+
+import (
+	"chainmscan/blockchain"
+	"chainmscan/db/dao"
+	"context"
+	"errors"
+)
+
+// SenderAddressMigrateBatchSize 补齐发送者地址时每批查询及更新的交易数
+const SenderAddressMigrateBatchSize = 500
+
+// startSenderAddressMigration 补齐发送者地址的链任务，与区块同步并行执行，
+// 补齐失败不影响区块同步，下次开启订阅时重试
+func (s *Server) startSenderAddressMigration(sub *subscriber,
+	c *blockchain.BlockChainClient) func(ctx context.Context) error {
+
+	return func(ctx context.Context) error {
+		err := s.migrateSenderAddresses(ctx, sub, c)
+		if err != nil {
+			s.SysLog().Errorf("fail to migrate the sender addresses, err: [%s], genHash: [%s]\n",
+				err.Error(), sub.genHash)
+			return err
+		}
+
+		return nil
+	}
+}
+
+// migrateSenderAddresses 旧版本入库的交易没有发送者地址，按交易详情中保存的发送者补齐，
+// 证书哈希发送者通过链上登记的证书计算地址，每条链只执行一次
+func (s *Server) migrateSenderAddresses(ctx context.Context, sub *subscriber,
+	c *blockchain.BlockChainClient) error {
+
+	chainInfo, err := dao.GetChainInfo(sub.genHash, s.gormDb)
+	if err != nil {
+		return errors.New("query chain info err, " + err.Error())
+	}
+
+	if chainInfo == nil || chainInfo.SenderAddressMigrated {
+		return nil
+	}
+
+	s.SysLog().Infof("start to migrate the sender addresses, genHash: [%s]\n", sub.genHash)
+
+	var (
+		afterId uint
+		updated int
+	)
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		list, err := dao.GetTxSendersWithoutAddress(sub.tableNum, afterId, SenderAddressMigrateBatchSize,
+			s.gormDb)
+		if err != nil {
+			return errors.New("fail to get the tx senders, " + err.Error())
+		}
+
+		addresses := make([]*dao.TxSenderAddress, 0, len(list))
+
+		for _, v := range list {
+			afterId = v.Id
+
+			signer, err := blockchain.EndorsementSigner(v.SenderBytes)
+			if err != nil || signer == nil {
+				continue
+			}
+
+			signer, err = blockchain.ResolveCertHash(signer, c.LookupCert)
+			if err != nil {
+				return err
+			}
+
+			addr, evmAddr, err := blockchain.MemberAddress(signer, c.GetChainHashType())
+			if err != nil || len(addr) == 0 {
+				continue
+			}
+
+			addresses = append(addresses, &dao.TxSenderAddress{
+				Id:         v.Id,
+				Address:    addr,
+				EvmAddress: evmAddr,
+			})
+		}
+
+		err = dao.UpdateTxSenderAddresses(sub.tableNum, addresses, s.gormDb)
+		if err != nil {
+			return errors.New("fail to update the sender addresses, " + err.Error())
+		}
+
+		updated += len(addresses)
+
+		if len(list) < SenderAddressMigrateBatchSize {
+			break
+		}
+	}
+
+	err = dao.FinishSenderAddressMigration(sub.genHash, sub.tableNum, s.gormDb)
+	if err != nil {
+		return errors.New("fail to finish the sender address migration, " + err.Error())
+	}
+
+	s.SysLog().Infof("migrate the sender addresses successfully, genHash: [%s], updated: [%d]\n",
+		sub.genHash, updated)
+
+	return nil
+}
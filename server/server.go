@@ -1,17 +1,22 @@
 package server
 
 import (
+	"chainmscan/blockchain"
 	"chainmscan/config"
 	"chainmscan/db"
+	"chainmscan/db/dao"
 	"chainmscan/logger"
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"sync"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+
+	dbModel "chainmscan/db/model"
 )
 
 type Server struct {
@@ -22,8 +27,14 @@ type Server struct {
 	gormDb            *gorm.DB
 	ctxCancel         context.CancelFunc
 	workerPool        *WorkerPool
-	chainList         map[string]chan<- string
+	chainList         map[string]*subscriber
 	chainListMapMutex sync.Mutex
+	sinks             []blockchain.BlockSink
+	sinkConfigs       []*blockchain.SinkConfig
+	// 订阅、取消订阅、暂停、恢复及重建索引操作串行执行
+	lifecycleMutex sync.Mutex
+	// 实时查询到的未入库区块
+	liveIngestC chan *liveIngestTask
 }
 type Option func(s *Server)
 
@@ -58,8 +69,9 @@ func NewServer(opts ...Option) (*Server, error) {
 		opt(server)
 	}
 
-	server.chainList = make(map[string]chan<- string)
+	server.chainList = make(map[string]*subscriber)
 	server.chainListMapMutex = sync.Mutex{}
+	server.liveIngestC = make(chan *liveIngestTask, LiveIngestQueueSize)
 
 	wpLog, err := server.GetZapLogger("WorkerPool")
 	if err != nil {
@@ -75,6 +87,9 @@ func NewServer(opts ...Option) (*Server, error) {
 }
 
 func (s *Server) Start() error {
+	// 入库和查询统计前设置统计时区
+	dbModel.SetStatUtcOffset(s.config.StatsUtcOffset)
+
 	zlog, err := s.logBus.GetZapLogger("mysql")
 	if err != nil {
 		return err
@@ -88,6 +103,20 @@ func (s *Server) Start() error {
 
 	s.gormDb = mysqlDb
 
+	// 迁移已订阅链的分表
+	if s.config.GormConfig.EnableAutoMigrate {
+		err = s.migrateShardTables()
+		if err != nil {
+			return err
+		}
+	}
+
+	// 创建区块导出目标
+	err = s.initSinks()
+	if err != nil {
+		return err
+	}
+
 	// 启动协程管理池
 	s.workerPool.Start()
 
@@ -102,6 +131,63 @@ func (s *Server) Start() error {
 		return err
 	}
 
+	// 启动同步进度检查
+	err = s.workerPool.Submit(s.syncMonitor)
+	if err != nil {
+		return err
+	}
+
+	// 启动实时查询区块的入库任务
+	if s.liveFallbackEnabled() {
+		err = s.workerPool.Submit(s.liveIngest)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 启动数据完整性定时校验
+	if s.config.IntegrityCheckInterval > 0 {
+		err = s.workerPool.Submit(s.integrityCheck)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) migrateShardTables() error {
+	chainInfos, err := dao.GetAllChainInfo(s.gormDb)
+	if err != nil {
+		return errors.New("fail to get the chain info list, " + err.Error())
+	}
+
+	for _, v := range chainInfos {
+		err = dao.MigrateLegacyContracts(v.TableNum, s.gormDb)
+		if err != nil {
+			return fmt.Errorf("fail to migrate the legacy contracts, genHash: [%s], err: [%s]",
+				v.GenHash, err.Error())
+		}
+
+		err = db.MigrateShardTables(s.gormDb, v.TableNum)
+		if err != nil {
+			return fmt.Errorf("fail to migrate the shard tables, genHash: [%s], err: [%s]",
+				v.GenHash, err.Error())
+		}
+
+		err = dao.MigrateGasRollups(v.TableNum, s.gormDb)
+		if err != nil {
+			return fmt.Errorf("fail to migrate the gas rollups, genHash: [%s], err: [%s]",
+				v.GenHash, err.Error())
+		}
+
+		err = dao.MigrateDailyStats(v.TableNum, s.gormDb)
+		if err != nil {
+			return fmt.Errorf("fail to migrate the daily stats, genHash: [%s], err: [%s]",
+				v.GenHash, err.Error())
+		}
+	}
+
 	return nil
 }
 
@@ -128,6 +214,7 @@ func (s *Server) UploadFilePath() string {
 func (s *Server) Stop() error {
 	s.workerPool.Stop()
 	s.ctxCancel()
+	s.closeSinks()
 	return nil
 }
 
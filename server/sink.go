@@ -0,0 +1,278 @@
+package server
+
+import (
+	"chainmscan/blockchain"
+	"chainmscan/db/dao"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// 导出任务空闲时检查库内新区块的间隔
+	sinkPollInterval = 5 * time.Second
+	// 导出任务缓存的待导出批次数，缓存满时丢弃，由导出任务从节点补拉
+	sinkBufferSize = 16
+)
+
+// sinkRunner 单条链单个导出目标的导出任务，与区块入库解耦，按各自的检查点顺序导出
+type sinkRunner struct {
+	sink   blockchain.BlockSink
+	blockC chan []*blockchain.BlockData
+}
+
+// initSinks 根据配置创建区块导出目标
+func (s *Server) initSinks() error {
+	names := make(map[string]struct{})
+
+	for _, conf := range s.config.Sinks {
+		if _, ok := names[conf.Name]; ok {
+			return fmt.Errorf("the sink name [%s] is duplicated", conf.Name)
+		}
+		names[conf.Name] = struct{}{}
+
+		log, err := s.GetZapLogger("Sink")
+		if err != nil {
+			return err
+		}
+
+		sink, err := blockchain.NewBlockSink(conf, log)
+		if err != nil {
+			return fmt.Errorf("fail to create the sink [%s], err: [%s]", conf.Name, err.Error())
+		}
+
+		s.sinks = append(s.sinks, sink)
+		s.sinkConfigs = append(s.sinkConfigs, conf)
+	}
+
+	return nil
+}
+
+func (s *Server) closeSinks() {
+	for _, sink := range s.sinks {
+		err := sink.Close()
+		if err != nil {
+			s.SysLog().Errorf("fail to close the sink, err: [%s], sink: [%s]\n", err.Error(), sink.Name())
+		}
+	}
+}
+
+// resetSinks 删除匹配的导出目标中链的高度不小于fromHeight的区块
+func (s *Server) resetSinks(genHash string, fromHeight uint64) error {
+	for i, sink := range s.sinks {
+		if !s.sinkConfigs[i].Match(genHash) {
+			continue
+		}
+
+		err := sink.Reset(genHash, fromHeight)
+		if err != nil {
+			return fmt.Errorf("fail to reset the sink [%s], err: [%s]", sink.Name(), err.Error())
+		}
+	}
+
+	return nil
+}
+
+// startSinks 为订阅链开启匹配的导出任务
+func (s *Server) startSinks(sub *subscriber) error {
+	for i, sink := range s.sinks {
+		if !s.sinkConfigs[i].Match(sub.genHash) {
+			continue
+		}
+
+		r := &sinkRunner{
+			sink:   sink,
+			blockC: make(chan []*blockchain.BlockData, sinkBufferSize),
+		}
+
+		sub.sinkRunners = append(sub.sinkRunners, r)
+
+		err := s.submitChainTask(sub, s.runSink(sub, r))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// notifySinks 将已入库的区块分发给导出任务，导出任务积压时丢弃，不阻塞入库
+func (sub *subscriber) notifySinks(blocks []*blockchain.BlockData) {
+	for _, r := range sub.sinkRunners {
+		select {
+		case r.blockC <- blocks:
+		default:
+		}
+	}
+}
+
+// runSink 导出任务：优先导出入库流水线分发的区块，中间缺失的区块从节点补拉；
+// 空闲时（回填期间或分发被丢弃后）定时从节点补拉至库内最大高度
+func (s *Server) runSink(sub *subscriber, r *sinkRunner) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		log := s.SysLog()
+
+		checkpoint, err := dao.GetSinkCheckpoint(sub.genHash, r.sink.Name(), s.gormDb)
+		if err != nil {
+			log.Errorf("fail to get the sink checkpoint, err: [%s], genHash: [%s], sink: [%s]\n",
+				err.Error(), sub.genHash, r.sink.Name())
+			return err
+		}
+
+		var next uint64
+		if checkpoint != nil {
+			next = checkpoint.NextHeight
+		}
+
+		ticker := time.NewTicker(sinkPollInterval)
+		defer ticker.Stop()
+
+		var received bool
+
+		for {
+			select {
+			case blocks := <-r.blockC:
+				received = true
+				err = s.exportBlocks(ctx, sub, r, &next, blocks)
+
+			case <-ticker.C:
+				if !s.isCurrentSubscriber(sub) {
+					return nil
+				}
+
+				if received {
+					received = false
+					continue
+				}
+
+				var maxHeight int64
+				maxHeight, err = dao.MaxBlockHeightInDb(sub.genHash, s.gormDb)
+				if err != nil {
+					err = errors.New("fail to get max block height in db, " + err.Error())
+					break
+				}
+
+				if maxHeight >= int64(next) {
+					err = s.exportFromNode(ctx, sub, r, &next, uint64(maxHeight))
+				}
+
+			case <-ctx.Done():
+				return nil
+			}
+
+			if err != nil {
+				log.Errorf("fail to export blocks, err: [%s], genHash: [%s], sink: [%s], height: [%d]\n",
+					err.Error(), sub.genHash, r.sink.Name(), next)
+
+				err = dao.SaveSinkCheckpoint(sub.genHash, r.sink.Name(), next, err.Error(), s.gormDb)
+				if err != nil {
+					log.Errorf("fail to save the sink checkpoint, err: [%s], genHash: [%s], sink: [%s]\n",
+						err.Error(), sub.genHash, r.sink.Name())
+				}
+			}
+		}
+	}
+}
+
+// exportBlocks 导出分发的一批区块，与检查点之间缺失的区块先从节点补拉
+func (s *Server) exportBlocks(ctx context.Context, sub *subscriber, r *sinkRunner,
+	next *uint64, blocks []*blockchain.BlockData) error {
+
+	for len(blocks) != 0 && blocks[0].Block.BlockHeight < *next {
+		blocks = blocks[1:]
+	}
+
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	if blocks[0].Block.BlockHeight > *next {
+		err := s.exportFromNode(ctx, sub, r, next, blocks[0].Block.BlockHeight-1)
+		if err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+
+	return s.writeSink(sub, r, next, blocks)
+}
+
+// exportFromNode 从节点按高度拉取[next, end]的区块并导出
+func (s *Server) exportFromNode(ctx context.Context, sub *subscriber, r *sinkRunner,
+	next *uint64, end uint64) error {
+
+	s.chainListMapMutex.Lock()
+	c := sub.client
+	s.chainListMapMutex.Unlock()
+
+	if c == nil {
+		return errors.New("the chain client is not available")
+	}
+
+	batchBlocks := s.config.IngestConfig.BatchBlocks
+
+	batch := make([]*blockchain.BlockData, 0, batchBlocks)
+
+	for h := *next; h <= end; h++ {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		blockInfo, err := c.GetChainMakerClient().GetBlockByHeight(h, sub.withRWSet)
+		if err != nil {
+			return fmt.Errorf("fail to get block by height, height: [%d], err: [%s]", h, err.Error())
+		}
+
+		blockData, err := blockchain.ParseBlock(blockInfo, c.GetChainHashType(), c.LookupCert)
+		if err != nil {
+			return fmt.Errorf("fail to parse block, height: [%d], err: [%s]", h, err.Error())
+		}
+
+		batch = append(batch, blockData)
+
+		if len(batch) < batchBlocks && h != end {
+			continue
+		}
+
+		err = s.writeSink(sub, r, next, batch)
+		if err != nil {
+			return err
+		}
+
+		batch = make([]*blockchain.BlockData, 0, batchBlocks)
+	}
+
+	return nil
+}
+
+// writeSink 写入导出目标并保存检查点
+func (s *Server) writeSink(sub *subscriber, r *sinkRunner, next *uint64,
+	blocks []*blockchain.BlockData) error {
+
+	err := r.sink.WriteBlocks(sub.genHash, blocks)
+	if err != nil {
+		return fmt.Errorf("fail to write the sink, height: [%d-%d], err: [%s]",
+			blocks[0].Block.BlockHeight, blocks[len(blocks)-1].Block.BlockHeight, err.Error())
+	}
+
+	*next = blocks[len(blocks)-1].Block.BlockHeight + 1
+
+	err = dao.SaveSinkCheckpoint(sub.genHash, r.sink.Name(), *next, "", s.gormDb)
+	if err != nil {
+		return errors.New("fail to save the sink checkpoint, " + err.Error())
+	}
+
+	return nil
+}
+
+// isCurrentSubscriber 订阅是否仍有效，取消订阅或重新订阅后旧的导出任务退出
+func (s *Server) isCurrentSubscriber(sub *subscriber) bool {
+	s.chainListMapMutex.Lock()
+	defer s.chainListMapMutex.Unlock()
+
+	return s.chainList[sub.genHash] == sub
+}
@@ -2,26 +2,100 @@ package server
 
 import (
 	"chainmscan/blockchain"
+	"chainmscan/db"
 	"chainmscan/db/dao"
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
+	"sync"
+	"time"
 
-	"chainmaker.org/chainmaker/pb-go/v2/common"
 	"gorm.io/gorm"
 
 	dbModel "chainmscan/db/model"
 )
 
-func (s *Server) Subscribe(c *blockchain.BlockChainClient, chainName string) error {
+const (
+	// 重新订阅的初始退避时间
+	ResubscribeMinInterval = time.Second
+	// 重新订阅的最大退避时间
+	ResubscribeMaxInterval = 5 * time.Minute
+)
+
+// subscriber 单条链的订阅信息
+type subscriber struct {
+	genHash   string
+	tableNum  int
+	withRWSet bool
+	client    *blockchain.BlockChainClient
+	closedC   chan string
+	// 该链的区块导出任务
+	sinkRunners []*sinkRunner
+	// 同步进度
+	sync *syncTracker
+	// 该链全部任务的上下文，暂停或取消订阅时取消
+	ctx    context.Context
+	cancel context.CancelFunc
+	// 运行中的链任务
+	tasks  sync.WaitGroup
+	paused bool
+}
+
+func (s *Server) newSubscriber(genHash string, tableNum int, withRWSet bool) *subscriber {
+	sub := &subscriber{
+		genHash:   genHash,
+		tableNum:  tableNum,
+		withRWSet: withRWSet,
+		closedC:   make(chan string, 1),
+		sync:      newSyncTracker(genHash),
+	}
+
+	sub.ctx, sub.cancel = context.WithCancel(s.ctx)
+
+	return sub
+}
+
+// submitChainTask 提交链任务，任务在链的上下文取消时随之退出
+func (s *Server) submitChainTask(sub *subscriber, f func(ctx context.Context) error) error {
+	sub.tasks.Add(1)
+
+	err := s.workerPool.Submit(func(ctx context.Context) error {
+		defer sub.tasks.Done()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		stop := context.AfterFunc(sub.ctx, cancel)
+		defer stop()
+
+		return f(ctx)
+	})
+	if err != nil {
+		sub.tasks.Done()
+		return err
+	}
+
+	return nil
+}
+
+// notifyClosed 通知订阅监听区块处理任务已退出，已有未处理的通知时忽略
+func (sub *subscriber) notifyClosed() {
+	select {
+	case sub.closedC <- sub.genHash:
+	default:
+	}
+}
+
+// Subscribe 订阅链，withRWSet为true时同时索引交易读写集
+func (s *Server) Subscribe(c *blockchain.BlockChainClient, chainName string, withRWSet bool) error {
 	// 判断是否已经订阅
 	chainGenHash := c.GetChainGenHash()
 
-	s.chainListMapMutex.Lock()
-	defer s.chainListMapMutex.Unlock()
+	s.lifecycleMutex.Lock()
+	defer s.lifecycleMutex.Unlock()
 
-	_, ok := s.chainList[chainGenHash]
-	if ok {
+	if s.isSubscribed(chainGenHash) {
 		return nil
 	}
 
@@ -33,12 +107,8 @@ func (s *Server) Subscribe(c *blockchain.BlockChainClient, chainName string) err
 
 	var tableNum int
 
-	var blockC <-chan interface{}
-
-	closedC := make(chan string)
-
 	if chainInfo == nil {
-		// 第一次订阅表后缀序号递增（需要提前数据库分好表）
+		// 第一次订阅表后缀序号递增，并创建该链的全部分表
 		maxTableNum, err := dao.GetMaxTableNumOfChainInfo(s.gormDb)
 		if err != nil {
 			return errors.New("query the sub info err, " + err.Error())
@@ -46,10 +116,10 @@ func (s *Server) Subscribe(c *blockchain.BlockChainClient, chainName string) err
 
 		tableNum = maxTableNum + 1
 
-		// 从0号区块开始订阅
-		blockC, err = c.GetChainMakerClient().SubscribeBlock(s.ctx, 0, -1, false, false)
+		// 分表创建失败时不写入链信息，再次订阅时重新创建
+		err = db.MigrateShardTables(s.gormDb, tableNum)
 		if err != nil {
-			return errors.New("fail to subscribe block, " + err.Error())
+			return errors.New("fail to create the shard tables, " + err.Error())
 		}
 
 		chainInfo = new(dbModel.ChainInfo)
@@ -57,76 +127,152 @@ func (s *Server) Subscribe(c *blockchain.BlockChainClient, chainName string) err
 		chainInfo.TableNum = tableNum
 		chainInfo.ChainId = c.GetConfig().ChainId
 		chainInfo.GenHash = c.GetChainGenHash()
+		chainInfo.SenderAddressMigrated = true
 
 		err = dao.InsertOneObjectToDB(chainInfo, s.gormDb)
 		if err != nil {
 			return errors.New("insert chaininfo to db err, " + err.Error())
 		}
 
-	} else {
+		// 开启回填时先并行回填历史区块，否则从0号区块开始订阅
+		if s.backfillEnabled() {
+			err = s.planBackfill(c, chainGenHash, 0)
+			if err != nil {
+				return errors.New("fail to plan the backfill, " + err.Error())
+			}
+		}
 
-		// 不是初次订阅需要索引到该链的分表，查询当前库内区块高度
+	} else {
 
+		// 不是初次订阅需要索引到该链的分表，从库内最大高度+1处开始订阅
 		tableNum = chainInfo.TableNum
+	}
 
-		maxHeight, err := dao.MaxBlockHeightInDb(chainGenHash, s.gormDb)
-		if err != nil {
-			return errors.New("fail to get max block height in db, " + err.Error())
-		}
+	// 数据库更新订阅配置，保存成功后再开启链任务
+	oldSubInfo, err := dao.GetInfoOfSubscription(chainGenHash, s.gormDb)
+	if err != nil {
+		return errors.New("query the sub info err, " + err.Error())
+	}
 
-		blockC, err = c.GetChainMakerClient().SubscribeBlock(s.ctx, int64(maxHeight+1), -1, false, false)
-		if err != nil {
-			return errors.New("fail to subscribe block, " + err.Error())
-		}
+	oldNodes, err := dao.GetSubscriptionNodes(chainGenHash, s.gormDb)
+	if err != nil {
+		return errors.New("query the sub nodes err, " + err.Error())
+	}
 
+	subInfo := &dbModel.Subscription{}
+	if oldSubInfo != nil {
+		*subInfo = *oldSubInfo
+	}
+
+	subInfo.ChainName = chainName
+	subInfo.GenHash = chainGenHash
+	subInfo.ChainId = c.GetConfig().ChainId
+	subInfo.OrgId = c.GetConfig().OrgId
+	// 节点配置保存在subscription_node，清空旧版本的单节点配置
+	subInfo.NodeAddr = ""
+	subInfo.NodeUseTls = false
+	subInfo.NodeCaCertPem = ""
+	subInfo.NodeTlsHostName = ""
+	subInfo.SignCertPem = string(c.GetConfig().SignCertBytes)
+	subInfo.SignKeyPem = string(c.GetConfig().SignKeyBytes)
+	subInfo.TlsCertPem = string(c.GetConfig().TlsCertBytes)
+	subInfo.TlsKeyPem = string(c.GetConfig().TlsKeyBytes)
+	subInfo.ArchiveCenterUrl = c.GetConfig().ArchiveCenterUrl
+	subInfo.WithRwSet = withRWSet
+	subInfo.AuthType = c.GetConfig().AuthType
+	subInfo.HashAlgorithm = c.GetConfig().HashAlgorithm
+
+	nodes := make([]*dbModel.SubscriptionNode, 0, len(c.GetConfig().NodeConfs))
+	for i, n := range c.GetConfig().NodeConfs {
+		node := &dbModel.SubscriptionNode{
+			GenHash:   chainGenHash,
+			NodeIndex: i,
+			Addr:      n.Addr,
+			UseTls:    n.UseTls,
+		}
+		if n.UseTls {
+			node.CaCertPems = n.CaCertPem
+			node.TlsHostName = n.TlsHostName
+		}
+		nodes = append(nodes, node)
 	}
 
-	// 开启订阅监听
-	s.workerPool.Submit(s.listen(closedC))
+	err = dao.SaveSubscription(subInfo, nodes, s.gormDb)
+	if err != nil {
+		return errors.New("fail to save the subscription, " + err.Error())
+	}
 
-	// 开启区块监听
-	s.workerPool.Submit(s.startProcess(chainGenHash, tableNum, closedC, blockC, s.gormDb))
+	sub := s.newSubscriber(chainGenHash, tableNum, withRWSet)
+	sub.client = c
 
 	// 新增订阅列表
-	s.chainList[chainGenHash] = closedC
+	s.chainListMapMutex.Lock()
+	s.chainList[chainGenHash] = sub
+	s.chainListMapMutex.Unlock()
 
-	// 数据库更新订阅配置
-	sub, err := dao.GetInfoOfSubscription(chainGenHash, s.gormDb)
+	// 开启区块导出、订阅监听、区块监听及发送者地址补齐
+	err = s.startSinks(sub)
+	if err == nil {
+		err = s.submitChainTask(sub, s.listen(sub))
+	}
+	if err == nil {
+		err = s.submitChainTask(sub, s.startProcess(sub, c))
+	}
+	if err == nil {
+		err = s.submitChainTask(sub, s.startSenderAddressMigration(sub, c))
+	}
 	if err != nil {
-		return errors.New("query the sub info err, " + err.Error())
+		err = errors.New("fail to start the chain tasks, " + err.Error())
+
+		s.chainListMapMutex.Lock()
+		delete(s.chainList, chainGenHash)
+		s.chainListMapMutex.Unlock()
+
+		stopErr := s.stopSubscriber(sub)
+		if stopErr != nil {
+			s.SysLog().Errorf("fail to stop the chain tasks, err: [%s], genHash: [%s]\n",
+				stopErr.Error(), chainGenHash)
+		}
+
+		// 回滚订阅配置
+		var rollbackErr error
+		if oldSubInfo == nil {
+			rollbackErr = dao.DeleteSubscription(chainGenHash, s.gormDb)
+		} else {
+			rollbackErr = dao.SaveSubscription(oldSubInfo, oldNodes, s.gormDb)
+		}
+		if rollbackErr != nil {
+			return fmt.Errorf("%s, and fail to roll back the subscription, err: [%s]", err.Error(),
+				rollbackErr.Error())
+		}
+
+		return err
 	}
 
-	if sub == nil {
-		sub = &dbModel.Subscription{}
-	}
-
-	sub.ChainName = chainName
-	sub.GenHash = chainGenHash
-	sub.ChainId = c.GetConfig().ChainId
-	sub.OrgId = c.GetConfig().OrgId
-	sub.NodeAddr = c.GetConfig().NodeConfs[0].Addr
-	sub.NodeUseTls = c.GetConfig().NodeConfs[0].UseTls
-	if sub.NodeUseTls {
-		sub.NodeCaCertPem = c.GetConfig().NodeConfs[0].CaCertPem[0]
-		sub.NodeTlsHostName = c.GetConfig().NodeConfs[0].TlsHostName
-	}
-	sub.SignCertPem = string(c.GetConfig().SignCertBytes)
-	sub.SignKeyPem = string(c.GetConfig().SignKeyBytes)
-	sub.TlsCertPem = string(c.GetConfig().TlsCertBytes)
-	sub.TlsKeyPem = string(c.GetConfig().TlsKeyBytes)
-	sub.ArchiveCenterUrl = c.GetConfig().ArchiveCenterUrl
-
-	return dao.SaveInfoOfSubscription(sub, s.gormDb)
+	return nil
 }
 
+// UnSubscribe 取消订阅，停止该链的全部任务，已入库的数据保留
 func (s *Server) UnSubscribe(genHash string, db *gorm.DB) error {
-	s.chainListMapMutex.Lock()
-	defer s.chainListMapMutex.Unlock()
+	s.lifecycleMutex.Lock()
+	defer s.lifecycleMutex.Unlock()
 
+	s.chainListMapMutex.Lock()
+	sub, ok := s.chainList[genHash]
 	delete(s.chainList, genHash)
+	s.chainListMapMutex.Unlock()
 
 	// 主动删除订阅配置
-	return dao.DeleteSubscription(genHash, db)
+	err := dao.DeleteSubscription(genHash, db)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		return s.stopSubscriber(sub)
+	}
+
+	return nil
 }
 
 func (s *Server) GetChainList() []string {
@@ -142,59 +288,66 @@ func (s *Server) GetChainList() []string {
 	return list
 }
 
-func (s *Server) startProcess(genHash string, tableNum int,
-	closedSignal chan<- string, c <-chan interface{},
-	gormDb *gorm.DB) func(ctx context.Context) error {
+// startProcess 区块处理任务：先完成未完成的历史回填，再从库内最大高度+1处订阅实时区块
+func (s *Server) startProcess(sub *subscriber,
+	c *blockchain.BlockChainClient) func(ctx context.Context) error {
 
 	return func(ctx context.Context) error {
-		for {
-			select {
-			case block, ok := <-c:
-				if !ok {
-					closedSignal <- genHash
-					err := errors.New("the chan of subscriber is closed")
-					s.SysLog().Error(err.Error())
-					return err
-				}
+		sub.sync.setRunning(true)
 
-				blockInfo, ok := block.(*common.BlockInfo)
-				if !ok {
-					closedSignal <- genHash
-					err := errors.New("the block info type error")
-					s.SysLog().Error(err.Error())
-					return err
-				}
+		err := s.process(ctx, sub, c)
 
-				err := blockchain.StorageBlock(blockInfo, genHash, tableNum, gormDb)
-				if err != nil {
-					closedSignal <- genHash
-					err := fmt.Errorf("fail to storage block, err: [%s]", err.Error())
-					s.SysLog().Error(err.Error())
-					return err
-				}
+		sub.sync.setRunning(false)
 
-			case <-ctx.Done():
-				s.SysLog().Infof("the chain subscriber has been closed, genHash: [%s]\n", genHash)
-				return nil
-			}
+		if err != nil && ctx.Err() == nil {
+			sub.sync.setError(err)
+			sub.notifyClosed()
+			s.SysLog().Errorf("the block process exits, err: [%s], genHash: [%s]\n", err.Error(), sub.genHash)
+			return err
 		}
+
+		s.SysLog().Infof("the chain subscriber has been closed, genHash: [%s]\n", sub.genHash)
+		return nil
 	}
 }
 
-func (s *Server) listen(closedSignal <-chan string) func(ctx context.Context) error {
+func (s *Server) process(ctx context.Context, sub *subscriber,
+	c *blockchain.BlockChainClient) error {
+
+	err := s.backfill(ctx, sub, c)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	maxHeight, err := dao.MaxBlockHeightInDb(sub.genHash, s.gormDb)
+	if err != nil {
+		return errors.New("fail to get max block height in db, " + err.Error())
+	}
+
+	blockC, err := c.GetChainMakerClient().SubscribeBlock(ctx, int64(maxHeight+1), -1, sub.withRWSet, false)
+	if err != nil {
+		return errors.New("fail to subscribe block, " + err.Error())
+	}
+
+	return blockchain.ProcessBlocks(ctx, blockC, sub.genHash, sub.tableNum, c.GetChainHashType(),
+		c.LookupCert, s.config.IngestConfig, s.gormDb, sub.notifySinks)
+}
+
+// listen 订阅监听，区块处理协程退出后按指数退避重新订阅
+func (s *Server) listen(sub *subscriber) func(ctx context.Context) error {
 	return func(ctx context.Context) error {
 		for {
 			select {
-			case genHash := <-closedSignal:
-				// 将链状态更新为未订阅状态，走重新订阅逻辑。
-
-				s.chainListMapMutex.Lock()
-				delete(s.chainList, genHash)
-				s.chainListMapMutex.Unlock()
-
+			case genHash := <-sub.closedC:
 				s.SysLog().Warnf("the subscriber is closed, genHash: [%s]\n", genHash)
 
-				return nil
+				if !s.resubscribe(ctx, sub) {
+					return nil
+				}
 
 			case <-ctx.Done():
 				s.SysLog().Info("the subscriber's listening has been closed ...")
@@ -204,59 +357,198 @@ func (s *Server) listen(closedSignal <-chan string) func(ctx context.Context) er
 	}
 }
 
-func (s *Server) SubscriberStart() error {
-	s.chainListMapMutex.Lock()
-	defer s.chainListMapMutex.Unlock()
+// resubscribe 重建客户端并重新开启区块处理任务，直到成功、服务退出或链被取消订阅
+func (s *Server) resubscribe(ctx context.Context, sub *subscriber) bool {
+	for attempt := 0; ; attempt++ {
+		interval := resubscribeInterval(attempt)
 
-	// 查询订阅配置列表
-	list, err := dao.GetAllSubscription(s.gormDb)
-	if err != nil {
-		return errors.New("fail to get all subscription, " + err.Error())
-	}
+		s.SysLog().Infof("resubscribe after [%s], genHash: [%s], attempt: [%d]\n",
+			interval, sub.genHash, attempt+1)
 
-	sdkLog, err := s.GetZapLogger("BCSDK")
-	if err != nil {
-		return err
-	}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return false
+		}
 
-	// 依次重新开启订阅
-	for _, v := range list {
-		// 建立客户端
-		config := &blockchain.ClientConfig{
-			ChainId:       v.ChainId,
-			OrgId:         v.OrgId,
-			SignKeyBytes:  []byte(v.SignKeyPem),
-			SignCertBytes: []byte(v.SignCertPem),
-			NodeConfs: []*blockchain.NodeConnConfig{
-				{
-					Addr:        v.NodeAddr,
-					CaCertPem:   []string{v.NodeCaCertPem},
-					TlsHostName: v.NodeTlsHostName,
-					UseTls:      v.NodeUseTls,
-				},
-			},
-			TlsKeyBytes:      []byte(v.TlsKeyPem),
-			TlsCertBytes:     []byte(v.TlsCertPem),
-			ArchiveCenterUrl: v.ArchiveCenterUrl,
-			Logger:           sdkLog,
+		if !s.isSubscribed(sub.genHash) {
+			s.SysLog().Infof("the chain has been unsubscribed, stop resubscribing, genHash: [%s]\n",
+				sub.genHash)
+			return false
 		}
 
-		c, err := blockchain.NewChainmakerClient(config)
+		// SDK的节点连接池会跳过不可用的节点
+		client, err := s.connectFromDb(sub.genHash)
+
+		lastErr := ""
 		if err != nil {
-			return errors.New("create chainmaker client err, " + err.Error())
+			lastErr = err.Error()
+		}
+
+		dbErr := dao.UpdateSubscriptionRetry(sub.genHash, time.Now().Unix(), lastErr, s.gormDb)
+		if dbErr != nil {
+			s.SysLog().Errorf("fail to update the subscription retry info, err: [%s], genHash: [%s]\n",
+				dbErr.Error(), sub.genHash)
 		}
 
-		maxHeight, err := dao.MaxBlockHeightInDb(c.GetChainGenHash(), s.gormDb)
 		if err != nil {
-			return errors.New("fail to get max block height in db, " + err.Error())
+			sub.sync.setError(err)
+			s.SysLog().Errorf("fail to resubscribe, err: [%s], genHash: [%s], attempt: [%d]\n",
+				err.Error(), sub.genHash, attempt+1)
+			continue
+		}
+
+		s.chainListMapMutex.Lock()
+		if sub.client != nil {
+			sub.client.GetChainMakerClient().Stop()
 		}
+		sub.client = client
+		s.chainListMapMutex.Unlock()
 
-		blockC, err := c.GetChainMakerClient().SubscribeBlock(s.ctx, int64(maxHeight+1), -1, false, false)
+		err = s.submitChainTask(sub, s.startProcess(sub, client))
 		if err != nil {
-			return errors.New("fail to subscribe block, " + err.Error())
+			s.SysLog().Errorf("fail to submit the block process, err: [%s], genHash: [%s]\n",
+				err.Error(), sub.genHash)
+			return false
+		}
+
+		s.SysLog().Infof("resubscribe successfully, genHash: [%s], attempt: [%d]\n",
+			sub.genHash, attempt+1)
+
+		return true
+	}
+}
+
+// resubscribeInterval 指数退避加随机抖动，取值范围[d/2, d)
+func resubscribeInterval(attempt int) time.Duration {
+	d := ResubscribeMaxInterval
+	if attempt < 16 {
+		d = ResubscribeMinInterval << uint(attempt)
+		if d > ResubscribeMaxInterval {
+			d = ResubscribeMaxInterval
+		}
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+func (s *Server) isSubscribed(genHash string) bool {
+	s.chainListMapMutex.Lock()
+	defer s.chainListMapMutex.Unlock()
+
+	_, ok := s.chainList[genHash]
+	return ok
+}
+
+// connectFromDb 根据数据库中的订阅配置建立客户端，全部节点交给SDK的节点连接池
+func (s *Server) connectFromDb(genHash string) (*blockchain.BlockChainClient, error) {
+
+	sub, err := dao.GetInfoOfSubscription(genHash, s.gormDb)
+	if err != nil {
+		return nil, errors.New("query the sub info err, " + err.Error())
+	}
+
+	if sub == nil {
+		return nil, errors.New("the subscription does not exist")
+	}
+
+	nodes, err := s.getSubscriptionNodeConfs(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.newClientFromSubscription(sub, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.GetChainGenHash() != sub.GenHash {
+		c.GetChainMakerClient().Stop()
+		return nil, errors.New("the genesis hash of the chain does not match")
+	}
+
+	return c, nil
+}
+
+// getSubscriptionNodeConfs 获取订阅链的节点列表，兼容只保存了单节点配置的旧订阅
+func (s *Server) getSubscriptionNodeConfs(sub *dbModel.Subscription) ([]*blockchain.NodeConnConfig, error) {
+	list, err := dao.GetSubscriptionNodes(sub.GenHash, s.gormDb)
+	if err != nil {
+		return nil, errors.New("query the subscription nodes err, " + err.Error())
+	}
+
+	nodes := make([]*blockchain.NodeConnConfig, 0, len(list))
+
+	for _, n := range list {
+		caCertPems := n.CaCertPems
+		if len(caCertPems) == 0 {
+			caCertPems = blockchain.SplitCertPems(n.CaCertPem)
 		}
 
-		chainInfo, err := dao.GetChainInfo(c.GetChainGenHash(), s.gormDb)
+		nodes = append(nodes, &blockchain.NodeConnConfig{
+			Addr:        n.Addr,
+			CaCertPem:   caCertPems,
+			TlsHostName: n.TlsHostName,
+			UseTls:      n.UseTls,
+		})
+	}
+
+	if len(nodes) == 0 {
+		nodes = append(nodes, &blockchain.NodeConnConfig{
+			Addr:        sub.NodeAddr,
+			CaCertPem:   blockchain.SplitCertPems(sub.NodeCaCertPem),
+			TlsHostName: sub.NodeTlsHostName,
+			UseTls:      sub.NodeUseTls,
+		})
+	}
+
+	return nodes, nil
+}
+
+func (s *Server) newClientFromSubscription(v *dbModel.Subscription,
+	nodes []*blockchain.NodeConnConfig) (*blockchain.BlockChainClient, error) {
+	sdkLog, err := s.GetZapLogger("BCSDK")
+	if err != nil {
+		return nil, err
+	}
+
+	// 建立客户端
+	config := &blockchain.ClientConfig{
+		ChainId:          v.ChainId,
+		OrgId:            v.OrgId,
+		SignKeyBytes:     []byte(v.SignKeyPem),
+		SignCertBytes:    []byte(v.SignCertPem),
+		NodeConfs:        nodes,
+		TlsKeyBytes:      []byte(v.TlsKeyPem),
+		TlsCertBytes:     []byte(v.TlsCertPem),
+		ArchiveCenterUrl: v.ArchiveCenterUrl,
+		Logger:           sdkLog,
+		HashAlgorithm:    v.HashAlgorithm,
+		AuthType:         v.AuthType,
+	}
+
+	c, err := blockchain.NewChainmakerClient(config)
+	if err != nil {
+		return nil, errors.New("create chainmaker client err, " + err.Error())
+	}
+
+	return c, nil
+}
+
+// SubscriberStart 按库内的订阅配置重新开启订阅，连接节点期间不持有chainListMapMutex
+func (s *Server) SubscriberStart() error {
+	s.lifecycleMutex.Lock()
+	defer s.lifecycleMutex.Unlock()
+
+	// 查询订阅配置列表
+	list, err := dao.GetAllSubscription(s.gormDb)
+	if err != nil {
+		return errors.New("fail to get all subscription, " + err.Error())
+	}
+
+	// 依次重新开启订阅
+	for _, v := range list {
+		chainInfo, err := dao.GetChainInfo(v.GenHash, s.gormDb)
 		if err != nil {
 			return errors.New("query chain info err, " + err.Error())
 		}
@@ -265,15 +557,47 @@ func (s *Server) SubscriberStart() error {
 			return errors.New("the chain info does not exist")
 		}
 
-		closedC := make(chan string)
+		sub := s.newSubscriber(v.GenHash, chainInfo.TableNum, v.WithRwSet)
+
+		// 已暂停的链不开启任务
+		if v.Paused {
+			sub.paused = true
+			sub.cancel()
+		}
+
+		// 新增订阅列表
+		s.chainListMapMutex.Lock()
+		s.chainList[v.GenHash] = sub
+		s.chainListMapMutex.Unlock()
+
+		if v.Paused {
+			continue
+		}
+
+		// 开启区块导出
+		s.startSinks(sub)
+
 		// 开启订阅监听
-		s.workerPool.Submit(s.listen(closedC))
+		s.submitChainTask(sub, s.listen(sub))
+
+		c, err := s.connectFromDb(v.GenHash)
+		if err != nil {
+			// 启动时节点不可用不影响服务启动，交由订阅监听重试
+			s.SysLog().Errorf("fail to start the subscriber, err: [%s], genHash: [%s]\n",
+				err.Error(), v.GenHash)
+			sub.notifyClosed()
+			continue
+		}
+
+		s.chainListMapMutex.Lock()
+		sub.client = c
+		s.chainListMapMutex.Unlock()
 
 		// 开启区块监听
-		s.workerPool.Submit(s.startProcess(c.GetChainGenHash(), chainInfo.TableNum, closedC, blockC, s.gormDb))
+		s.submitChainTask(sub, s.startProcess(sub, c))
 
-		// 新增订阅列表
-		s.chainList[c.GetChainGenHash()] = closedC
+		// 补齐旧版本交易的发送者地址
+		s.submitChainTask(sub, s.startSenderAddressMigration(sub, c))
 	}
 
 	return nil
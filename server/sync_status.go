@@ -0,0 +1,247 @@
+package server
+
+import (
+	"chainmscan/db/dao"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	SyncStatus_Syncing = "syncing"
+	SyncStatus_Live    = "live"
+	SyncStatus_Stalled = "stalled"
+	SyncStatus_Error   = "error"
+	SyncStatus_Paused  = "paused"
+
+	// 落后节点的区块数不超过该值视为实时同步
+	syncLiveLagBlocks = 2
+	// 连续多次检查入库区块数无增长且落后于节点视为停滞
+	syncStalledChecks = 3
+)
+
+// SyncStatus 订阅链的同步进度
+type SyncStatus struct {
+	GenHash       string `json:"genHash"`
+	Status        string `json:"status"`
+	Backfilling   bool   `json:"backfilling"`
+	TipHeight     int64  `json:"tipHeight"`
+	IndexedHeight int64  `json:"indexedHeight"`
+	LagBlocks     int64  `json:"lagBlocks"`
+	LagSeconds    int64  `json:"lagSeconds"`
+	// 最近一个检查周期的入库速率（区块/秒）
+	IngestRate    float64 `json:"ingestRate"`
+	LastError     string  `json:"lastError"`
+	LastErrorTime int64   `json:"lastErrorTime"`
+	UpdatedAt     int64   `json:"updatedAt"`
+}
+
+// syncTracker 记录单条链的同步进度，由进度检查任务和区块处理任务并发更新
+type syncTracker struct {
+	mutex  sync.Mutex
+	status SyncStatus
+	// 区块处理任务是否在运行
+	running bool
+	// 上次检查时的入库区块数
+	lastBlockAmount int
+	lastCheckTime   time.Time
+	stalledChecks   int
+	// 本次检查周期内是否发生错误
+	failed bool
+}
+
+func newSyncTracker(genHash string) *syncTracker {
+	return &syncTracker{
+		status: SyncStatus{
+			GenHash:       genHash,
+			Status:        SyncStatus_Syncing,
+			TipHeight:     -1,
+			IndexedHeight: -1,
+		},
+	}
+}
+
+func (t *syncTracker) setRunning(running bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.running = running
+}
+
+func (t *syncTracker) setError(err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.failed = true
+	t.status.LastError = err.Error()
+	t.status.LastErrorTime = time.Now().Unix()
+}
+
+func (t *syncTracker) setPaused() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.status.Status = SyncStatus_Paused
+	t.status.IngestRate = 0
+	t.status.UpdatedAt = time.Now().Unix()
+}
+
+func (t *syncTracker) get() SyncStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.status
+}
+
+// GetSyncStatus 查询订阅链的同步进度
+func (s *Server) GetSyncStatus(genHash string) (*SyncStatus, bool) {
+	s.chainListMapMutex.Lock()
+	sub, ok := s.chainList[genHash]
+	s.chainListMapMutex.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	status := sub.sync.get()
+	return &status, true
+}
+
+// syncMonitor 定时比对节点最新高度与库内最大高度，更新各订阅链的同步进度
+func (s *Server) syncMonitor(ctx context.Context) error {
+	interval := time.Duration(s.config.SyncStatusInterval) * time.Second
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.chainListMapMutex.Lock()
+			subs := make([]*subscriber, 0, len(s.chainList))
+			for _, sub := range s.chainList {
+				if sub.paused {
+					sub.sync.setPaused()
+					continue
+				}
+				subs = append(subs, sub)
+			}
+			s.chainListMapMutex.Unlock()
+
+			for _, sub := range subs {
+				err := s.checkSyncStatus(sub)
+				if err != nil {
+					sub.sync.setError(err)
+					s.SysLog().Errorf("fail to check the sync status, err: [%s], genHash: [%s]\n",
+						err.Error(), sub.genHash)
+				}
+				sub.sync.updateStatus()
+			}
+
+		case <-ctx.Done():
+			s.SysLog().Info("the sync monitor has been closed ...")
+			return nil
+		}
+	}
+}
+
+// checkSyncStatus 查询节点与库内的高度并计算落后程度和入库速率
+func (s *Server) checkSyncStatus(sub *subscriber) error {
+	s.chainListMapMutex.Lock()
+	c := sub.client
+	s.chainListMapMutex.Unlock()
+
+	if c == nil {
+		return errors.New("the chain client is not available")
+	}
+
+	tip, err := c.GetChainMakerClient().GetCurrentBlockHeight()
+	if err != nil {
+		return errors.New("fail to get current block height, " + err.Error())
+	}
+
+	indexedHeight, err := dao.MaxBlockHeightInDb(sub.genHash, s.gormDb)
+	if err != nil {
+		return errors.New("fail to get max block height in db, " + err.Error())
+	}
+
+	chainInfo, err := dao.GetChainInfo(sub.genHash, s.gormDb)
+	if err != nil {
+		return errors.New("query chain info err, " + err.Error())
+	}
+
+	if chainInfo == nil {
+		return errors.New("the chain info does not exist")
+	}
+
+	ranges, err := dao.GetUnfinishedBackfillRanges(sub.genHash, s.gormDb)
+	if err != nil {
+		return errors.New("fail to get the backfill ranges, " + err.Error())
+	}
+
+	var lagSeconds int64
+	lagBlocks := int64(tip) - indexedHeight
+	if lagBlocks < 0 {
+		lagBlocks = 0
+	}
+
+	if lagBlocks > 0 && indexedHeight >= 0 {
+		block, _, err := dao.GetBlockInfo(sub.genHash, indexedHeight, "", 0, s.gormDb)
+		if err != nil {
+			return errors.New("fail to get block info, " + err.Error())
+		}
+
+		if block != nil {
+			lagSeconds = time.Now().Unix() - block.BlockTimestamp
+		}
+	}
+
+	now := time.Now()
+
+	t := sub.sync
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !t.lastCheckTime.IsZero() {
+		delta := chainInfo.BlockAmount - t.lastBlockAmount
+		t.status.IngestRate = float64(delta) / now.Sub(t.lastCheckTime).Seconds()
+
+		if delta == 0 && lagBlocks > syncLiveLagBlocks {
+			t.stalledChecks++
+		} else {
+			t.stalledChecks = 0
+		}
+	}
+
+	t.lastBlockAmount = chainInfo.BlockAmount
+	t.lastCheckTime = now
+
+	t.status.Backfilling = len(ranges) != 0
+	t.status.TipHeight = int64(tip)
+	t.status.IndexedHeight = indexedHeight
+	t.status.LagBlocks = lagBlocks
+	t.status.LagSeconds = lagSeconds
+
+	return nil
+}
+
+// updateStatus 根据本周期的检查结果更新同步状态
+func (t *syncTracker) updateStatus() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	switch {
+	case t.failed || !t.running:
+		t.status.Status = SyncStatus_Error
+	case t.stalledChecks >= syncStalledChecks:
+		t.status.Status = SyncStatus_Stalled
+	case t.status.Backfilling || t.status.LagBlocks > syncLiveLagBlocks:
+		t.status.Status = SyncStatus_Syncing
+	default:
+		t.status.Status = SyncStatus_Live
+	}
+
+	t.failed = false
+	t.status.UpdatedAt = time.Now().Unix()
+}